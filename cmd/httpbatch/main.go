@@ -0,0 +1,49 @@
+// Command httpbatch sends the requests of a JSONL file and appends their
+// results to another JSONL file, see httpclient.BatchRunner for the line
+// formats. Running it again with the same results file resumes the batch.
+//
+//	httpbatch -in requests.jsonl -out results.jsonl -c 8 -per-host 2 -retries 3
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+func main() {
+	input := flag.String("in", "", "JSONL requests file, required")
+	output := flag.String("out", "results.jsonl", "JSONL results file")
+	runner := &httpclient.BatchRunner{}
+	flag.IntVar(&runner.Concurrency, "c", 4, "requests in flight")
+	flag.IntVar(&runner.PerHost, "per-host", 0, "requests in flight per host, 0 for no cap")
+	flag.IntVar(&runner.Retries, "retries", 0, "retries after transport errors, 429 and 5xx")
+	flag.DurationVar(&runner.RetryBackoff, "backoff", 0, "delay before the first retry")
+	flag.DurationVar(&runner.Timeout, "timeout", 0, "default request timeout")
+	flag.IntVar(&runner.MaxBody, "max-body", 0, "largest body kept in the results")
+	flag.BoolVar(&runner.HashOnly, "hash-only", false, "record bodies by hash only")
+	flag.BoolVar(&runner.RetryFailed, "retry-failed", false, "send failed lines again")
+	flag.Parse()
+
+	if *input == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	summary, err := runner.RunFile(ctx, *input, *output)
+	fmt.Fprintf(os.Stderr, "httpbatch: %d requests, %d skipped, %d succeeded, %d failed\n",
+		summary.Total, summary.Skipped, summary.Succeeded, summary.Failed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "httpbatch:", err)
+		os.Exit(1)
+	}
+	if summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const httpclientPath = "github.com/mgolfam/gogutils/httpclient"
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// binding maps a query, form or header name to a Go expression.
+type binding struct {
+	Name string
+	Expr string
+}
+
+type method struct {
+	Name         string
+	Signature    string
+	Ctx          string
+	HTTPMethod   string
+	Path         string
+	Params       []binding
+	Query        []binding
+	QueryStructs []string
+	Headers      []binding
+	Form         []binding
+	FormStructs  []string
+	Body         string
+	Result       string
+	Zero         string
+}
+
+type client struct {
+	Package   string
+	Interface string
+	Impl      string
+	HC        string
+	StdLib    []string
+	Imports   []string
+	Methods   []method
+	Command   string
+}
+
+// generate parses the Go files of dir and returns the source of the clients
+// implementing the named interfaces.
+func generate(dir string, types []string, command string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	imports := map[string]bool{`"context"`: true}
+	var clients []client
+	hc := "httpclient"
+
+	for _, name := range types {
+		file, spec, iface := findInterface(pkgs, name)
+		if iface == nil {
+			return nil, fmt.Errorf("interface %s not found in %s", name, dir)
+		}
+
+		c := client{Package: file.Name.Name, Interface: name, Impl: lowerFirst(name) + "Client", Command: command}
+		for _, field := range iface.Methods.List {
+			fn, ok := field.Type.(*ast.FuncType)
+			if !ok || len(field.Names) == 0 {
+				return nil, fmt.Errorf("%s: embedded interfaces are not supported", fset.Position(field.Pos()))
+			}
+			m, err := parseMethod(fset, field.Names[0].Name, field.Doc, fn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s.%s: %w", fset.Position(field.Pos()), name, field.Names[0].Name, err)
+			}
+			c.Methods = append(c.Methods, m)
+		}
+		for _, imp := range usedImports(file, spec) {
+			imports[imp] = true
+		}
+		// Reuse the name the interface's file gives the httpclient package.
+		for _, imp := range file.Imports {
+			if imp.Path.Value == strconv.Quote(httpclientPath) && imp.Name != nil {
+				hc = imp.Name.Name
+			}
+		}
+		clients = append(clients, c)
+	}
+
+	if hc == "httpclient" {
+		imports[strconv.Quote(httpclientPath)] = true
+	} else {
+		imports[hc+" "+strconv.Quote(httpclientPath)] = true
+	}
+	for i := range clients {
+		clients[i].HC = hc
+	}
+
+	if needsFmt(clients) {
+		imports[`"fmt"`] = true
+	}
+	for imp := range imports {
+		importPath := imp[strings.Index(imp, `"`)+1:]
+		if strings.Contains(strings.SplitN(importPath, "/", 2)[0], ".") {
+			clients[0].Imports = append(clients[0].Imports, imp)
+		} else {
+			clients[0].StdLib = append(clients[0].StdLib, imp)
+		}
+	}
+	sort.Strings(clients[0].StdLib)
+	sort.Strings(clients[0].Imports)
+
+	if err := headerTemplate.Execute(&out, clients[0]); err != nil {
+		return nil, err
+	}
+	for _, c := range clients {
+		if err := clientTemplate.Execute(&out, c); err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func findInterface(pkgs map[string]*ast.Package, name string) (*ast.File, *ast.TypeSpec, *ast.InterfaceType) {
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.Name, "_test") {
+			continue
+		}
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, s := range gen.Specs {
+					spec := s.(*ast.TypeSpec)
+					if iface, ok := spec.Type.(*ast.InterfaceType); ok && spec.Name.Name == name {
+						return file, spec, iface
+					}
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// parseMethod reads the annotations of one interface method:
+//
+//	@GET /users/{id}        method and path template, {id} binds parameter id
+//	@query name=param       query parameter, "@query param" uses the parameter name
+//	@querystruct param      struct parameter encoded by its query tags
+//	@header Name: value     header, value may contain {param}
+//	@body param             JSON request body
+//	@form name=param        url-encoded form field
+//	@formstruct param       struct parameter encoded as form
+//
+// The first parameter must be a context.Context and every other parameter
+// must be bound. The results are either error or (T, error), where T is
+// decoded from the JSON response, or is []byte or *httpclient.HttpResponse.
+func parseMethod(fset *token.FileSet, name string, doc *ast.CommentGroup, fn *ast.FuncType) (method, error) {
+	m := method{Name: name}
+
+	if fn.Params.NumFields() == 0 {
+		return m, fmt.Errorf("the first parameter must be a context.Context")
+	}
+
+	params := map[string]bool{}
+	var names []string
+	var signature []string
+	for i, field := range fn.Params.List {
+		typ := exprString(fset, field.Type)
+		if len(field.Names) == 0 {
+			return m, fmt.Errorf("parameters must be named")
+		}
+		if _, ok := field.Type.(*ast.Ellipsis); ok {
+			return m, fmt.Errorf("variadic parameters are not supported")
+		}
+		for j, ident := range field.Names {
+			if i == 0 && j == 0 {
+				if typ != "context.Context" {
+					return m, fmt.Errorf("the first parameter must be a context.Context")
+				}
+			} else {
+				params[ident.Name] = false
+				names = append(names, ident.Name)
+			}
+			signature = append(signature, ident.Name+" "+typ)
+		}
+	}
+	m.Ctx = fn.Params.List[0].Names[0].Name
+
+	results := fn.Results
+	if results == nil || results.NumFields() == 0 || results.NumFields() > 2 ||
+		exprString(fset, results.List[len(results.List)-1].Type) != "error" {
+		return m, fmt.Errorf("the results must be error or (T, error)")
+	}
+	if results.NumFields() == 2 {
+		m.Result = exprString(fset, results.List[0].Type)
+		m.Zero = "*new(" + m.Result + ")"
+		switch results.List[0].Type.(type) {
+		case *ast.StarExpr, *ast.ArrayType, *ast.MapType, *ast.InterfaceType, *ast.FuncType, *ast.ChanType:
+			m.Zero = "nil"
+		}
+	}
+
+	use := func(param string) (string, error) {
+		if _, ok := params[param]; !ok {
+			return "", fmt.Errorf("unknown parameter %s", param)
+		}
+		params[param] = true
+		return param, nil
+	}
+
+	if doc != nil {
+		for _, comment := range doc.List {
+			line := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(comment.Text, "//"), "/*"))
+			if !strings.HasPrefix(line, "@") {
+				continue
+			}
+			tag, arg, _ := strings.Cut(line[1:], " ")
+			arg = strings.TrimSpace(arg)
+
+			var err error
+			switch lower := strings.ToLower(tag); {
+			case httpMethods[strings.ToUpper(tag)]:
+				if m.HTTPMethod != "" {
+					return m, fmt.Errorf("more than one HTTP method")
+				}
+				m.HTTPMethod, m.Path = strings.ToUpper(tag), arg
+			case lower == "query" || lower == "form":
+				key, param, ok := strings.Cut(arg, "=")
+				if !ok {
+					param = key
+				}
+				key, param = strings.TrimSpace(key), strings.TrimSpace(param)
+				if param, err = use(param); err == nil {
+					if lower == "query" {
+						m.Query = append(m.Query, binding{key, param})
+					} else {
+						m.Form = append(m.Form, binding{key, param})
+					}
+				}
+			case lower == "querystruct":
+				if arg, err = use(arg); err == nil {
+					m.QueryStructs = append(m.QueryStructs, arg)
+				}
+			case lower == "formstruct":
+				if arg, err = use(arg); err == nil {
+					m.FormStructs = append(m.FormStructs, arg)
+				}
+			case lower == "body":
+				if m.Body != "" {
+					return m, fmt.Errorf("more than one @body")
+				}
+				m.Body, err = use(arg)
+			case lower == "header":
+				key, value, ok := strings.Cut(arg, ":")
+				if !ok {
+					return m, fmt.Errorf("@header needs Name: value")
+				}
+				var expr string
+				expr, err = templateExpr(strings.TrimSpace(value), use)
+				m.Headers = append(m.Headers, binding{strings.TrimSpace(key), expr})
+			default:
+				return m, fmt.Errorf("unknown annotation @%s", tag)
+			}
+			if err != nil {
+				return m, err
+			}
+		}
+	}
+
+	if m.HTTPMethod == "" {
+		return m, fmt.Errorf("missing HTTP method annotation such as @GET /path")
+	}
+	if m.Body != "" && (len(m.Form) > 0 || len(m.FormStructs) > 0) {
+		return m, fmt.Errorf("@body and @form cannot be combined")
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(m.Path, -1) {
+		param, err := use(match[1])
+		if err != nil {
+			return m, fmt.Errorf("path %s: %w", m.Path, err)
+		}
+		m.Params = append(m.Params, binding{param, param})
+	}
+	for _, param := range names {
+		if !params[param] {
+			return m, fmt.Errorf("parameter %s is not bound to the request", param)
+		}
+	}
+
+	m.Signature = "(" + strings.Join(signature, ", ") + ")"
+	if m.Result != "" {
+		m.Signature += " (" + m.Result + ", error)"
+	} else {
+		m.Signature += " error"
+	}
+	return m, nil
+}
+
+// templateExpr turns "Bearer {token}" into a Go string expression.
+func templateExpr(value string, use func(string) (string, error)) (string, error) {
+	var parts []string
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(value, -1) {
+		if loc[0] > last {
+			parts = append(parts, strconv.Quote(value[last:loc[0]]))
+		}
+		param, err := use(value[loc[2]:loc[3]])
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "fmt.Sprint("+param+")")
+		last = loc[1]
+	}
+	if last < len(value) || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(value[last:]))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// usedImports returns the imports of file referenced by spec.
+func usedImports(file *ast.File, spec *ast.TypeSpec) []string {
+	used := map[string]bool{}
+	ast.Inspect(spec, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	var imports []string
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if !used[name] {
+			continue
+		}
+		if imp.Name != nil {
+			imports = append(imports, imp.Name.Name+" "+imp.Path.Value)
+		} else {
+			imports = append(imports, imp.Path.Value)
+		}
+	}
+	return imports
+}
+
+func needsFmt(clients []client) bool {
+	for _, c := range clients {
+		for _, m := range c.Methods {
+			for _, h := range m.Headers {
+				if strings.Contains(h.Expr, "fmt.Sprint") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, expr)
+	return buf.String()
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	for i := range r {
+		// Lower the whole leading acronym, keeping the last upper case
+		// letter when a word follows: "IPInfo" becomes "ipInfo".
+		if !unicode.IsUpper(r[i]) || (i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+var headerTemplate = template.Must(template.New("header").Parse(`// Code generated by {{.Command}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdLib}}
+	{{.}}
+{{- end}}
+{{range .Imports}}
+	{{.}}
+{{- end}}
+)
+`))
+
+var clientTemplate = template.Must(template.New("client").Parse(`
+type {{.Impl}} struct {
+	api *{{.HC}}.APIClient
+}
+
+// New{{.Interface}} returns an implementation of {{.Interface}} sending its
+// requests through api.
+func New{{.Interface}}(api *{{.HC}}.APIClient) {{.Interface}} {
+	return &{{.Impl}}{api: api}
+}
+{{range .Methods}}
+func (c *{{$.Impl}}) {{.Name}}{{.Signature}} {
+	req := c.api.NewRequest({{printf "%q" .HTTPMethod}}, {{printf "%q" .Path}})
+{{- range .Params}}
+	req.Param({{printf "%q" .Name}}, {{.Expr}})
+{{- end}}
+{{- range .Query}}
+	req.Query({{printf "%q" .Name}}, {{.Expr}})
+{{- end}}
+{{- range .QueryStructs}}
+	req.QueryStruct({{.}})
+{{- end}}
+{{- range .Headers}}
+	req.Header({{printf "%q" .Name}}, {{.Expr}})
+{{- end}}
+{{- range .Form}}
+	req.Form({{printf "%q" .Name}}, {{.Expr}})
+{{- end}}
+{{- range .FormStructs}}
+	req.FormStruct({{.}})
+{{- end}}
+{{- if .Body}}
+	req.JSON({{.Body}})
+{{- end}}
+{{if .Result}}
+	var out {{.Result}}
+	if err := c.api.Do({{.Ctx}}, req, &out); err != nil {
+		return {{.Zero}}, err
+	}
+	return out, nil
+{{- else}}
+	return c.api.Do({{.Ctx}}, req, nil)
+{{- end}}
+}
+{{end}}`))
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		dir    string
+		types  string
+		golden string
+	}{
+		{name: "all annotations", dir: "testdata/api", types: "Users", golden: "testdata/api/users_client.golden"},
+		{name: "services is up to date", dir: "../../services", types: "IpAPI", golden: "../../services/ipapi_client.go"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src, err := generate(test.dir, strings.Split(test.types, ","), "httpgen -type "+test.types)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected, err := os.ReadFile(test.golden)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(src) != string(expected) {
+				t.Errorf("Generated code differs from %s, run go generate. Got:\n%s", test.golden, src)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		expected string
+	}{
+		{name: "no method", method: "Get(ctx context.Context) error", expected: "missing HTTP method"},
+		{name: "no context", method: "// @GET /x\n\tGet(id int) error", expected: "context.Context"},
+		{name: "unbound param", method: "// @GET /x\n\tGet(ctx context.Context, id int) error", expected: "parameter id is not bound"},
+		{name: "unknown path param", method: "// @GET /x/{name}\n\tGet(ctx context.Context) error", expected: "unknown parameter name"},
+		{name: "bad results", method: "// @GET /x\n\tGet(ctx context.Context) string", expected: "results must be"},
+		{name: "unknown annotation", method: "// @GET /x\n\t// @cookie a\n\tGet(ctx context.Context) error", expected: "unknown annotation @cookie"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package api\n\nimport \"context\"\n\ntype API interface {\n\t" + test.method + "\n}\n"
+			if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := generate(dir, []string{"API"}, "httpgen")
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected an error containing %q, Got: %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Command httpgen generates HTTP clients from annotated Go interfaces. It is
+// meant to be run by go generate:
+//
+//	//go:generate go run github.com/mgolfam/gogutils/cmd/httpgen -type IpAPI
+//	type IpAPI interface {
+//		// @GET /json/{ip}
+//		// @query fields
+//		Lookup(ctx context.Context, ip string, fields string) (*dto.IpInfo, error)
+//	}
+//
+// For every interface a New<Interface>(*httpclient.APIClient) constructor
+// is written. See parseMethod for the annotations.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated interface names, required")
+	output := flag.String("output", "", "output file, <type>_client.go by default")
+	dir := flag.String("dir", ".", "package directory")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	src, err := generate(*dir, types, "httpgen -type "+*typeNames)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "httpgen:", err)
+		os.Exit(1)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(types[0]) + "_client.go"
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(*dir, name)
+	}
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "httpgen:", err)
+		os.Exit(1)
+	}
+}
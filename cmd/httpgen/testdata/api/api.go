@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	hc "github.com/mgolfam/gogutils/httpclient"
+)
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Filter struct {
+	Since time.Time `query:"since,omitempty"`
+	Tags  []string  `query:"tag"`
+}
+
+type Users interface {
+	// @GET /users/{id}
+	// @header Authorization: Bearer {token}
+	Get(ctx context.Context, id int, token string) (*User, error)
+
+	// @GET /users
+	// @query page
+	// @query per_page=size
+	// @querystruct filter
+	List(ctx context.Context, page, size int, filter Filter) ([]User, error)
+
+	// @POST /users
+	// @body user
+	Create(ctx context.Context, user User) (User, error)
+
+	// @POST /users/{id}/avatar
+	// @form url=avatarURL
+	SetAvatar(ctx context.Context, id int, avatarURL string) (*hc.HttpResponse, error)
+
+	// @DELETE /users/{id}
+	Delete(ctx context.Context, id int) error
+}
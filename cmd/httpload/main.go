@@ -0,0 +1,94 @@
+// Command httpload load tests one endpoint and prints throughput, status
+// codes, errors and latency percentiles.
+//
+//	httpload -rps 200 -duration 30s https://staging.example.com/health
+//	httpload -n 1000 -c 20 -curl "curl -X POST https://staging.example.com/login -d @login.json"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+	"github.com/mgolfam/gogutils/httpclient"
+	"github.com/mgolfam/gogutils/loadtest"
+)
+
+type headerFlags []string
+
+func (h *headerFlags) String() string     { return strings.Join(*h, ", ") }
+func (h *headerFlags) Set(v string) error { *h = append(*h, v); return nil }
+
+func main() {
+	var headers headerFlags
+	method := flag.String("X", "GET", "request method")
+	flag.Var(&headers, "H", "request header, repeatable")
+	data := flag.String("data", "", "request body")
+	curl := flag.String("curl", "", "curl command to send instead of the URL argument")
+	timeout := flag.Duration("timeout", 10*time.Second, "request timeout")
+	runner := &loadtest.Runner{}
+	flag.Float64Var(&runner.Rate, "rps", 0, "target requests per second, 0 for as fast as possible")
+	flag.IntVar(&runner.Concurrency, "c", 10, "workers")
+	flag.DurationVar(&runner.Duration, "duration", 0, "test duration")
+	flag.IntVar(&runner.Requests, "n", 0, "number of requests")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *curl != "" {
+		config, err := httpclient.ParseCurlCommand(*curl)
+		if err != nil {
+			fail(err)
+		}
+		runner.Config = *config
+	} else if flag.NArg() == 1 {
+		runner.Config = httpclient.HttpConfig{Method: strings.ToUpper(*method), URL: flag.Arg(0), Headers: map[string]string{}}
+		for _, h := range headers {
+			name, value, ok := strings.Cut(h, ":")
+			if !ok {
+				fail(fmt.Errorf("invalid header %q", h))
+			}
+			runner.Config.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		if *data != "" {
+			runner.Config.Body = []byte(*data)
+		}
+	} else {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if runner.Config.Timeout == 0 {
+		runner.Config.Timeout = *timeout
+	}
+	if runner.Duration == 0 && runner.Requests == 0 {
+		runner.Duration = 10 * time.Second
+	}
+
+	// Every request is logged at INFO, which would drown the report.
+	glog.LogLevel.Label = glog.WARN
+	glog.LogLevel.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := runner.Run(ctx)
+	if report == nil {
+		fail(err)
+	}
+	if *asJSON {
+		report.WriteJSON(os.Stdout)
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if report.Failures() > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "httpload:", err)
+	os.Exit(1)
+}
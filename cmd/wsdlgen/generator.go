@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/golang-cz/textcase"
+	"github.com/mgolfam/gogutils/soap"
+	"github.com/mgolfam/gogutils/soap/wsdl"
+)
+
+type goField struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+type goType struct {
+	Name    string
+	XMLName string
+	Fields  []goField
+}
+
+type goMethod struct {
+	Name   string
+	Doc    string
+	Action string
+	Input  string
+	Output string
+}
+
+type goClient struct {
+	Name    string
+	Doc     string
+	Port    string
+	Address string
+	Version string
+	Methods []goMethod
+}
+
+// generator turns the schema types used by the operations into Go types.
+type generator struct {
+	d       *wsdl.Definitions
+	types   map[string]*goType
+	named   map[*wsdl.ComplexType]string
+	taken   map[string]bool
+	clients []goClient
+}
+
+// generate returns the Go source of the types and clients of the services
+// of d. A service gets a client for port, or for its first SOAP 1.1 port
+// when port is empty.
+func generate(d *wsdl.Definitions, pkg, port, command string) ([]byte, error) {
+	g := &generator{d: d, types: map[string]*goType{}, named: map[*wsdl.ComplexType]string{}, taken: map[string]bool{}}
+
+	var services [][]*wsdl.Operation
+	for _, service := range d.Services {
+		operations, err := g.serviceOperations(service, port)
+		if err != nil {
+			return nil, err
+		}
+		if len(operations) > 0 {
+			services = append(services, operations)
+		}
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no SOAP port found")
+	}
+
+	// Element structs are named after their element, named types give way
+	// to them.
+	for _, operations := range services {
+		for _, op := range operations {
+			for _, m := range []*wsdl.Message{op.Input, op.Output} {
+				if m == nil {
+					continue
+				}
+				if op.Style == "rpc" {
+					input, output := op.Wrapper()
+					g.taken[exported(input)], g.taken[exported(output)] = true, true
+					continue
+				}
+				for _, part := range m.Parts {
+					if e := d.Element(part.Element); e != nil {
+						g.taken[exported(e.Name)] = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, operations := range services {
+		client := goClient{
+			Name:    exported(operations[0].Service),
+			Port:    operations[0].Port,
+			Address: operations[0].Address,
+			Version: "soap.V11",
+		}
+		if operations[0].Version == soap.V12 {
+			client.Version = "soap.V12"
+		}
+		for _, service := range d.Services {
+			if service.Name == operations[0].Service {
+				client.Doc = oneLine(service.Doc)
+			}
+		}
+		for _, op := range operations {
+			method := goMethod{Name: exported(op.Name), Doc: oneLine(op.Doc), Action: op.Action}
+			var err error
+			if method.Input, err = g.messageType(op, op.Input, false); err != nil {
+				return nil, err
+			}
+			if method.Output, err = g.messageType(op, op.Output, true); err != nil {
+				return nil, err
+			}
+			client.Methods = append(client.Methods, method)
+		}
+		g.clients = append(g.clients, client)
+	}
+
+	names := make([]string, 0, len(g.types))
+	for name := range g.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var types []*goType
+	for _, name := range names {
+		types = append(types, g.types[name])
+	}
+
+	var out bytes.Buffer
+	err := fileTemplate.Execute(&out, map[string]interface{}{
+		"Command": command,
+		"Package": pkg,
+		"Types":   types,
+		"Clients": g.clients,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) serviceOperations(service *wsdl.Service, port string) ([]*wsdl.Operation, error) {
+	var fallback []*wsdl.Operation
+	for _, p := range service.Ports {
+		if port != "" && p.Name != port {
+			continue
+		}
+		operations, err := g.d.PortOperations(service, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(operations) == 0 {
+			continue
+		}
+		if port != "" || operations[0].Version == soap.V11 {
+			return operations, nil
+		}
+		if fallback == nil {
+			fallback = operations
+		}
+	}
+	return fallback, nil
+}
+
+// messageType returns the Go type of the body of m, "" for no body.
+func (g *generator) messageType(op *wsdl.Operation, m *wsdl.Message, output bool) (string, error) {
+	if m == nil || len(m.Parts) == 0 {
+		return "", nil
+	}
+	if op.Style == "rpc" {
+		input, wrapper := op.Wrapper()
+		if !output {
+			wrapper = input
+		}
+		name := exported(wrapper)
+		t := &goType{Name: name, XMLName: op.Namespace + " " + wrapper}
+		for _, part := range m.Parts {
+			field := goField{Name: exported(part.Name), Tag: part.Name}
+			if ct := g.d.ComplexType(part.Type); ct != nil {
+				field.Type = "*" + g.complexType(ct, name+field.Name)
+			} else {
+				field.Type = goBuiltin(g.d.BuiltinType(part.Type))
+			}
+			t.Fields = append(t.Fields, field)
+		}
+		g.types[name] = t
+		return name, nil
+	}
+
+	part := m.Parts[0]
+	if part.Element == "" {
+		return "", fmt.Errorf("%s: document part %s has no element", op.Name, part.Name)
+	}
+	e := g.d.Element(part.Element)
+	if e == nil {
+		return "", fmt.Errorf("%s: element %s not found", op.Name, part.Element)
+	}
+	return g.element(e), nil
+}
+
+// element adds the struct of a global element.
+func (g *generator) element(e *wsdl.Element) string {
+	name := exported(e.Name)
+	if _, ok := g.types[name]; ok {
+		return name
+	}
+	t := &goType{Name: name, XMLName: e.Namespace() + " " + e.Name}
+	g.types[name] = t
+	if ct := g.d.TypeOf(e); ct != nil {
+		t.Fields = g.fields(ct, name)
+	} else {
+		t.Fields = []goField{{Name: "Value", Type: goBuiltin(g.builtin(e)), Tag: ",chardata"}}
+	}
+	return name
+}
+
+// complexType adds the struct of t, named after the type or hint for an
+// anonymous type.
+func (g *generator) complexType(t *wsdl.ComplexType, hint string) string {
+	if name, ok := g.named[t]; ok {
+		return name
+	}
+	name := hint
+	if t.Name != "" {
+		name = exported(t.Name)
+		if g.taken[name] {
+			name += "Type"
+		}
+	}
+	for g.types[name] != nil {
+		name += "_"
+	}
+	g.named[t] = name
+	goT := &goType{Name: name}
+	g.types[name] = goT
+	goT.Fields = g.fields(t, name)
+	return name
+}
+
+func (g *generator) fields(t *wsdl.ComplexType, parent string) []goField {
+	var fields []goField
+	for _, child := range g.d.Children(t) {
+		e := g.d.Deref(child)
+		field := goField{Name: exported(e.Name), Tag: e.Name}
+		if ns := e.Namespace(); ns != "" {
+			field.Tag = ns + " " + e.Name
+		}
+		if child.Optional() {
+			field.Tag += ",omitempty"
+		}
+
+		if ct := g.d.TypeOf(e); ct != nil {
+			field.Type = g.complexType(ct, parent+field.Name)
+			if !child.Repeated() && child.Optional() {
+				field.Type = "*" + field.Type
+			}
+		} else {
+			field.Type = goBuiltin(g.builtin(e))
+		}
+		if child.Repeated() {
+			field.Type = "[]" + field.Type
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func (g *generator) builtin(e *wsdl.Element) string {
+	if e.SimpleType != nil && e.SimpleType.Restriction != nil {
+		return g.d.BuiltinType(e.SimpleType.Restriction.Base)
+	}
+	return g.d.BuiltinType(e.Type)
+}
+
+// goBuiltin maps an XML Schema type to Go. Dates stay strings, xsd:date
+// and xsd:time do not parse as time.Time.
+func goBuiltin(xsd string) string {
+	switch xsd {
+	case "boolean":
+		return "bool"
+	case "int", "integer", "short", "byte", "nonNegativeInteger", "positiveInteger", "negativeInteger", "nonPositiveInteger":
+		return "int"
+	case "long":
+		return "int64"
+	case "unsignedInt", "unsignedShort", "unsignedByte":
+		return "uint"
+	case "unsignedLong":
+		return "uint64"
+	case "float":
+		return "float32"
+	case "double", "decimal":
+		return "float64"
+	case "base64Binary":
+		return "[]byte"
+	}
+	return "string"
+}
+
+// exported returns the Go name of an XML name, "user_id" becomes UserID.
+func exported(name string) string {
+	s := textcase.PascalCase(name)
+	if strings.HasSuffix(s, "Id") {
+		s = strings.TrimSuffix(s, "Id") + "ID"
+	}
+	if s == "" {
+		return "X"
+	}
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by {{.Command}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/mgolfam/gogutils/soap"
+)
+{{range .Types}}
+type {{.Name}} struct {
+{{- if .XMLName}}
+	XMLName xml.Name ` + "`" + `xml:"{{.XMLName}}"` + "`" + `
+{{- end}}
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `xml:"{{.Tag}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- range $c := .Clients}}
+// {{.Name}}Address is the address of the {{.Port}} port.
+const {{.Name}}Address = {{printf "%q" .Address}}
+
+// {{.Name}}Client calls the operations of {{.Name}}.{{if .Doc}} {{.Doc}}{{end}}
+type {{.Name}}Client struct {
+	Client *soap.Client
+}
+
+// New{{.Name}}Client returns a client for address, {{.Name}}Address when
+// empty.
+func New{{.Name}}Client(address string) *{{.Name}}Client {
+	if address == "" {
+		address = {{.Name}}Address
+	}
+	return &{{.Name}}Client{Client: soap.NewClient(address, {{.Version}})}
+}
+{{range .Methods}}
+// {{.Name}} calls the {{.Name}} operation.{{if .Doc}} {{.Doc}}{{end}}
+{{- if .Output}}
+func (c *{{$c.Name}}Client) {{.Name}}(ctx context.Context{{if .Input}}, in *{{.Input}}{{end}}) (*{{.Output}}, error) {
+	out := new({{.Output}})
+	if err := c.Client.Call(ctx, {{printf "%q" .Action}}, {{if .Input}}in{{else}}nil{{end}}, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+{{- else}}
+func (c *{{$c.Name}}Client) {{.Name}}(ctx context.Context{{if .Input}}, in *{{.Input}}{{end}}) error {
+	return c.Client.Call(ctx, {{printf "%q" .Action}}, {{if .Input}}in{{else}}nil{{end}}, nil)
+}
+{{- end}}
+{{end}}
+{{- end}}`))
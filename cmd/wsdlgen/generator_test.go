@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mgolfam/gogutils/soap/wsdl"
+)
+
+func TestGenerateGolden(t *testing.T) {
+	d, err := wsdl.LoadFile("../../soap/wsdl/testdata/users.wsdl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	src, err := generate(d, "users", "", "wsdlgen")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, err := os.ReadFile("testdata/users_client.golden")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(src) != string(expected) {
+		t.Errorf("Generated code differs from testdata/users_client.golden. Got:\n%s", src)
+	}
+
+	src, err = generate(d, "users", "UserPort12", "wsdlgen")
+	if err != nil || !strings.Contains(string(src), "soap.NewClient(address, soap.V12)") || strings.Contains(string(src), "EchoService") {
+		t.Errorf("Expected only a SOAP 1.2 UserService client, Got: %v\n%s", err, src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		wsdl     string
+		expected string
+	}{
+		{name: "no port", wsdl: `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`, expected: "no SOAP port"},
+		{
+			name: "missing element",
+			wsdl: `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/">
+  <message name="In"><part name="p" element="Missing"/></message>
+  <portType name="PT"><operation name="Op"><input message="In"/></operation></portType>
+  <binding name="B" type="PT"><soap:binding style="document"/><operation name="Op"/></binding>
+  <service name="S"><port name="P" binding="B"><soap:address location="http://localhost"/></port></service>
+</definitions>`,
+			expected: "element Missing not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := wsdl.Load([]byte(test.wsdl))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_, err = generate(d, "x", "", "wsdlgen")
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected an error containing %q, Got: %v", test.expected, err)
+			}
+		})
+	}
+}
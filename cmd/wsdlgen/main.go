@@ -0,0 +1,86 @@
+// Command wsdlgen reads a WSDL 1.1 document, from a file or a URL, and
+// lists its operations, prints sample request envelopes or generates Go
+// types with a soap.Client based client per service.
+//
+//	wsdlgen -wsdl partner.wsdl -list
+//	wsdlgen -wsdl http://localhost:8080/users?wsdl -sample GetUser
+//	wsdlgen -wsdl partner.wsdl -pkg partner -output partner_client.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mgolfam/gogutils/soap/wsdl"
+)
+
+func main() {
+	source := flag.String("wsdl", "", "WSDL file or URL, required")
+	list := flag.Bool("list", false, "list the operations")
+	sample := flag.String("sample", "", "print a sample request envelope of the operation")
+	pkg := flag.String("pkg", "main", "package of the generated code")
+	port := flag.String("port", "", "port to generate the clients for, the first SOAP 1.1 port by default")
+	output := flag.String("output", "", "output file, stdout by default")
+	flag.Parse()
+
+	if *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var d *wsdl.Definitions
+	var err error
+	if strings.HasPrefix(*source, "http://") || strings.HasPrefix(*source, "https://") {
+		d, err = wsdl.LoadURL(context.Background(), *source)
+	} else {
+		d, err = wsdl.LoadFile(*source)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	switch {
+	case *list:
+		operations, err := d.Operations()
+		if err != nil {
+			fail(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tPORT\tOPERATION\tVERSION\tSTYLE\tACTION\tADDRESS")
+		for _, op := range operations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", op.Service, op.Port, op.Name, op.Version, op.Style, op.Action, op.Address)
+		}
+		w.Flush()
+	case *sample != "":
+		op, err := d.Operation(*sample)
+		if err != nil {
+			fail(err)
+		}
+		env, err := d.SampleEnvelope(op)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(env))
+	default:
+		src, err := generate(d, *pkg, *port, "wsdlgen")
+		if err != nil {
+			fail(err)
+		}
+		if *output == "" {
+			os.Stdout.Write(src)
+			return
+		}
+		if err := os.WriteFile(*output, src, 0644); err != nil {
+			fail(err)
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "wsdlgen:", err)
+	os.Exit(1)
+}
@@ -49,6 +49,11 @@ func concatenateWithSpace(values ...interface{}) string {
 	return result
 }
 
+// Enabled reports whether messages of the given level are logged.
+func Enabled(level string) bool {
+	return LogLevelMap[level] >= LogLevel.Code
+}
+
 func LogL(level string, v ...interface{}) {
 	if LogLevelMap[level] < LogLevel.Code {
 		return
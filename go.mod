@@ -7,4 +7,8 @@ require (
 	github.com/google/brotli/go/cbrotli v0.0.0-20240715182736-39bcecf4559f
 	github.com/google/uuid v1.6.0
 	golang.org/x/net v0.28.0
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.4.0
 )
+
+require golang.org/x/crypto v0.26.0 // indirect
@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIClient is the runtime of the clients generated by cmd/httpgen. It can
+// be used directly as well, to send builder requests against one base URL
+// and decode JSON answers.
+type APIClient struct {
+	BaseURL string
+	// Client sends the requests, DefaultClient when nil.
+	Client *Client
+	// Headers are sent with every request.
+	Headers map[string]string
+	Timeout time.Duration
+	// DecodeError turns a non 2xx response into an error, a *StatusError
+	// by default.
+	DecodeError func(resp *HttpResponse) error
+}
+
+// StatusError is returned for responses with a non 2xx status.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	body := string(e.Body)
+	if len(body) > 200 {
+		body = body[:200] + "..."
+	}
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.URL, e.StatusCode, strings.TrimSpace(body))
+}
+
+// NewRequest starts a request for path, relative to BaseURL.
+func (a *APIClient) NewRequest(method, path string) *RequestBuilder {
+	b := NewRequest(method, a.BaseURL).Path(path).Client(a.Client)
+	for key, value := range a.Headers {
+		b.Header(key, value)
+	}
+	if a.Timeout > 0 {
+		b.Timeout(a.Timeout)
+	}
+	return b
+}
+
+// Do sends the request and decodes the response into out. out may be nil,
+// a *[]byte for the raw body, a **HttpResponse for the whole response, or
+// any value the JSON body is unmarshaled into.
+func (a *APIClient) Do(ctx context.Context, b *RequestBuilder, out interface{}) error {
+	resp, err := b.SendContext(ctx)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if a.DecodeError != nil {
+			return a.DecodeError(resp)
+		}
+		return &StatusError{Method: resp.Method, URL: resp.Address, StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out = resp.Body
+		return nil
+	case **HttpResponse:
+		*out = resp
+		return nil
+	}
+	if len(resp.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return fmt.Errorf("%s %s: decoding response: %w", resp.Method, resp.Address, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"bad key"}`))
+			return
+		}
+		w.Write([]byte(`{"id":7,"name":"` + r.URL.Query().Get("name") + `"}`))
+	}))
+	defer server.Close()
+
+	api := &APIClient{BaseURL: server.URL, Headers: map[string]string{"X-Api-Key": "secret"}}
+	var user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := api.Do(context.Background(), api.NewRequest("GET", "/users").Query("name", "bob"), &user); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.ID != 7 || user.Name != "bob" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+
+	api.Headers = nil
+	err := api.Do(context.Background(), api.NewRequest("GET", "/users"), nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized || string(statusErr.Body) != `{"error":"bad key"}` {
+		t.Errorf("Expected a StatusError with status 401, Got: %v", err)
+	}
+}
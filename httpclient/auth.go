@@ -0,0 +1,166 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mgolfam/gogutils/glog"
+)
+
+// AuthProvider adds credentials to outbound requests. Providers can be set
+// on a Client, to apply to all of its requests, or on a single config, which
+// takes precedence over the client's provider.
+type AuthProvider interface {
+	Authorize(request *http.Request) error
+}
+
+// Challenger is implemented by providers that can answer a 401 response,
+// such as DigestAuth or OAuth2. HandleChallenge reports whether the request
+// should be sent once more with fresh credentials.
+type Challenger interface {
+	HandleChallenge(request *http.Request, response *http.Response) (bool, error)
+}
+
+// BasicAuth sends the username and password with HTTP Basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Authorize(request *http.Request) error {
+	request.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerToken sends a static bearer token.
+type BearerToken struct {
+	Token string
+}
+
+func (a BearerToken) Authorize(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// authFor returns the provider of the config if set and the client's
+// provider otherwise.
+func (c *Client) authFor(auth AuthProvider) AuthProvider {
+	if auth != nil {
+		return auth
+	}
+	return c.Auth
+}
+
+// doAuthorized sends request through client. When auth is set the request
+// is authorized first, and a 401 answered by a Challenger is retried once.
+func (c *Client) doAuthorized(client *http.Client, request *http.Request, auth AuthProvider) (*http.Response, error) {
+	if auth == nil {
+		return c.send(client, request)
+	}
+
+	if err := auth.Authorize(request); err != nil {
+		return nil, err
+	}
+
+	response, err := c.send(client, request)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	challenger, ok := auth.(Challenger)
+	if !ok || (request.Body != nil && request.Body != http.NoBody && request.GetBody == nil) {
+		return response, nil
+	}
+
+	retry, err := challenger.HandleChallenge(request, response)
+	if err != nil {
+		glog.LogL(glog.WARN, "http auth challenge:", err)
+	}
+	if err != nil || !retry {
+		return response, nil
+	}
+
+	retryRequest := request.Clone(request.Context())
+	if request.GetBody != nil {
+		retryRequest.Body, err = request.GetBody()
+		if err != nil {
+			return response, nil
+		}
+	}
+	if err := auth.Authorize(retryRequest); err != nil {
+		glog.LogL(glog.WARN, "http auth retry:", err)
+		return response, nil
+	}
+
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	c.recordRetry(retryRequest)
+	return c.send(client, retryRequest)
+}
+
+// requestBody returns the body of request without consuming it.
+func requestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewReader(data))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return data, nil
+}
+
+// parseAuthParams parses the comma separated key=value pairs of a
+// WWW-Authenticate challenge, after the scheme has been removed.
+func parseAuthParams(params string) map[string]string {
+	result := make(map[string]string)
+	for len(params) > 0 {
+		params = strings.TrimLeft(params, " ,")
+		eq := strings.IndexByte(params, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(params[:eq]))
+		params = strings.TrimLeft(params[eq+1:], " ")
+
+		var value string
+		if strings.HasPrefix(params, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(params) && params[i] != '"'; i++ {
+				if params[i] == '\\' && i+1 < len(params) {
+					i++
+				}
+				sb.WriteByte(params[i])
+			}
+			value = sb.String()
+			params = params[min(i+1, len(params)):]
+		} else {
+			end := strings.IndexByte(params, ',')
+			if end < 0 {
+				end = len(params)
+			}
+			value = strings.TrimSpace(params[:end])
+			params = params[end:]
+		}
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,277 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBasicAndBearerAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	client := &Client{Auth: BasicAuth{Username: "user", Password: "pass"}}
+	resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(resp.Body) != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Expected basic credentials, Got: %s", resp.Body)
+	}
+
+	// A provider on the config overrides the client's.
+	resp, err = client.SendRequest(HttpConfig{Method: "GET", URL: server.URL, Auth: BearerToken{Token: "t0k"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(resp.Body) != "Bearer t0k" {
+		t.Errorf("Expected bearer token, Got: %s", resp.Body)
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	var revoked int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&revoked) == 1 && r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer api.Close()
+
+	auth := NewClientCredentials(tokenServer.URL, "id", "secret", "read")
+	client := &Client{Auth: auth}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: api.URL})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(resp.Body) != "Bearer token-1" {
+			t.Errorf("Expected cached token, Got: %s", resp.Body)
+		}
+	}
+
+	// The upstream rejects the cached token: a new one is fetched.
+	atomic.StoreInt32(&revoked, 1)
+	resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: api.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(resp.Body) != "Bearer token-2" {
+		t.Errorf("Expected refreshed token, Got: %s", resp.Body)
+	}
+
+	// Tokens close to their expiry are renewed before use.
+	auth.RefreshBefore = 2 * time.Hour
+	if token, _ := auth.Token(); token != "token-3" {
+		t.Errorf("Expected proactive refresh, Got: %s", token)
+	}
+
+	bad := NewClientCredentials(tokenServer.URL, "id", "wrong")
+	if _, err := bad.Token(); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Expected invalid_client error, Got: %v", err)
+	}
+}
+
+func TestOAuth2ClientOwnProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("Expected no credentials on the token request, Got: %s", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"access_token":"self","expires_in":3600}`))
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	// The token request goes through the client whose provider is auth.
+	auth := NewClientCredentials(server.URL+"/token", "id", "secret")
+	auth.CredentialsInBody = true
+	client := &Client{Auth: auth}
+	auth.Client = client
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL + "/api"})
+		if err != nil || string(resp.Body) != "Bearer self" {
+			t.Errorf("Expected Bearer self, Got: %v %v", resp, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the token request not to deadlock")
+	}
+}
+
+func TestOAuth2RefreshTokenRotation(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-for-" + r.Form.Get("refresh_token"),
+			"refresh_token": r.Form.Get("refresh_token") + "+",
+			"expires_in":    1,
+		})
+	}))
+	defer tokenServer.Close()
+
+	auth := NewRefreshTokenFlow(tokenServer.URL, "id", "secret", "r")
+	if token, err := auth.Token(); err != nil || token != "access-for-r" {
+		t.Fatalf("Expected access-for-r, Got: %s %v", token, err)
+	}
+	if token, err := auth.Token(); err != nil || token != "access-for-r+" {
+		t.Errorf("Expected rotated refresh token to be used, Got: %s %v", token, err)
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	const realm, nonce = "test", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	h := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, "Digest ") {
+			p := parseAuthParams(header[7:])
+			ha1 := h("alice:" + realm + ":wonderland")
+			ha2 := h(r.Method + ":" + p["uri"])
+			expected := h(ha1 + ":" + nonce + ":" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+			if p["response"] == expected && p["opaque"] == "xyz" {
+				w.Write([]byte("welcome"))
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Digest realm="`+realm+`", qop="auth,auth-int", nonce="`+nonce+`", opaque="xyz"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	resp, err := SendRequest(HttpConfig{
+		Method: "POST",
+		URL:    server.URL + "/dir/index.html?x=1",
+		Body:   []byte("payload"),
+		Auth:   &DigestAuth{Username: "alice", Password: "wonderland"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "welcome" {
+		t.Errorf("Expected 200 welcome, Got: %d %s", resp.StatusCode, resp.Body)
+	}
+
+	resp, err = SendRequest(HttpConfig{
+		Method: "GET",
+		URL:    server.URL,
+		Auth:   &DigestAuth{Username: "alice", Password: "wrong"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected StatusCode: 401, Got: %d", resp.StatusCode)
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	secret := []byte("s3cr3t")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		r.Body.Read(body)
+		p := parseAuthParams(strings.TrimPrefix(r.Header.Get("X-Signature"), "HMAC "))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(HMACCanonicalString(r, body, strings.Fields(p["headers"]))))
+		if p["keyid"] != "key-1" || p["signature"] != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := SendRequest(HttpConfig{
+		Method:  "PUT",
+		URL:     server.URL + "/items/1?v=2",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    []byte(`{"a":1}`),
+		Auth:    &HMACAuth{KeyID: "key-1", Secret: secret, Header: "X-Signature", SignedHeaders: []string{"Host", "Content-Type"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected StatusCode: 200, Got: %d", resp.StatusCode)
+	}
+}
+
+func TestAWSSigV4(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		// get-vanilla from the AWS Signature Version 4 test suite.
+		{name: "vanilla", url: "https://example.amazonaws.com/", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		// The canonical query sorts by key then value: a=2&a-b=1&a-b=3.
+		{name: "prefix keys", url: "https://example.amazonaws.com/?a-b=3&a=2&a-b=1", signature: "5e0d646d9d645a0929907abc6d7d83e3e325dacb2fcff74ee1a98293e8530d43"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", test.url, nil)
+			auth := &AWSSigV4{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+				Region:          "us-east-1",
+				Service:         "service",
+				Now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+			}
+			if err := auth.Authorize(request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=" + test.signature
+			if got := request.Header.Get("Authorization"); got != expected {
+				t.Errorf("Expected Authorization: %s, Got: %s", expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mgolfam/gogutils/glog"
+)
+
+// BatchRequest is one line of a batch input file. A line is either a JSON
+// object of this shape, a JSON string holding a curl command or a bare
+// curl command. Blank lines and lines starting with # are skipped.
+//
+//	{"id": "user-1", "method": "POST", "url": "https://api.example.com/users",
+//	 "headers": {"Content-Type": "application/json"}, "body": {"name": "bob"},
+//	 "timeout": "5s"}
+//	{"id": "ping", "curl": "curl -s https://api.example.com/ping"}
+//	curl https://api.example.com/health
+type BatchRequest struct {
+	// ID identifies the request in the results, the line number when empty.
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as is when it is a JSON string, any other JSON value is
+	// sent encoded.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Timeout is a duration string such as "5s" or a number of milliseconds.
+	Timeout json.RawMessage `json:"timeout,omitempty"`
+	// Curl is parsed with ParseCurlCommand, the fields above override it.
+	Curl string `json:"curl,omitempty"`
+}
+
+// BatchResult is one line of a batch results file.
+type BatchResult struct {
+	ID         string       `json:"id"`
+	Line       int          `json:"line"`
+	Method     string       `json:"method,omitempty"`
+	URL        string       `json:"url,omitempty"`
+	StatusCode int          `json:"status_code,omitempty"`
+	Attempts   int          `json:"attempts"`
+	DurationMs float64      `json:"duration_ms"`
+	Timing     *BatchTiming `json:"timing,omitempty"`
+	Headers    http.Header  `json:"headers,omitempty"`
+	// Body holds text bodies up to BatchRunner.MaxBody, BodySHA256 and
+	// BodySize are set for every body.
+	Body       string `json:"body,omitempty"`
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	BodySize   int    `json:"body_size"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BatchTiming is the Timing of the last attempt in milliseconds.
+type BatchTiming struct {
+	DNS              float64 `json:"dns_ms"`
+	Connect          float64 `json:"connect_ms"`
+	TLSHandshake     float64 `json:"tls_ms"`
+	ServerProcessing float64 `json:"server_ms"`
+	TimeToFirstByte  float64 `json:"ttfb_ms"`
+	Transfer         float64 `json:"transfer_ms"`
+	Total            float64 `json:"total_ms"`
+	ConnReused       bool    `json:"conn_reused"`
+}
+
+// BatchSummary counts the lines of a batch run.
+type BatchSummary struct {
+	Total     int
+	Skipped   int
+	Succeeded int
+	Failed    int
+}
+
+// BatchRunner sends the requests of a JSONL file and appends one result
+// line per request to a results file. Lines whose ID already has a result
+// are skipped, so an interrupted run continues where it stopped.
+type BatchRunner struct {
+	// Client sends the requests, DefaultClient when nil. Its Limiter and
+	// Breaker apply as usual.
+	Client *Client
+	// Concurrency is the number of requests in flight, 4 when 0.
+	Concurrency int
+	// PerHost caps the requests in flight to one host, 0 means no cap.
+	PerHost int
+	// Retries is the number of extra attempts after a transport error, a
+	// 429 or a 5xx response. They apply to every method, the batch author
+	// decides what is safe to send again.
+	Retries int
+	// RetryBackoff is the delay before the first retry, 500ms when 0. It
+	// doubles with every retry, Retry-After takes precedence.
+	RetryBackoff time.Duration
+	// Timeout applies to requests without their own, 30 seconds when 0.
+	Timeout time.Duration
+	// MaxBody is the largest text body kept in the results, 64KiB when 0.
+	// Larger and binary bodies are only recorded by hash and size.
+	MaxBody int
+	// HashOnly keeps no bodies at all.
+	HashOnly bool
+	// RetryFailed sends the lines again whose last result has an error.
+	RetryFailed bool
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+type batchLine struct {
+	id     string
+	line   int
+	config HttpConfig
+	err    error
+}
+
+// RunFile runs the requests of inputPath, see Run.
+func (r *BatchRunner) RunFile(ctx context.Context, inputPath, resultsPath string) (BatchSummary, error) {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return BatchSummary{}, err
+	}
+	defer input.Close()
+	return r.Run(ctx, input, resultsPath)
+}
+
+// Run reads requests from input and appends their results to resultsPath,
+// which is created when missing. Results are written in completion order.
+// A canceled run returns the context's error. The requests it interrupted
+// get no result, so the next run sends them again.
+func (r *BatchRunner) Run(ctx context.Context, input io.Reader, resultsPath string) (BatchSummary, error) {
+	var summary BatchSummary
+	done, err := r.completed(resultsPath)
+	if err != nil {
+		return summary, err
+	}
+	out, err := os.OpenFile(resultsPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return summary, err
+	}
+	defer out.Close()
+	// Terminate a line truncated by a killed run so the next result starts
+	// on its own line.
+	if info, err := out.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := out.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			out.Write([]byte("\n"))
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+		encoder  = json.NewEncoder(out)
+	)
+	write := func(result *BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result.Error == "" {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		if err := encoder.Encode(result); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	lines := make(chan batchLine)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l := range lines {
+				if result := r.send(ctx, l); result != nil {
+					write(result)
+				}
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for scanner.Scan() && ctx.Err() == nil {
+		n++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		l := r.parseLine(text, n)
+		mu.Lock()
+		summary.Total++
+		if done[l.id] {
+			summary.Skipped++
+			mu.Unlock()
+			continue
+		}
+		mu.Unlock()
+		lines <- l
+	}
+	close(lines)
+	wg.Wait()
+
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	if writeErr != nil {
+		return summary, writeErr
+	}
+	return summary, ctx.Err()
+}
+
+// completed returns the IDs with a result in resultsPath. With RetryFailed
+// only the IDs whose last result succeeded count.
+func (r *BatchRunner) completed(resultsPath string) (map[string]bool, error) {
+	done := map[string]bool{}
+	file, err := os.Open(resultsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var result struct {
+			ID    string `json:"id"`
+			Error string `json:"error"`
+		}
+		// A run killed mid-write leaves a truncated last line, that
+		// request is simply sent again.
+		if json.Unmarshal(scanner.Bytes(), &result) != nil || result.ID == "" {
+			continue
+		}
+		done[result.ID] = !r.RetryFailed || result.Error == ""
+	}
+	return done, scanner.Err()
+}
+
+func (r *BatchRunner) parseLine(text string, n int) batchLine {
+	l := batchLine{id: strconv.Itoa(n), line: n}
+	var req BatchRequest
+	switch {
+	case strings.HasPrefix(text, "curl "):
+		req.Curl = text
+	case strings.HasPrefix(text, `"`):
+		l.err = json.Unmarshal([]byte(text), &req.Curl)
+	default:
+		l.err = json.Unmarshal([]byte(text), &req)
+	}
+	if req.ID != "" {
+		l.id = req.ID
+	}
+	if l.err == nil {
+		l.config, l.err = req.config()
+	}
+	if l.config.Timeout == 0 {
+		l.config.Timeout = r.Timeout
+		if l.config.Timeout == 0 {
+			l.config.Timeout = 30 * time.Second
+		}
+	}
+	return l
+}
+
+// config resolves req into an HttpConfig.
+func (req *BatchRequest) config() (HttpConfig, error) {
+	config := HttpConfig{Method: "GET"}
+	if req.Curl != "" {
+		parsed, err := ParseCurlCommand(req.Curl)
+		if err != nil {
+			return config, err
+		}
+		config = *parsed
+	}
+	if req.Method != "" {
+		config.Method = strings.ToUpper(req.Method)
+	}
+	if req.URL != "" {
+		config.URL = req.URL
+	}
+	if config.URL == "" {
+		return config, errors.New("batch request has no url")
+	}
+	if len(req.Headers) > 0 && config.Headers == nil {
+		config.Headers = map[string]string{}
+	}
+	for key, value := range req.Headers {
+		config.Headers[key] = value
+	}
+
+	if len(req.Body) > 0 && string(req.Body) != "null" {
+		var text string
+		if json.Unmarshal(req.Body, &text) == nil {
+			config.Body = []byte(text)
+		} else {
+			config.Body = append([]byte(nil), req.Body...)
+			if !hasHeaderFold(config.Headers, "Content-Type") {
+				if config.Headers == nil {
+					config.Headers = map[string]string{}
+				}
+				config.Headers["Content-Type"] = "application/json"
+			}
+		}
+	}
+
+	if len(req.Timeout) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(req.Timeout, &value); err != nil {
+			return config, err
+		}
+		switch value := value.(type) {
+		case string:
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("batch request timeout: %w", err)
+			}
+			config.Timeout = timeout
+		case float64:
+			config.Timeout = time.Duration(value * float64(time.Millisecond))
+		}
+	}
+	return config, nil
+}
+
+// send sends l and returns its result, nil when the run stopped before the
+// request completed.
+func (r *BatchRunner) send(ctx context.Context, l batchLine) *BatchResult {
+	result := &BatchResult{ID: l.id, Line: l.line, Method: l.config.Method, URL: l.config.URL}
+	if l.err != nil {
+		result.Error = l.err.Error()
+		return result
+	}
+
+	client := r.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	release, err := r.acquireHost(ctx, l.config.URL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		result.Error = err.Error()
+		return result
+	}
+	defer release()
+
+	backoff := r.RetryBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	start := time.Now()
+	var resp *HttpResponse
+	for {
+		result.Attempts++
+		resp, err = client.SendRequestContext(ctx, l.config)
+		if !r.retryable(resp, err) || result.Attempts > r.Retries {
+			break
+		}
+		delay := backoff
+		if resp != nil && resp.Header != nil {
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = after
+			}
+		}
+		glog.LogL(glog.DEBUG, "batch", l.id, "retrying in", delay, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if u, err := url.Parse(l.config.URL); err == nil {
+			client.recordRetry(&http.Request{Method: l.config.Method, URL: u})
+		}
+		backoff *= 2
+	}
+	result.DurationMs = msDuration(time.Since(start))
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		result.Error = err.Error()
+		return result
+	}
+	if r.retryable(resp, nil) {
+		// A 429 or 5xx left after all retries fails the line, so
+		// RetryFailed sends it again.
+		result.Error = (&StatusError{Method: l.config.Method, URL: l.config.URL, StatusCode: resp.StatusCode, Body: resp.Body}).Error()
+	}
+	result.StatusCode = resp.StatusCode
+	result.Headers = resp.Header
+	result.Timing = newBatchTiming(resp.Timing)
+	r.setBody(result, resp.Body)
+	return result
+}
+
+func (r *BatchRunner) retryable(resp *HttpResponse, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCircuitOpen)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// acquireHost takes one of the PerHost slots of rawURL's host.
+func (r *BatchRunner) acquireHost(ctx context.Context, rawURL string) (func(), error) {
+	if r.PerHost <= 0 {
+		return func() {}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.hosts == nil {
+		r.hosts = map[string]chan struct{}{}
+	}
+	slots, ok := r.hosts[u.Host]
+	if !ok {
+		slots = make(chan struct{}, r.PerHost)
+		r.hosts[u.Host] = slots
+	}
+	r.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *BatchRunner) setBody(result *BatchResult, body []byte) {
+	result.BodySize = len(body)
+	if len(body) == 0 {
+		return
+	}
+	sum := sha256.Sum256(body)
+	result.BodySHA256 = hex.EncodeToString(sum[:])
+
+	maxBody := r.MaxBody
+	if maxBody <= 0 {
+		maxBody = 64 * 1024
+	}
+	if !r.HashOnly && len(body) <= maxBody && utf8.Valid(body) {
+		result.Body = string(body)
+	}
+}
+
+func newBatchTiming(t Timing) *BatchTiming {
+	return &BatchTiming{
+		DNS:              msDuration(t.DNS),
+		Connect:          msDuration(t.Connect),
+		TLSHandshake:     msDuration(t.TLSHandshake),
+		ServerProcessing: msDuration(t.ServerProcessing),
+		TimeToFirstByte:  msDuration(t.TimeToFirstByte),
+		Transfer:         msDuration(t.Transfer),
+		Total:            msDuration(t.Total),
+		ConnReused:       t.ConnReused,
+	}
+}
+
+func msDuration(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func hasHeaderFold(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
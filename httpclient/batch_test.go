@@ -0,0 +1,241 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+func readBatchResults(t *testing.T, path string) map[string]BatchResult {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	results := map[string]BatchResult{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Invalid result line %q: %v", scanner.Text(), err)
+		}
+		results[result.ID] = result
+	}
+	return results
+}
+
+func TestBatchRunner(t *testing.T) {
+	var flaky, inFlight, maxInFlight int32
+	var mu sync.Mutex
+	bodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = r.Header.Get("Content-Type") + " " + string(data)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&flaky, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/binary":
+			w.Write([]byte{0xff, 0xfe, 0x00})
+			return
+		}
+		w.Header().Add("Link", "<a>")
+		w.Header().Add("Link", "<b>")
+		w.Write([]byte("ok " + r.Method + " " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	input := strings.Join([]string{
+		`# users`,
+		`{"id": "create", "method": "post", "url": "` + server.URL + `/users", "body": {"name": "bob"}, "timeout": "2s"}`,
+		``,
+		`{"id": "flaky", "url": "` + server.URL + `/flaky", "timeout": 2000}`,
+		`curl -X PUT ` + server.URL + `/raw -d 'x=1'`,
+		`"curl ` + server.URL + `/binary"`,
+		`{"id": "broken", "url": ""}`,
+		`{"id": "a", "url": "` + server.URL + `/a"}`,
+		`{"id": "b", "url": "` + server.URL + `/b"}`,
+	}, "\n")
+
+	resultsPath := filepath.Join(t.TempDir(), "results.jsonl")
+	runner := &BatchRunner{Concurrency: 4, PerHost: 2, Retries: 2, RetryBackoff: time.Millisecond}
+	summary, err := runner.Run(context.Background(), strings.NewReader(input), resultsPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := BatchSummary{Total: 7, Succeeded: 6, Failed: 1}
+	if summary != expected {
+		t.Errorf("Expected summary %+v, Got: %+v", expected, summary)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in flight, Got: %d", maxInFlight)
+	}
+
+	results := readBatchResults(t, resultsPath)
+	tests := []struct {
+		id       string
+		status   int
+		attempts int
+		body     string
+		hasError bool
+	}{
+		{id: "create", status: 200, attempts: 1, body: "ok POST /users"},
+		{id: "flaky", status: 200, attempts: 3, body: "ok GET /flaky"},
+		{id: "5", status: 200, attempts: 1, body: "ok PUT /raw"},
+		{id: "6", status: 200, attempts: 1},
+		{id: "broken", hasError: true},
+	}
+	for _, test := range tests {
+		result := results[test.id]
+		if result.StatusCode != test.status || result.Attempts != test.attempts || result.Body != test.body || (result.Error != "") != test.hasError {
+			t.Errorf("Unexpected result for %s: %+v", test.id, result)
+		}
+	}
+	if results["6"].BodySize != 3 || len(results["6"].BodySHA256) != 64 {
+		t.Errorf("Expected the binary body by hash, Got: %+v", results["6"])
+	}
+	if links := results["create"].Headers["Link"]; len(links) != 2 || results["create"].Timing == nil {
+		t.Errorf("Expected both Link headers and a timing, Got: %+v", results["create"])
+	}
+	if bodies["/users"] != `application/json {"name": "bob"}` {
+		t.Errorf("Unexpected request body: %q", bodies["/users"])
+	}
+
+	// A second run only sends the lines without a result.
+	os.WriteFile(resultsPath, []byte(strings.Join(strings.Split(readFile(t, resultsPath), "\n")[:4], "\n")+"\n{\"id\": \"trunc"), 0644)
+	summary, err = (&BatchRunner{}).Run(context.Background(), strings.NewReader(input), resultsPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Skipped != 4 || summary.Succeeded+summary.Failed != 3 {
+		t.Errorf("Expected 4 skipped lines, Got: %+v", summary)
+	}
+	if results := readBatchResultsLenient(t, resultsPath); len(results) != 7 {
+		t.Errorf("Expected 7 results after resuming, Got: %d", len(results))
+	}
+}
+
+func TestBatchRunnerResume(t *testing.T) {
+	var down, slow int32 = 1, 0
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			if atomic.AddInt32(&slow, 1) == 1 {
+				close(started)
+				<-r.Context().Done()
+				return
+			}
+		case "/down":
+			if atomic.LoadInt32(&down) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	input := strings.Join([]string{
+		`{"id": "down", "url": "` + server.URL + `/down"}`,
+		`{"id": "slow", "url": "` + server.URL + `/slow"}`,
+	}, "\n")
+	resultsPath := filepath.Join(t.TempDir(), "results.jsonl")
+	registry := metrics.NewRegistry()
+	runner := &BatchRunner{
+		Client:       &Client{Metrics: NewClientMetrics(registry)},
+		Concurrency:  1,
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	}
+
+	// The run is canceled while /slow is in flight, after down got its
+	// result.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+	summary, err := runner.Run(ctx, strings.NewReader(input), resultsPath)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, Got: %v", err)
+	}
+	results := readBatchResults(t, resultsPath)
+	if _, ok := results["slow"]; ok || len(results) != 1 {
+		t.Errorf("Expected no result for the canceled request, Got: %+v", results)
+	}
+	// A 503 left after the retries is a failure.
+	if result := results["down"]; result.StatusCode != http.StatusServiceUnavailable || result.Attempts != 2 || result.Error == "" || summary.Failed != 1 {
+		t.Errorf("Expected down to fail after 2 attempts, Got: %+v, %+v", result, summary)
+	}
+	host := hostOf(server.URL)
+	if sample, ok := registry.Find("httpclient_retries_total", map[string]string{"host": host, "method": "GET"}); !ok || sample.Value != 1 {
+		t.Errorf("Expected 1 retry counted, Got: %v", sample.Value)
+	}
+
+	// Resuming sends the canceled line, and the failed one with RetryFailed.
+	atomic.StoreInt32(&down, 0)
+	runner.RetryFailed = true
+	summary, err = runner.Run(context.Background(), strings.NewReader(input), resultsPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := (BatchSummary{Total: 2, Succeeded: 2}); summary != expected {
+		t.Errorf("Expected summary %+v, Got: %+v", expected, summary)
+	}
+	results = readBatchResults(t, resultsPath)
+	if results["slow"].Body != "ok /slow" || results["down"].Error != "" {
+		t.Errorf("Expected both lines to succeed, Got: %+v", results)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return string(data)
+}
+
+// readBatchResultsLenient skips lines that are not results, such as the
+// line left by a killed run.
+func readBatchResultsLenient(t *testing.T, path string) map[string]BatchResult {
+	results := map[string]BatchResult{}
+	for _, line := range strings.Split(readFile(t, path), "\n") {
+		var result BatchResult
+		if json.Unmarshal([]byte(line), &result) == nil && result.ID != "" {
+			results[result.ID] = result
+		}
+	}
+	return results
+}
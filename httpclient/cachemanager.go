@@ -11,6 +11,10 @@ import (
 	"github.com/mgolfam/gogutils/filemanager"
 )
 
+// cacheVersion is bumped whenever the on-disk layout of HttpResponse changes.
+// Entries written with another version are treated as a cache miss.
+const cacheVersion = 3
+
 // Function to convert headers map to a sorted string
 func headersToString(headers map[string]string) string {
 	var result strings.Builder
@@ -47,6 +51,7 @@ func makeHash(config HttpConfig) string {
 func (resp *HttpResponse) SerializeCache(hash string) error {
 	path := "http-cache/" + hash + ".json"
 	filemanager.MkDir("http-cache/")
+	resp.CacheVersion = cacheVersion
 	data, err := json.MarshalIndent(resp, "", "  ")
 	if err != nil {
 		return err
@@ -66,6 +71,12 @@ func (resp *HttpResponse) DeserializeCache(hash string) error {
 
 	err = json.Unmarshal(data, resp)
 
+	if err == nil && resp.CacheVersion != cacheVersion {
+		filemanager.DeleteFile(path)
+		*resp = HttpResponse{}
+		return errors.New("cache version mismatch.")
+	}
+
 	if resp.IsCacheEXpired() {
 		filemanager.DeleteFile(path)
 		return errors.New("cache has been expired.")
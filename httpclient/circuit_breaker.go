@@ -0,0 +1,280 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+)
+
+// ErrCircuitOpen is matched by the BreakerOpenError returned while a
+// host's breaker is open.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker is open")
+
+// BreakerOpenError is returned without contacting the host while its
+// breaker is open.
+type BreakerOpenError struct {
+	Host string
+	// RetryAfter is the time left until probes are let through.
+	RetryAfter time.Duration
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("httpclient: circuit breaker for %s is open, retry in %s", e.Host, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState is the state of a host's breaker.
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings configures a CircuitBreaker. At least one of
+// ConsecutiveFailures and ErrorRate should be set.
+type BreakerSettings struct {
+	// ConsecutiveFailures opens the breaker after that many failures in a
+	// row.
+	ConsecutiveFailures int
+	// ErrorRate opens the breaker when the share of failures within Window
+	// reaches it, once MinRequests calls were made in the window.
+	ErrorRate   float64
+	Window      time.Duration
+	MinRequests int
+	// OpenTimeout is how long the breaker stays open before it goes
+	// half-open and lets probes through, 30 seconds when 0.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probe calls allowed while half-open,
+	// all of which must succeed to close the breaker again. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure classifies the outcome of a call. By default transport
+	// errors and 5xx responses are failures.
+	IsFailure func(response *http.Response, err error) bool
+	// OnStateChange is called, besides logging, on every transition. It
+	// runs after the breaker's lock is released, so it may call State.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+// CircuitBreaker tracks failures per host and stops calling hosts that
+// keep failing.
+type CircuitBreaker struct {
+	settings BreakerSettings
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+	// changes are the transitions made under mu, reported by unlock.
+	changes []stateChange
+}
+
+type stateChange struct {
+	host     string
+	from, to BreakerState
+}
+
+type hostBreaker struct {
+	state       BreakerState
+	generation  uint64
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	if settings.Window <= 0 {
+		settings.Window = time.Minute
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = 10
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = 30 * time.Second
+	}
+	if settings.HalfOpenProbes <= 0 {
+		settings.HalfOpenProbes = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(response *http.Response, err error) bool {
+			return err != nil || response.StatusCode >= 500
+		}
+	}
+	return &CircuitBreaker{settings: settings, hosts: make(map[string]*hostBreaker)}
+}
+
+// State returns the current state of host's breaker.
+func (b *CircuitBreaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+
+	hb, ok := b.hosts[host]
+	if !ok {
+		return StateClosed
+	}
+	b.advance(host, hb, time.Now())
+	return hb.state
+}
+
+// allow reports whether a call to host may go out. The generation is handed
+// back to record, so outcomes of calls started before a transition are
+// ignored.
+func (b *CircuitBreaker) allow(host string) (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	hb, ok := b.hosts[host]
+	if !ok {
+		hb = &hostBreaker{windowStart: time.Now()}
+		b.hosts[host] = hb
+	}
+
+	now := time.Now()
+	b.advance(host, hb, now)
+
+	switch hb.state {
+	case StateOpen:
+		return 0, &BreakerOpenError{Host: host, RetryAfter: hb.openedAt.Add(b.settings.OpenTimeout).Sub(now)}
+	case StateHalfOpen:
+		if hb.probes >= b.settings.HalfOpenProbes {
+			return 0, &BreakerOpenError{Host: host}
+		}
+		hb.probes++
+	}
+	return hb.generation, nil
+}
+
+func (b *CircuitBreaker) record(host string, generation uint64, response *http.Response, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	hb := b.hosts[host]
+	now := time.Now()
+	b.advance(host, hb, now)
+	if hb.generation != generation {
+		return
+	}
+
+	// The caller gave up, which says nothing about the host: the probe slot
+	// taken by allow is given back.
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited) {
+		if hb.state == StateHalfOpen && hb.probes > 0 {
+			hb.probes--
+		}
+		return
+	}
+	failure := b.settings.IsFailure(response, err)
+
+	switch hb.state {
+	case StateHalfOpen:
+		if failure {
+			b.transition(host, hb, StateOpen, now)
+			return
+		}
+		hb.successes++
+		if hb.successes >= b.settings.HalfOpenProbes {
+			b.transition(host, hb, StateClosed, now)
+		}
+
+	case StateClosed:
+		hb.requests++
+		if failure {
+			hb.failures++
+			hb.consecutive++
+		} else {
+			hb.consecutive = 0
+		}
+
+		s := b.settings
+		if s.ConsecutiveFailures > 0 && hb.consecutive >= s.ConsecutiveFailures {
+			b.transition(host, hb, StateOpen, now)
+		} else if s.ErrorRate > 0 && hb.requests >= s.MinRequests &&
+			float64(hb.failures)/float64(hb.requests) >= s.ErrorRate {
+			b.transition(host, hb, StateOpen, now)
+		}
+	}
+}
+
+// advance moves an open breaker to half-open once OpenTimeout passed and
+// starts a new error rate window when the current one is over.
+func (b *CircuitBreaker) advance(host string, hb *hostBreaker, now time.Time) {
+	if hb.state == StateOpen && !now.Before(hb.openedAt.Add(b.settings.OpenTimeout)) {
+		b.transition(host, hb, StateHalfOpen, now)
+	}
+	if hb.state == StateClosed && now.Sub(hb.windowStart) >= b.settings.Window {
+		hb.windowStart = now
+		hb.requests = 0
+		hb.failures = 0
+	}
+}
+
+func (b *CircuitBreaker) transition(host string, hb *hostBreaker, to BreakerState, now time.Time) {
+	from := hb.state
+	hb.state = to
+	hb.generation++
+	hb.consecutive = 0
+	hb.requests = 0
+	hb.failures = 0
+	hb.probes = 0
+	hb.successes = 0
+	hb.windowStart = now
+	if to == StateOpen {
+		hb.openedAt = now
+	}
+
+	b.changes = append(b.changes, stateChange{host: host, from: from, to: to})
+}
+
+// unlock releases mu, then reports the transitions made while it was held.
+func (b *CircuitBreaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	for _, change := range changes {
+		glog.LogL(glog.WARN, "circuit breaker", change.host, change.from, "->", change.to)
+		if b.settings.OnStateChange != nil {
+			b.settings.OnStateChange(change.host, change.from, change.to)
+		}
+	}
+}
+
+// guard applies the client's circuit breaker around send.
+func (c *Client) guard(request *http.Request, send func() (*http.Response, error)) (*http.Response, error) {
+	if c.Breaker == nil {
+		return send()
+	}
+
+	host := request.URL.Host
+	generation, err := c.Breaker.allow(host)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := send()
+	c.Breaker.record(host, generation, response, err)
+	return response, err
+}
@@ -0,0 +1,189 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	var failing int32 = 1
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	var transitions []string
+	breaker := NewCircuitBreaker(BreakerSettings{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		OnStateChange: func(host string, from, to BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	client := &Client{Breaker: breaker}
+	host := hostOf(server.URL)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if state := breaker.State(host); state != StateOpen {
+		t.Fatalf("Expected state: open, Got: %s", state)
+	}
+
+	_, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	var openErr *BreakerOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openErr) || openErr.Host != host {
+		t.Errorf("Expected a BreakerOpenError for %s, Got: %v", host, err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected the open breaker to fail fast, Got: %d calls", calls)
+	}
+
+	// A failed probe opens the breaker again.
+	time.Sleep(60 * time.Millisecond)
+	client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	if state := breaker.State(host); state != StateOpen {
+		t.Errorf("Expected state after failed probe: open, Got: %s", state)
+	}
+
+	// A successful probe closes it.
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state := breaker.State(host); state != StateClosed {
+		t.Errorf("Expected state after probe: closed, Got: %s", state)
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions: %v, Got: %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transitions: %v, Got: %v", expected, transitions)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerSettings{ErrorRate: 0.5, MinRequests: 4})
+	host := "api.example.com"
+	ok := &http.Response{StatusCode: http.StatusOK}
+	failed := &http.Response{StatusCode: http.StatusInternalServerError}
+
+	for _, response := range []*http.Response{ok, failed, ok} {
+		generation, err := breaker.allow(host)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		breaker.record(host, generation, response, nil)
+	}
+	if state := breaker.State(host); state != StateClosed {
+		t.Fatalf("Expected state below MinRequests: closed, Got: %s", state)
+	}
+
+	generation, _ := breaker.allow(host)
+	breaker.record(host, generation, failed, nil)
+	if state := breaker.State(host); state != StateOpen {
+		t.Errorf("Expected state at 50%% errors: open, Got: %s", state)
+	}
+
+	// Refused calls do not count as failures.
+	other := NewCircuitBreaker(BreakerSettings{ConsecutiveFailures: 1})
+	generation, _ = other.allow(host)
+	other.record(host, generation, nil, ErrRateLimited)
+	if state := other.State(host); state != StateClosed {
+		t.Errorf("Expected rate limited calls to be ignored, Got: %s", state)
+	}
+}
+
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	var failing, slow int32 = 1, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(BreakerSettings{ConsecutiveFailures: 1, OpenTimeout: 20 * time.Millisecond})
+	client := &Client{Breaker: breaker}
+	host := hostOf(server.URL)
+
+	client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(30 * time.Millisecond)
+
+	// The only probe is canceled, which leaves the breaker half-open.
+	atomic.StoreInt32(&slow, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := client.SendRequestContext(ctx, HttpConfig{Method: "GET", URL: server.URL}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, Got: %v", err)
+	}
+	if state := breaker.State(host); state != StateHalfOpen {
+		t.Fatalf("Expected state: half-open, Got: %s", state)
+	}
+
+	// Its slot is free for the next probe.
+	atomic.StoreInt32(&slow, 0)
+	if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state := breaker.State(host); state != StateClosed {
+		t.Errorf("Expected state after probe: closed, Got: %s", state)
+	}
+}
+
+func TestCircuitBreakerCallbackCallsState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var breaker *CircuitBreaker
+	states := make(chan BreakerState, 1)
+	breaker = NewCircuitBreaker(BreakerSettings{
+		ConsecutiveFailures: 1,
+		OnStateChange: func(host string, from, to BreakerState) {
+			states <- breaker.State(host)
+		},
+	})
+	client := &Client{Breaker: breaker}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the callback not to deadlock the breaker")
+	}
+	if state := <-states; state != StateOpen {
+		t.Errorf("Expected state in the callback: open, Got: %s", state)
+	}
+}
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"mime/multipart"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mgolfam/gogutils/filemanager"
 	"github.com/mgolfam/gogutils/glog"
 
 	"github.com/mgolfam/gogutils/utils"
@@ -21,6 +23,108 @@ import (
 
 const UserAgent = "gogutils_client/v0.2.1"
 
+// Client carries state that is shared between requests, such as the cookie
+// jar. A zero Client is ready to use; the package level functions send
+// through DefaultClient.
+type Client struct {
+	// Jar stores cookies received from and sent to upstreams. It is nil by
+	// default, use NewSession to get a client with a jar.
+	Jar *CookieJar
+	// Auth authorizes every request of the client that does not carry its
+	// own provider in the config.
+	Auth AuthProvider
+	// TLS applies to every request of the client that does not carry its
+	// own TLS settings in the config.
+	TLS *TLSConfig
+	// Metrics receives the request metrics, DefaultMetrics when nil.
+	Metrics *ClientMetrics
+	// Tracer is told about the span of every call. The traceparent header
+	// is sent when Tracer is set or the call's context carries a span.
+	Tracer SpanHook
+	// Limiter throttles calls per host or URL prefix.
+	Limiter *RateLimiter
+	// Breaker fails calls fast while their host keeps failing.
+	Breaker *CircuitBreaker
+}
+
+// DefaultClient is used by SendRequest, SendMultipartFormData, MultipartData,
+// SoapCall and Download.
+var DefaultClient = &Client{}
+
+// NewClient returns a Client without a cookie jar.
+func NewClient() *Client {
+	return &Client{}
+}
+
+// NewSession returns a Client with an empty cookie jar, so cookies set by one
+// response are sent with the following requests.
+func NewSession() *Client {
+	return &Client{Jar: NewCookieJar()}
+}
+
+// LoadSession returns a session whose cookie jar is loaded from jarPath. A
+// missing file is not an error, the session simply starts empty.
+func LoadSession(jarPath string) (*Client, error) {
+	session := NewSession()
+	if !filemanager.FileDirExist(jarPath) {
+		return session, nil
+	}
+
+	if err := session.Jar.Load(jarPath); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SaveSession writes the client's cookie jar to jarPath, see CookieJar.Save.
+func (c *Client) SaveSession(jarPath string) error {
+	if c.Jar == nil {
+		return errors.New("client has no cookie jar")
+	}
+	return c.Jar.Save(jarPath)
+}
+
+// httpClient builds the net/http client for a single call with the state
+// kept on c. tlsConfig overrides the client's TLS settings when not nil.
+func (c *Client) httpClient(timeout time.Duration, useProxy bool, tlsConfig *TLSConfig) (*http.Client, error) {
+	client := &http.Client{
+		Timeout: timeout,
+	}
+
+	if c.Jar != nil {
+		client.Jar = c.Jar
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = c.TLS
+	}
+
+	if useProxy {
+		transport, err := getTransport()
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig != nil {
+			transport.TLSClientConfig, err = tlsConfig.Load()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		client.Transport = transport
+	} else if tlsConfig != nil {
+		transport, err := tlsConfig.httpTransport()
+		if err != nil {
+			return nil, err
+		}
+
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
 // HTTPClientConfig contains the configuration for the HTTP client.
 type HttpConfig struct {
 	Method        string
@@ -33,12 +137,16 @@ type HttpConfig struct {
 	RetrieveCache bool
 	CacheTtl      int64
 	UseProxy      bool
+	Auth          AuthProvider
+	TLS           *TLSConfig
 }
 
 type FormDataField struct {
 	Name  string
 	Value string
 	Text  bool
+	// FileName is the file name sent for a file field, Value by default.
+	FileName string
 }
 
 type FormDataConfig struct {
@@ -47,6 +155,8 @@ type FormDataConfig struct {
 	Headers map[string]string
 	Timeout time.Duration
 	Fields  []FormDataField
+	Auth    AuthProvider
+	TLS     *TLSConfig
 }
 
 type HttpResponse struct {
@@ -54,24 +164,47 @@ type HttpResponse struct {
 	Method      string
 	ElapsedTime int64
 	StatusCode  int
-	Headers     map[string]string
-	Body        []byte
-	FromCache   bool
-	CreatedUnix int64
-	CacheTtl    int64
+	// Headers keeps the first value of every response header. Use Header
+	// for repeated headers such as Set-Cookie, Link or Vary.
+	Headers      map[string]string
+	Header       http.Header
+	Trailer      http.Header
+	Cookies      []*http.Cookie
+	FinalURL     string
+	Proto        string
+	Body         []byte
+	Timing       Timing
+	FromCache    bool
+	CreatedUnix  int64
+	CacheTtl     int64
+	CacheVersion int
 }
 
 func (resp *HttpResponse) IsCacheEXpired() bool {
 	return utils.NowUnixSeconds() > resp.CreatedUnix+resp.CacheTtl
 }
 
-// SoapConfig contains the configuration for the SOAP client.
+// setMeta copies the full header set, trailers, cookies, final URL and
+// protocol version from response. Call it after the body has been read,
+// trailers are only populated once the body reaches EOF.
+func (resp *HttpResponse) setMeta(response *http.Response) {
+	resp.Header = response.Header.Clone()
+	resp.Trailer = response.Trailer.Clone()
+	resp.Cookies = response.Cookies()
+	resp.FinalURL = finalURL(response)
+	resp.Proto = response.Proto
+}
+
+// SoapConfig contains the configuration for the SOAP client. The body is
+// sent as is, package soap builds typed envelopes on top of SoapCall.
 type SoapConfig struct {
 	URL     string
 	Headers map[string]string
 	Body    string
 	Timeout time.Duration
 	LogSoap bool
+	Auth    AuthProvider
+	TLS     *TLSConfig
 }
 
 // SoapResponse represents the response from a SOAP call.
@@ -79,13 +212,36 @@ type SoapResponse struct {
 	ElapsedTime int64
 	StatusCode  int
 	Headers     map[string]string
+	Header      http.Header
+	Trailer     http.Header
+	Cookies     []*http.Cookie
+	FinalURL    string
+	Proto       string
 	Body        string
+	Timing      Timing
 }
 
 func (resp *SoapResponse) IsSuccess() bool {
 	return resp.StatusCode >= 200 && resp.StatusCode < 300
 }
 
+// setMeta is the SoapResponse counterpart of HttpResponse.setMeta.
+func (resp *SoapResponse) setMeta(response *http.Response) {
+	resp.Header = response.Header.Clone()
+	resp.Trailer = response.Trailer.Clone()
+	resp.Cookies = response.Cookies()
+	resp.FinalURL = finalURL(response)
+	resp.Proto = response.Proto
+}
+
+// finalURL returns the URL of the last request in the redirect chain.
+func finalURL(response *http.Response) string {
+	if response.Request == nil || response.Request.URL == nil {
+		return ""
+	}
+	return response.Request.URL.String()
+}
+
 func getProxyDialer() (proxy.Dialer, error) {
 	// proxyUrl := ""
 	// if config.Config.App.Proxy.Port == "" {
@@ -117,41 +273,30 @@ func getTransport() (*http.Transport, error) {
 	return transport, err
 }
 
+// do runs request through the client's pipeline: tracing, rate limiting,
+// authorization and metrics.
+func (c *Client) do(client *http.Client, request *http.Request, auth AuthProvider) (*http.Response, error) {
+	span := c.startSpan(request)
+	response, err := c.guard(request, func() (*http.Response, error) {
+		return c.limit(request, func() (*http.Response, error) {
+			return c.doAuthorized(client, request, auth)
+		})
+	})
+	c.endSpan(span, response, err)
+	return response, err
+}
+
 func SendMultipartFormData(config FormDataConfig) (*HttpResponse, error) {
+	return DefaultClient.SendMultipartFormData(config)
+}
+
+func (c *Client) SendMultipartFormData(config FormDataConfig) (*HttpResponse, error) {
 	payload := &bytes.Buffer{}
 	writer := multipart.NewWriter(payload)
 
 	config.Headers["Content-Type"] = writer.FormDataContentType()
-	// files := make([]*os.File, 0)
-	for i := 0; i < len(config.Fields); i++ {
-		// for loop over all form data fields.
-		field := config.Fields[i]
-
-		// we separate files and text fields.
-		if !field.Text {
-			// creating form data file in bin
-			file, err := os.Open(field.Value)
-			if err == nil {
-				formFile, eff := writer.CreateFormFile(field.Name, field.Value)
-				if eff != nil {
-					glog.LogL(glog.ERROR, eff)
-				}
-				_, eff = io.Copy(formFile, file)
-
-				if eff != nil {
-					glog.LogL(glog.ERROR, eff)
-				}
-			}
-			defer file.Close()
-		} else {
-			// creatnig form data field
-			formField, eff := writer.CreateFormField(field.Name)
-			if eff != nil {
-				glog.LogL(glog.ERROR, eff)
-			}
-
-			formField.Write([]byte(field.Value))
-		}
+	if err := writeFormFields(writer, config.Fields); err != nil {
+		glog.LogL(glog.ERROR, err)
 	}
 
 	err := writer.Close()
@@ -159,7 +304,11 @@ func SendMultipartFormData(config FormDataConfig) (*HttpResponse, error) {
 		glog.LogL(glog.ERROR, err)
 	}
 
-	client := &http.Client{}
+	client, err := c.httpClient(config.Timeout, false, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest(config.Method, config.URL, payload)
 	if err != nil {
 		glog.LogL(glog.ERROR, err)
@@ -172,20 +321,66 @@ func SendMultipartFormData(config FormDataConfig) (*HttpResponse, error) {
 
 	// Send the HTTP request
 	glog.LogL(glog.DEBUG, "http multipart ->", config.Method, config.URL)
+	timer := newRequestTimer()
 	startTime := time.Now()
-	response, err := client.Do(req)
+	response, err := c.do(client, timer.trace(req), c.authFor(config.Auth))
 	if err != nil {
 		return nil, err
 	}
 	elapsedTime := time.Since(startTime)
-	return makeResponse(config.Method, config.URL, response, elapsedTime)
+	return makeResponse(config.Method, config.URL, response, elapsedTime, timer)
+}
+
+// writeFormFields writes text fields and files, by path, in order. A field
+// that fails is skipped and reported in the returned error.
+func writeFormFields(writer *multipart.Writer, fields []FormDataField) error {
+	var errs []error
+	for _, field := range fields {
+		// we separate files and text fields.
+		if field.Text {
+			errs = append(errs, writer.WriteField(field.Name, field.Value))
+			continue
+		}
+
+		file, err := os.Open(field.Value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		name := field.FileName
+		if name == "" {
+			name = field.Value
+		}
+		formFile, err := writer.CreateFormFile(field.Name, name)
+		if err == nil {
+			_, err = io.Copy(formFile, file)
+		}
+		file.Close()
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
 
 func SendRequest(config HttpConfig) (*HttpResponse, error) {
+	return DefaultClient.SendRequest(config)
+}
+
+// SendRequestContext is SendRequest with a context, which carries the
+// cancellation and the trace of the call.
+func SendRequestContext(ctx context.Context, config HttpConfig) (*HttpResponse, error) {
+	return DefaultClient.SendRequestContext(ctx, config)
+}
+
+func (c *Client) SendRequest(config HttpConfig) (*HttpResponse, error) {
+	return c.SendRequestContext(context.Background(), config)
+}
+
+func (c *Client) SendRequestContext(ctx context.Context, config HttpConfig) (*HttpResponse, error) {
 	var hresp HttpResponse
 	requestHash := makeHash(config)
 	if config.RetrieveCache {
 		err := hresp.DeserializeCache(requestHash)
+		c.recordCache(config.URL, err == nil)
 		if err == nil {
 			glog.LogL(glog.ERROR, "http ~cache~", config.Method, config.URL)
 			return &hresp, err
@@ -193,17 +388,9 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 	}
 
 	// Create a new HTTP client with a custom timeout
-	client := &http.Client{
-		Timeout: config.Timeout,
-	}
-
-	if config.UseProxy {
-		transport, err := getTransport()
-		if err != nil {
-			return nil, err
-		}
-
-		client.Transport = transport
+	client, err := c.httpClient(config.Timeout, config.UseProxy, config.TLS)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a request body reader from the string
@@ -213,7 +400,7 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 	}
 
 	// Create an HTTP request based on the configuration
-	request, err := http.NewRequest(config.Method, config.URL, requestBodyReader)
+	request, err := http.NewRequestWithContext(ctx, config.Method, config.URL, requestBodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -229,22 +416,18 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 	} else {
 		glog.LogL(glog.INFO, "http ->", config.Method, config.URL)
 	}
+	timer := newRequestTimer()
 	startTime := time.Now()
-	response, err := client.Do(request)
+	response, err := c.do(client, timer.trace(request), c.authFor(config.Auth))
 	elapsedTime := time.Since(startTime)
 
 	if err != nil || response == nil {
 		return &hresp, err
 	}
+	defer response.Body.Close()
 
 	// Parse the response headers into a map
-	headers := make(map[string]string)
-	if response.Header != nil {
-		for key, values := range response.Header {
-			// Combine multiple header values with a comma (or your preferred delimiter)
-			headers[key] = values[0]
-		}
-	}
+	headers := firstHeaderValues(response.Header)
 
 	// Read the response body into a byte slice
 	body, err := io.ReadAll(response.Body)
@@ -264,7 +447,9 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 		Headers:     headers,
 		Body:        responseBody,
 		ElapsedTime: int64(elapsedTime.Milliseconds()),
+		Timing:      timer.done(),
 	}
+	hresp.setMeta(response)
 
 	if config.Cache {
 		hresp.CacheTtl = config.CacheTtl
@@ -272,11 +457,7 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 		hresp.SerializeCache(requestHash)
 	}
 
-	if config.LogResponse {
-		glog.LogL(glog.INFO, "http <-", hresp.ElapsedTime, hresp.StatusCode, config.Method, config.URL, hresp.Body)
-	} else {
-		glog.LogL(glog.INFO, "http <-", hresp.ElapsedTime, hresp.StatusCode, config.Method, config.URL)
-	}
+	logResponse(glog.INFO, &hresp, config.Method, config.URL, config.LogResponse)
 
 	if err != nil {
 		return nil, err
@@ -286,18 +467,14 @@ func SendRequest(config HttpConfig) (*HttpResponse, error) {
 }
 
 func makeResponse(method string, url string,
-	response *http.Response, elapsedTime time.Duration) (*HttpResponse, error) {
+	response *http.Response, elapsedTime time.Duration, timer *requestTimer) (*HttpResponse, error) {
 	if response == nil {
 		return nil, errors.New("http.response is null")
 	}
+	defer response.Body.Close()
+
 	// Parse the response headers into a map
-	headers := make(map[string]string)
-	if response.Header != nil && len(response.Header) > 0 {
-		for key, values := range response.Header {
-			// Combine multiple header values with a comma (or your preferred delimiter)
-			headers[key] = values[0]
-		}
-	}
+	headers := firstHeaderValues(response.Header)
 
 	// Read the response body into a byte slice
 	body, err := io.ReadAll(response.Body)
@@ -316,8 +493,10 @@ func makeResponse(method string, url string,
 		Headers:     headers,
 		Body:        responseBody,
 		ElapsedTime: int64(elapsedTime.Milliseconds()),
+		Timing:      timer.done(),
 	}
-	glog.LogL(glog.INFO, "http <-", hresp.ElapsedTime, hresp.StatusCode, method, url, hresp.Body)
+	hresp.setMeta(response)
+	logResponse(glog.INFO, &hresp, method, url, true)
 
 	if err != nil {
 		return nil, err
@@ -326,6 +505,19 @@ func makeResponse(method string, url string,
 	return &hresp, nil
 }
 
+// logResponse writes the "http <-" line of a call. The timing breakdown is
+// added when debug logging is enabled.
+func logResponse(level string, hresp *HttpResponse, method, url string, withBody bool) {
+	args := []interface{}{"http <-", hresp.ElapsedTime, hresp.StatusCode, method, url}
+	if glog.Enabled(glog.DEBUG) {
+		args = append(args, hresp.Timing)
+	}
+	if withBody {
+		args = append(args, hresp.Body)
+	}
+	glog.LogL(level, args...)
+}
+
 func getBodyString(headers map[string]string, body []byte) string {
 	// compression check
 	contentEncoding := headers["Content-Encoding"]
@@ -370,23 +562,51 @@ func getBody(headers map[string]string, body []byte) []byte {
 	return nil
 }
 
+// mkHeader flattens headers into a map, combining repeated values with ", "
+// as allowed by RFC 9110 section 5.3.
 func mkHeader(headers map[string][]string) map[string]string {
 	header := make(map[string]string)
 	if headers != nil && len(headers) > 0 {
 		for key, values := range headers {
-			// Combine multiple header values with a comma (or your preferred delimiter)
-			header[key] = strings.Join(values, "")
+			header[key] = strings.Join(values, ", ")
+		}
+	}
+	return header
+}
+
+// firstHeaderValues flattens headers into a map keeping only the first value
+// of each header, which is what HttpResponse.Headers has always held.
+func firstHeaderValues(headers http.Header) map[string]string {
+	header := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if len(values) > 0 {
+			header[key] = values[0]
 		}
 	}
 	return header
 }
 
 func SoapCall(config SoapConfig) (*SoapResponse, error) {
-	client := &http.Client{
-		Timeout: config.Timeout,
+	return DefaultClient.SoapCall(config)
+}
+
+// SoapCallContext is SoapCall with a context, which carries the
+// cancellation and the trace of the call.
+func SoapCallContext(ctx context.Context, config SoapConfig) (*SoapResponse, error) {
+	return DefaultClient.SoapCallContext(ctx, config)
+}
+
+func (c *Client) SoapCall(config SoapConfig) (*SoapResponse, error) {
+	return c.SoapCallContext(context.Background(), config)
+}
+
+func (c *Client) SoapCallContext(ctx context.Context, config SoapConfig) (*SoapResponse, error) {
+	client, err := c.httpClient(config.Timeout, false, config.TLS)
+	if err != nil {
+		return nil, err
 	}
 
-	request, err := http.NewRequest("POST", config.URL, bytes.NewBufferString(config.Body))
+	request, err := http.NewRequestWithContext(ctx, "POST", config.URL, bytes.NewBufferString(config.Body))
 	if err != nil {
 		return nil, err
 	}
@@ -403,8 +623,9 @@ func SoapCall(config SoapConfig) (*SoapResponse, error) {
 
 	glog.LogL(glog.INFO, "SOAP ->", config.URL)
 
+	timer := newRequestTimer()
 	startTime := time.Now()
-	response, err := client.Do(request)
+	response, err := c.do(client, timer.trace(request), c.authFor(config.Auth))
 	if err != nil {
 		glog.LogL(glog.ERROR, "Error making SOAP request:", err)
 		return nil, err
@@ -425,13 +646,18 @@ func SoapCall(config SoapConfig) (*SoapResponse, error) {
 		StatusCode: response.StatusCode,
 		Body:       string(body),
 	}
+	soapResp.setMeta(response)
 	soapResp.ElapsedTime = int64(elapsedTime.Milliseconds())
+	soapResp.Timing = timer.done()
 
+	args := []interface{}{"SOAP <-", soapResp.ElapsedTime, soapResp.StatusCode, config.URL}
+	if glog.Enabled(glog.DEBUG) {
+		args = append(args, soapResp.Timing)
+	}
 	if config.LogSoap {
-		glog.LogL(glog.INFO, "SOAP <-", soapResp.ElapsedTime, soapResp.StatusCode, config.URL, soapResp.Body)
-	} else {
-		glog.LogL(glog.INFO, "SOAP <-", soapResp.ElapsedTime, soapResp.StatusCode, config.URL)
+		args = append(args, soapResp.Body)
 	}
+	glog.LogL(glog.INFO, args...)
 
 	return &soapResp, nil
 }
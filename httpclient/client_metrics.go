@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+// ClientMetrics are the request metrics of a Client, labeled by host, method
+// and status class ("2xx", "4xx", ... or "error" for transport failures).
+type ClientMetrics struct {
+	Requests    *metrics.CounterVec
+	Duration    *metrics.HistogramVec
+	InFlight    *metrics.GaugeVec
+	CacheHits   *metrics.CounterVec
+	CacheMisses *metrics.CounterVec
+	Retries     *metrics.CounterVec
+}
+
+// DefaultMetrics collects the metrics of every Client without its own
+// ClientMetrics into metrics.Default.
+var DefaultMetrics = NewClientMetrics(metrics.Default)
+
+// NewClientMetrics registers the httpclient metric families in registry.
+func NewClientMetrics(registry *metrics.Registry) *ClientMetrics {
+	return &ClientMetrics{
+		Requests: registry.Counter("httpclient_requests_total",
+			"Requests sent by httpclient.", "host", "method", "status_class"),
+		Duration: registry.Histogram("httpclient_request_duration_seconds",
+			"Time until the response headers were received.", nil, "host", "method", "status_class"),
+		InFlight: registry.Gauge("httpclient_requests_in_flight",
+			"Requests waiting for a response.", "host", "method"),
+		CacheHits: registry.Counter("httpclient_cache_hits_total",
+			"Responses served from the http-cache.", "host"),
+		CacheMisses: registry.Counter("httpclient_cache_misses_total",
+			"Cache lookups that had to go to the network.", "host"),
+		Retries: registry.Counter("httpclient_retries_total",
+			"Requests sent again after a failed attempt.", "host", "method"),
+	}
+}
+
+func (c *Client) metrics() *ClientMetrics {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return DefaultMetrics
+}
+
+// send performs one attempt through client and records it.
+func (c *Client) send(client *http.Client, request *http.Request) (*http.Response, error) {
+	m := c.metrics()
+	host, method := request.URL.Host, request.Method
+
+	inFlight := m.InFlight.With(host, method)
+	inFlight.Inc()
+	start := time.Now()
+	response, err := client.Do(request)
+	elapsed := time.Since(start)
+	inFlight.Dec()
+
+	class := "error"
+	if err == nil {
+		class = statusClass(response.StatusCode)
+	}
+	m.Requests.With(host, method, class).Inc()
+	m.Duration.With(host, method, class).Observe(elapsed.Seconds())
+
+	return response, err
+}
+
+// recordCache counts a cache lookup of SendRequest.
+func (c *Client) recordCache(url string, hit bool) {
+	host := hostOf(url)
+	if hit {
+		c.metrics().CacheHits.With(host).Inc()
+	} else {
+		c.metrics().CacheMisses.With(host).Inc()
+	}
+}
+
+// recordRetry counts a request that is sent again.
+func (c *Client) recordRetry(request *http.Request) {
+	c.metrics().Retries.With(request.URL.Host, request.Method).Inc()
+}
+
+// hostOf returns the host of rawURL, or rawURL itself when it does not
+// parse.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
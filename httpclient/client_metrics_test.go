@@ -0,0 +1,51 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+func TestClientMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := hostOf(server.URL)
+
+	registry := metrics.NewRegistry()
+	client := &Client{Metrics: NewClientMetrics(registry)}
+	for _, path := range []string{"/", "/", "/missing"} {
+		if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL + path, Timeout: 5 * time.Second}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	client.SendRequest(HttpConfig{Method: "GET", URL: "http://127.0.0.1:1/", Timeout: time.Second})
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{name: "httpclient_requests_total", labels: map[string]string{"host": host, "method": "GET", "status_class": "2xx"}, value: 2},
+		{name: "httpclient_requests_total", labels: map[string]string{"host": host, "method": "GET", "status_class": "4xx"}, value: 1},
+		{name: "httpclient_requests_total", labels: map[string]string{"host": "127.0.0.1:1", "method": "GET", "status_class": "error"}, value: 1},
+		{name: "httpclient_requests_in_flight", labels: map[string]string{"host": host, "method": "GET"}, value: 0},
+	}
+	for _, test := range tests {
+		sample, ok := registry.Find(test.name, test.labels)
+		if !ok || sample.Value != test.value {
+			t.Errorf("Expected %s%v = %v, Got: %v (found %v)", test.name, test.labels, test.value, sample.Value, ok)
+		}
+	}
+
+	sample, _ := registry.Find("httpclient_request_duration_seconds", map[string]string{"host": host, "method": "GET", "status_class": "2xx"})
+	if sample.Count != 2 {
+		t.Errorf("Expected 2 latency observations, Got: %d", sample.Count)
+	}
+}
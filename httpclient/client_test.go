@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSendRequestMultiValueHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Add("Set-Cookie", "a=1; Path=/")
+		w.Header().Add("Set-Cookie", "b=2; Path=/")
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Write([]byte("ok"))
+		w.Header().Set("X-Checksum", "abc")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := SendRequest(HttpConfig{
+		Method:  "GET",
+		URL:     server.URL + "/start",
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := resp.Header.Values("Set-Cookie"); len(got) != 2 {
+		t.Errorf("Expected 2 Set-Cookie values, Got: %v", got)
+	}
+	if got := resp.Header.Values("Vary"); !reflect.DeepEqual(got, []string{"Accept", "Accept-Encoding"}) {
+		t.Errorf("Expected Vary values, Got: %v", got)
+	}
+	if resp.Headers["Vary"] != "Accept" {
+		t.Errorf("Expected Headers[Vary]: Accept, Got: %s", resp.Headers["Vary"])
+	}
+	if len(resp.Cookies) != 2 || resp.Cookies[0].Name != "a" || resp.Cookies[1].Name != "b" {
+		t.Errorf("Expected cookies a and b, Got: %v", resp.Cookies)
+	}
+	if resp.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("Expected trailer X-Checksum: abc, Got: %v", resp.Trailer)
+	}
+	if resp.FinalURL != server.URL+"/final" {
+		t.Errorf("Expected FinalURL: %s, Got: %s", server.URL+"/final", resp.FinalURL)
+	}
+	if resp.Proto != "HTTP/1.1" {
+		t.Errorf("Expected Proto: HTTP/1.1, Got: %s", resp.Proto)
+	}
+}
+
+func TestMkHeaderJoinsValues(t *testing.T) {
+	header := mkHeader(http.Header{"Link": {"<a>; rel=next", "<b>; rel=last"}})
+	if header["Link"] != "<a>; rel=next, <b>; rel=last" {
+		t.Errorf("Expected joined Link header, Got: %s", header["Link"])
+	}
+}
+
+func TestSendRequestTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	timing := resp.Timing
+	if timing.ConnReused {
+		t.Errorf("Expected a new connection")
+	}
+	if timing.ServerProcessing < 30*time.Millisecond {
+		t.Errorf("Expected ServerProcessing >= 30ms, Got: %s", timing.ServerProcessing)
+	}
+	if timing.Transfer < 30*time.Millisecond {
+		t.Errorf("Expected Transfer >= 30ms, Got: %s", timing.Transfer)
+	}
+	if timing.Total < timing.TimeToFirstByte+timing.Transfer {
+		t.Errorf("Expected Total to cover TTFB and Transfer, Got: %s", timing)
+	}
+}
+
+func TestSendRequestTimingExcludesLimiterWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter()
+	limiter.Set(hostOf(server.URL), RateLimit{Requests: 1, Per: 200 * time.Millisecond, Wait: true})
+	client := &Client{Limiter: limiter}
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		resp, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL, Timeout: 5 * time.Second})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if i == 1 && time.Since(start) < 150*time.Millisecond {
+			t.Fatalf("Expected the second call to wait for the limiter")
+		}
+		if resp.Timing.Total >= 100*time.Millisecond {
+			t.Errorf("Expected Total without the limiter wait, Got: %s", resp.Timing)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgolfam/gogutils/filemanager"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const netscapeHeader = "# Netscape HTTP Cookie File"
+const netscapeHttpOnlyPrefix = "#HttpOnly_"
+
+// CookieJar is an http.CookieJar backed by net/http/cookiejar with the
+// public suffix list, which additionally remembers every accepted cookie so
+// the jar can be saved to and loaded from disk.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	entries map[string]jarEntry
+}
+
+// jarEntry is the persisted form of a cookie.
+type jarEntry struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain"`
+	HostOnly bool   `json:"hostOnly"`
+	Path     string `json:"path"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"httpOnly"`
+	// Expires is a unix timestamp, 0 marks a session cookie.
+	Expires int64 `json:"expires"`
+}
+
+func (e jarEntry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e jarEntry) expired(now time.Time) bool {
+	return e.Expires > 0 && e.Expires <= now.Unix()
+}
+
+// NewCookieJar returns an empty jar.
+func NewCookieJar() *CookieJar {
+	// cookiejar.New only fails on invalid options.
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &CookieJar{
+		jar:     jar,
+		entries: make(map[string]jarEntry),
+	}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	host := canonicalHost(u.Host)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+		domain, hostOnly, ok := cookieDomain(host, cookie.Domain)
+		if !ok {
+			continue
+		}
+
+		entry := jarEntry{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   domain,
+			HostOnly: hostOnly,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if entry.Path == "" || entry.Path[0] != '/' {
+			entry.Path = defaultCookiePath(u.Path)
+		}
+
+		if cookie.MaxAge < 0 {
+			delete(j.entries, entry.key())
+			continue
+		} else if cookie.MaxAge > 0 {
+			entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+		} else if !cookie.Expires.IsZero() {
+			if !cookie.Expires.After(now) {
+				delete(j.entries, entry.key())
+				continue
+			}
+			entry.Expires = cookie.Expires.Unix()
+		}
+
+		j.entries[entry.key()] = entry
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the jar to path, as JSON when path ends in .json and in the
+// Netscape cookies.txt format otherwise.
+func (j *CookieJar) Save(path string) error {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return j.SaveJSON(path)
+	}
+	return j.SaveNetscape(path)
+}
+
+// Load reads a jar written by Save, choosing the format the same way.
+func (j *CookieJar) Load(path string) error {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return j.LoadJSON(path)
+	}
+	return j.LoadNetscape(path)
+}
+
+// SaveJSON writes the jar to path as a JSON array.
+func (j *CookieJar) SaveJSON(path string) error {
+	data, err := json.MarshalIndent(j.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return filemanager.WriteFileBytes(path, data, 0600)
+}
+
+// LoadJSON adds the cookies saved by SaveJSON to the jar.
+func (j *CookieJar) LoadJSON(path string) error {
+	data, err := filemanager.ReadFileBytes(path)
+	if err != nil {
+		return err
+	}
+
+	var entries []jarEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	j.restore(entries)
+	return nil
+}
+
+// SaveNetscape writes the jar to path in the cookies.txt format used by curl
+// and wget.
+func (j *CookieJar) SaveNetscape(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(netscapeHeader + "\n\n")
+
+	for _, entry := range j.snapshot() {
+		domain := entry.Domain
+		includeSubdomains := "FALSE"
+		if !entry.HostOnly {
+			domain = "." + domain
+			includeSubdomains = "TRUE"
+		}
+		if entry.HttpOnly {
+			domain = netscapeHttpOnlyPrefix + domain
+		}
+
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, includeSubdomains, entry.Path, strings.ToUpper(strconv.FormatBool(entry.Secure)),
+			entry.Expires, entry.Name, entry.Value)
+	}
+
+	return filemanager.WriteFileBytes(path, buf.Bytes(), 0600)
+}
+
+// LoadNetscape adds the cookies of a cookies.txt file to the jar.
+func (j *CookieJar) LoadNetscape(path string) error {
+	data, err := filemanager.ReadFileBytes(path)
+	if err != nil {
+		return err
+	}
+
+	var entries []jarEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(line, netscapeHttpOnlyPrefix) {
+			line = strings.TrimPrefix(line, netscapeHttpOnlyPrefix)
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("cookies.txt line %d: expected 7 fields, got %d", lineNo, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cookies.txt line %d: invalid expiry: %w", lineNo, err)
+		}
+
+		entries = append(entries, jarEntry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	j.restore(entries)
+	return nil
+}
+
+// snapshot returns the unexpired cookies of the jar in a stable order.
+func (j *CookieJar) snapshot() []jarEntry {
+	now := time.Now()
+
+	j.mu.Lock()
+	entries := make([]jarEntry, 0, len(j.entries))
+	for key, entry := range j.entries {
+		if entry.expired(now) {
+			delete(j.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	j.mu.Unlock()
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].key() < entries[b].key()
+	})
+	return entries
+}
+
+// restore replays saved entries through SetCookies so the underlying jar
+// applies its usual domain and path rules.
+func (j *CookieJar) restore(entries []jarEntry) {
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Domain == "" || entry.expired(now) {
+			continue
+		}
+
+		scheme := "http"
+		if entry.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: entry.Domain, Path: entry.Path}
+
+		cookie := &http.Cookie{
+			Name:     entry.Name,
+			Value:    entry.Value,
+			Path:     entry.Path,
+			Secure:   entry.Secure,
+			HttpOnly: entry.HttpOnly,
+		}
+		if !entry.HostOnly {
+			cookie.Domain = entry.Domain
+		}
+		if entry.Expires > 0 {
+			cookie.Expires = time.Unix(entry.Expires, 0)
+		}
+
+		j.SetCookies(u, []*http.Cookie{cookie})
+	}
+}
+
+// cookieDomain mirrors the domain rules of net/http/cookiejar: it returns
+// the domain a cookie is stored under, whether it is a host-only cookie,
+// and false when the jar rejects it.
+func cookieDomain(host, domain string) (string, bool, bool) {
+	if domain == "" {
+		return host, true, true
+	}
+
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if net.ParseIP(host) != nil {
+		return host, true, domain == host
+	}
+
+	if publicsuffix.List.PublicSuffix(domain) == domain {
+		// A cookie for a public suffix is only kept as a host cookie of
+		// that very host.
+		return host, true, domain == host
+	}
+
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+// canonicalHost strips the port and the trailing dot from host.
+func canonicalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// defaultCookiePath implements the default-path algorithm of RFC 6265
+// section 5.1.4.
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
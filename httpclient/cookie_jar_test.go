@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionKeepsCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret", Path: "/", MaxAge: 3600})
+			return
+		}
+		if cookie, err := r.Cookie("sid"); err != nil || cookie.Value != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	session := NewSession()
+	if _, err := session.SendRequest(HttpConfig{Method: "POST", URL: server.URL + "/login", Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"cookies.txt", "cookies.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := session.SaveSession(path); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			restored, err := LoadSession(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			resp, err := restored.SendRequest(HttpConfig{Method: "GET", URL: server.URL + "/me", Timeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected StatusCode: 200, Got: %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestCookieJarPublicSuffix(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		domain string
+		stored bool
+	}{
+		{name: "host cookie", host: "www.example.com", domain: "", stored: true},
+		{name: "parent domain", host: "www.example.com", domain: ".example.com", stored: true},
+		{name: "public suffix", host: "www.example.co.uk", domain: "co.uk", stored: false},
+		{name: "foreign domain", host: "www.example.com", domain: "other.com", stored: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jar := NewCookieJar()
+			u := &url.URL{Scheme: "https", Host: test.host, Path: "/"}
+			jar.SetCookies(u, []*http.Cookie{{Name: "k", Value: "v", Domain: test.domain}})
+
+			if got := len(jar.snapshot()) == 1; got != test.stored {
+				t.Errorf("Expected stored: %v, Got: %v", test.stored, got)
+			}
+			if got := len(jar.Cookies(u)) == 1; got != test.stored {
+				t.Errorf("Expected sent: %v, Got: %v", test.stored, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"sort"
+	"strings"
+)
+
+// ToCurlCommand formats config as a multi-line curl command, the reverse of
+// ParseCurlCommand. Auth providers and proxies are not exported.
+func ToCurlCommand(config HttpConfig) string {
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	args := []string{"curl"}
+	if method != "GET" || config.Body != nil {
+		args = append(args, "-X "+method)
+	}
+	args = append(args, shellQuote(config.URL))
+
+	keys := make([]string, 0, len(config.Headers))
+	for key := range config.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-H "+shellQuote(key+": "+config.Headers[key]))
+	}
+
+	if config.Body != nil {
+		args = append(args, "--data "+shellQuote(string(config.Body)))
+	}
+
+	if tls := config.TLS; tls != nil {
+		if tls.InsecureSkipVerify {
+			args = append(args, "-k")
+		}
+		for _, file := range tls.CAFiles {
+			args = append(args, "--cacert "+shellQuote(file))
+		}
+		if tls.CADir != "" {
+			args = append(args, "--capath "+shellQuote(tls.CADir))
+		}
+		if tls.PKCS12File != "" {
+			args = append(args, "--cert-type P12", "--cert "+shellQuote(tls.PKCS12File))
+			if tls.PKCS12Password != "" {
+				args = append(args, "--pass "+shellQuote(tls.PKCS12Password))
+			}
+		}
+		if tls.CertFile != "" {
+			args = append(args, "--cert "+shellQuote(tls.CertFile))
+		}
+		if tls.KeyFile != "" {
+			args = append(args, "--key "+shellQuote(tls.KeyFile))
+		}
+		if len(tls.PinnedSPKI) > 0 {
+			args = append(args, "--pinnedpubkey "+shellQuote(strings.Join(tls.PinnedSPKI, ";")))
+		}
+		if tls.MinVersion != "" {
+			args = append(args, "--tlsv"+tls.MinVersion)
+		}
+	}
+
+	return strings.Join(args, " \\\n  ")
+}
+
+// shellQuote wraps s in single quotes when it is not a plain word.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@%+=,", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToCurlCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   HttpConfig
+		expected string
+	}{
+		{
+			name:     "GET",
+			config:   HttpConfig{Method: "GET", URL: "https://example.com/a?b=c&d=e"},
+			expected: "curl \\\n  'https://example.com/a?b=c&d=e'",
+		},
+		{
+			name: "POST with headers, body and TLS",
+			config: HttpConfig{
+				Method:  "POST",
+				URL:     "https://example.com/users",
+				Headers: map[string]string{"Content-Type": "application/json", "Accept": "*/*"},
+				Body:    []byte(`{"name":"O'Brien"}`),
+				TLS:     &TLSConfig{InsecureSkipVerify: true, MinVersion: "1.2"},
+			},
+			expected: "curl \\\n  -X POST \\\n  https://example.com/users \\\n  -H 'Accept: */*' \\\n  -H 'Content-Type: application/json' \\\n  --data '{\"name\":\"O'\\''Brien\"}' \\\n  -k \\\n  --tlsv1.2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ToCurlCommand(test.config); got != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestToCurlCommandRoundTrip(t *testing.T) {
+	config := HttpConfig{
+		Method:  "PUT",
+		URL:     "https://example.com/items/7",
+		Headers: map[string]string{"Content-Type": "application/json", "X-Trace": "a b"},
+		Body:    []byte(`{"qty":3}`),
+		Timeout: 30 * time.Second,
+		TLS:     &TLSConfig{CAFiles: []string{"/etc/ca.pem"}, CertFile: "client.crt", KeyFile: "client.key"},
+	}
+
+	parsed, err := ParseCurlCommand(ToCurlCommand(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*parsed, config) {
+		t.Errorf("Expected: %+v, Got: %+v", config, *parsed)
+	}
+}
@@ -1,7 +1,10 @@
 package httpclient
 
 import (
+	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -18,12 +21,24 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 
 	// Initialize variables
 	method := "GET"
+	methodSet := false
 	urlAddr := ""
 	headers := make(map[string]string)
 	var body []byte
 	contentTypeSet := false
 	proxy := ""
 
+	// TLS options are only allocated when the command uses one.
+	var tlsConfig *TLSConfig
+	tlsOptions := func() *TLSConfig {
+		if tlsConfig == nil {
+			tlsConfig = &TLSConfig{}
+		}
+		return tlsConfig
+	}
+	certType := ""
+	certPassword := ""
+
 	// Split the command into tokens
 	tokens := splitCurlCommand(curlCommand)
 
@@ -38,6 +53,7 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 				nextToken := strings.ToUpper(tokens[i+1])
 				if isValidMethod(nextToken) {
 					method = nextToken
+					methodSet = true
 				}
 				i++
 			}
@@ -55,14 +71,22 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 				}
 				i++
 			}
-		case "--data", "--data-urlencode":
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw", "--data-urlencode":
 			// Extract body data
 			if i+1 < len(tokens) {
+				data, err := curlData(token, strings.Trim(tokens[i+1], `'"`))
+				if err != nil {
+					return nil, err
+				}
 				if len(body) > 0 {
 					body = append(body, '&')
 				}
-				body = append(body, strings.Trim(tokens[i+1], `'"`)...)
+				body = append(body, data...)
 				i++
+				// Like curl, data makes a POST unless -X says otherwise
+				if !methodSet {
+					method = "POST"
+				}
 				// Automatically set Content-Type if not already set
 				if !contentTypeSet {
 					headers["Content-Type"] = "application/x-www-form-urlencoded"
@@ -75,6 +99,57 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 				proxy = strings.Trim(tokens[i+1], `'"`)
 				i++
 			}
+		case "-k", "--insecure":
+			tlsOptions().InsecureSkipVerify = true
+		case "--cacert":
+			if i+1 < len(tokens) {
+				tlsOptions().CAFiles = append(tlsOptions().CAFiles, tokens[i+1])
+				i++
+			}
+		case "--capath":
+			if i+1 < len(tokens) {
+				tlsOptions().CADir = tokens[i+1]
+				i++
+			}
+		case "-E", "--cert":
+			// The certificate may be followed by ":password".
+			if i+1 < len(tokens) {
+				cert := tokens[i+1]
+				if idx := strings.LastIndex(cert, ":"); idx > 1 {
+					cert, certPassword = cert[:idx], cert[idx+1:]
+				}
+				tlsOptions().CertFile = cert
+				i++
+			}
+		case "--cert-type":
+			if i+1 < len(tokens) {
+				certType = strings.ToUpper(tokens[i+1])
+				i++
+			}
+		case "--key":
+			if i+1 < len(tokens) {
+				tlsOptions().KeyFile = tokens[i+1]
+				i++
+			}
+		case "--pass":
+			if i+1 < len(tokens) {
+				certPassword = tokens[i+1]
+				i++
+			}
+		case "--pinnedpubkey":
+			// Only sha256// hashes are supported, not key files.
+			if i+1 < len(tokens) {
+				for _, pin := range strings.Split(tokens[i+1], ";") {
+					if strings.HasPrefix(pin, "sha256//") {
+						tlsOptions().PinnedSPKI = append(tlsOptions().PinnedSPKI, pin)
+					}
+				}
+				i++
+			}
+		case "--tlsv1", "--tlsv1.0":
+			tlsOptions().MinVersion = "1.0"
+		case "--tlsv1.1", "--tlsv1.2", "--tlsv1.3":
+			tlsOptions().MinVersion = strings.TrimPrefix(token, "--tlsv")
 		default:
 			// Assume it's the URL if it starts with http
 			if strings.HasPrefix(token, "http") {
@@ -93,6 +168,16 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 		headers["Proxy"] = proxy
 	}
 
+	// PKCS#12 bundles are told apart by --cert-type or their extension
+	if tlsConfig != nil && tlsConfig.CertFile != "" {
+		ext := strings.ToLower(filepath.Ext(tlsConfig.CertFile))
+		if certType == "P12" || ext == ".p12" || ext == ".pfx" {
+			tlsConfig.PKCS12File = tlsConfig.CertFile
+			tlsConfig.PKCS12Password = certPassword
+			tlsConfig.CertFile = ""
+		}
+	}
+
 	// Create the HttpConfig
 	config := &HttpConfig{
 		Method:   method,
@@ -101,12 +186,33 @@ func ParseCurlCommand(curlCommand string) (*HttpConfig, error) {
 		Body:     body,
 		Timeout:  30 * time.Second,
 		UseProxy: proxy != "",
+		TLS:      tlsConfig,
 	}
 
 	return config, nil
 }
 
-// normalizeCurlCommand combines multi-line curl commands into a single line.
+// curlData returns the data of a data option. @file reads the file, whose
+// line breaks are dropped except with --data-binary; --data-raw takes @
+// literally.
+func curlData(option, value string) ([]byte, error) {
+	if option == "--data-raw" || !strings.HasPrefix(value, "@") {
+		return []byte(value), nil
+	}
+	path := value[1:]
+	if path == "-" {
+		return nil, errors.New("curl data from stdin is not supported")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if option != "--data-binary" {
+		data = bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("\r"), nil), []byte("\n"), nil)
+	}
+	return data, nil
+}
+
 // normalizeCurlCommand combines multi-line curl commands into a single line.
 func normalizeCurlCommand(curlCommand string) string {
 	lines := strings.Split(curlCommand, "\n")
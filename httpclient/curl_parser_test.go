@@ -1,6 +1,8 @@
 package httpclient
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -145,3 +147,42 @@ func TestParseCurlCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCurlCommandData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "login.json")
+	if err := os.WriteFile(path, []byte("{\"user\":\"bob\",\r\n\"pass\":\"x\"}\n"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		curlCommand    string
+		expectedMethod string
+		expectedBody   string
+		expectError    bool
+	}{
+		{name: "file without line breaks", curlCommand: "curl -X PUT https://example.com -d @" + path, expectedMethod: "PUT", expectedBody: `{"user":"bob","pass":"x"}`},
+		{name: "binary file as is", curlCommand: "curl https://example.com --data-binary @" + path, expectedMethod: "POST", expectedBody: "{\"user\":\"bob\",\r\n\"pass\":\"x\"}\n"},
+		{name: "raw at sign", curlCommand: "curl https://example.com --data-raw @" + path, expectedMethod: "POST", expectedBody: "@" + path},
+		{name: "short data joined", curlCommand: "curl https://example.com -d a=1 -d b=2", expectedMethod: "POST", expectedBody: "a=1&b=2"},
+		{name: "missing file", curlCommand: "curl https://example.com -d @" + path + ".missing", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := ParseCurlCommand(test.curlCommand)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if conf.Method != test.expectedMethod || string(conf.Body) != test.expectedBody {
+				t.Errorf("Expected %s %q, Got: %s %q", test.expectedMethod, test.expectedBody, conf.Method, conf.Body)
+			}
+		})
+	}
+}
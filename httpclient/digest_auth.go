@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DigestAuth implements HTTP Digest authentication (RFC 7616). The first
+// request goes out without credentials; the server's challenge is kept and
+// answered on the retry and on the following requests.
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu        sync.Mutex
+	challenge map[string]string
+	nonceUsed int
+}
+
+func (a *DigestAuth) Authorize(request *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.challenge == nil {
+		return nil
+	}
+
+	a.nonceUsed++
+	header, err := a.authorization(request.Method, request.URL.RequestURI(), a.nonceUsed)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", header)
+	return nil
+}
+
+func (a *DigestAuth) HandleChallenge(request *http.Request, response *http.Response) (bool, error) {
+	var params map[string]string
+	for _, value := range response.Header.Values("WWW-Authenticate") {
+		if len(value) > 7 && strings.EqualFold(value[:7], "digest ") {
+			params = parseAuthParams(value[7:])
+			break
+		}
+	}
+	if params == nil {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A rejected answer to the same nonce means wrong credentials, unless the
+	// server says the nonce merely went stale.
+	if a.challenge != nil && a.challenge["nonce"] == params["nonce"] && !strings.EqualFold(params["stale"], "true") {
+		return false, nil
+	}
+
+	a.challenge = params
+	a.nonceUsed = 0
+	return true, nil
+}
+
+// authorization computes the Authorization header, a.mu must be held.
+func (a *DigestAuth) authorization(method, uri string, nc int) (string, error) {
+	algorithm := a.challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("digest: unsupported algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		hasher := newHash()
+		hasher.Write([]byte(s))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	realm, nonce := a.challenge["realm"], a.challenge["nonce"]
+	cnonce, err := digestCnonce()
+	if err != nil {
+		return "", err
+	}
+
+	ha1 := h(a.Username + ":" + realm + ":" + a.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	qop := ""
+	for _, option := range strings.Split(a.challenge["qop"], ",") {
+		if strings.TrimSpace(option) == "auth" {
+			qop = "auth"
+		}
+	}
+	if a.challenge["qop"] != "" && qop == "" {
+		return "", errors.New("digest: only qop=auth is supported")
+	}
+
+	ncValue := fmt.Sprintf("%08x", nc)
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ncValue + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		a.Username, realm, nonce, uri, algorithm, response)
+	if opaque, ok := a.challenge["opaque"]; ok {
+		fmt.Fprintf(&sb, `, opaque="%s"`, opaque)
+	}
+	if qop != "" {
+		fmt.Fprintf(&sb, `, qop=%s, nc=%s, cnonce="%s"`, qop, ncValue, cnonce)
+	}
+	return sb.String(), nil
+}
+
+func digestCnonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,278 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GraphQLClient sends GraphQL operations over HTTP POST.
+//
+//	gql := &httpclient.GraphQLClient{URL: "https://api.example.com/graphql"}
+//	var out struct {
+//		User struct{ Name string } `json:"user"`
+//	}
+//	err := gql.Query(ctx, `query($id: ID!) { user(id: $id) { name } }`, map[string]interface{}{"id": 7}, &out)
+type GraphQLClient struct {
+	URL string
+	// Client sends the requests, DefaultClient when nil.
+	Client  *Client
+	Headers map[string]string
+	Timeout time.Duration
+	Auth    AuthProvider
+	TLS     *TLSConfig
+	// PersistedQueries sends the SHA-256 hash of the query instead of its
+	// text, as in Apollo's automatic persisted queries, and the full query
+	// once when the server does not know the hash.
+	PersistedQueries bool
+}
+
+// GraphQLRequest is the body of a GraphQL operation.
+type GraphQLRequest struct {
+	Query         string                 `json:"query,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Upload is a file variable, sent with the GraphQL multipart request spec.
+// Variables may hold an Upload, a *Upload or a []Upload, in nested maps and
+// []interface{} values too.
+type Upload struct {
+	Path string
+	// FileName is sent as the name of the file, the base of Path by
+	// default.
+	FileName string
+}
+
+// GraphQLError is an entry of the errors of a GraphQL response.
+type GraphQLError struct {
+	Message   string `json:"message"`
+	Locations []struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"locations,omitempty"`
+	// Path holds field names and list indexes.
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return e.Message + " at " + strings.Join(path, ".")
+}
+
+// Code returns the extensions.code of the error, "" without one.
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors is returned for responses with errors. The data the
+// response carried anyway is still decoded.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	if len(e) == 0 {
+		return "graphql: no errors"
+	}
+	if len(e) == 1 {
+		return "graphql: " + e[0].Error()
+	}
+	return fmt.Sprintf("graphql: %s (and %d more errors)", e[0].Error(), len(e)-1)
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// Query sends query with its variables and decodes the data into out, see
+// Do.
+func (g *GraphQLClient) Query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	return g.Do(ctx, GraphQLRequest{Query: query, Variables: variables}, out)
+}
+
+// Do sends the operation and decodes the data of the response into out,
+// which may be nil. It returns GraphQLErrors when the response has errors,
+// and a *StatusError for a non 2xx status without GraphQL errors.
+func (g *GraphQLClient) Do(ctx context.Context, request GraphQLRequest, out interface{}) error {
+	if g.PersistedQueries && request.Query != "" {
+		persisted := request
+		persisted.Query = ""
+		persisted.Extensions = map[string]interface{}{}
+		for key, value := range request.Extensions {
+			persisted.Extensions[key] = value
+		}
+		hash := sha256.Sum256([]byte(request.Query))
+		persisted.Extensions["persistedQuery"] = map[string]interface{}{"version": 1, "sha256Hash": hex.EncodeToString(hash[:])}
+
+		err := g.do(ctx, persisted, out)
+		if errs, ok := err.(GraphQLErrors); !ok || !persistedQueryNotFound(errs) {
+			return err
+		}
+		// The server registers the hash with the full query.
+		request.Extensions = persisted.Extensions
+	}
+	return g.do(ctx, request, out)
+}
+
+func persistedQueryNotFound(errs GraphQLErrors) bool {
+	for _, e := range errs {
+		if e.Message == "PersistedQueryNotFound" || e.Code() == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GraphQLClient) do(ctx context.Context, request GraphQLRequest, out interface{}) error {
+	config := HttpConfig{
+		Method:  http.MethodPost,
+		URL:     g.URL,
+		Headers: map[string]string{"Accept": "application/graphql-response+json, application/json"},
+		Timeout: g.Timeout,
+		Auth:    g.Auth,
+		TLS:     g.TLS,
+	}
+	for key, value := range g.Headers {
+		config.Headers[key] = value
+	}
+
+	variables, uploads := extractUploads(request.Variables, "variables")
+	request.Variables = variables
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		config.Headers["Content-Type"] = "application/json"
+		config.Body = body
+	} else if config.Body, config.Headers["Content-Type"], err = graphQLMultipart(body, uploads); err != nil {
+		return err
+	}
+
+	client := g.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.SendRequestContext(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	var result graphQLResponse
+	decodeErr := json.Unmarshal(resp.Body, &result)
+	if decodeErr != nil || (result.Data == nil && result.Errors == nil) {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &StatusError{Method: http.MethodPost, URL: g.URL, StatusCode: resp.StatusCode, Body: resp.Body}
+		}
+		if decodeErr == nil {
+			decodeErr = fmt.Errorf("no data and no errors")
+		}
+		return fmt.Errorf("graphql: decoding response: %w", decodeErr)
+	}
+	if out != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("graphql: decoding data: %w", err)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors
+	}
+	return nil
+}
+
+type graphQLUpload struct {
+	path   string
+	upload Upload
+}
+
+// extractUploads returns a copy of v with its uploads replaced by null,
+// and the uploads with their object paths.
+func extractUploads(v map[string]interface{}, path string) (map[string]interface{}, []graphQLUpload) {
+	var uploads []graphQLUpload
+	var walk func(v interface{}, path string) interface{}
+	walk = func(v interface{}, path string) interface{} {
+		switch v := v.(type) {
+		case Upload:
+			uploads = append(uploads, graphQLUpload{path: path, upload: v})
+			return nil
+		case *Upload:
+			uploads = append(uploads, graphQLUpload{path: path, upload: *v})
+			return nil
+		case []Upload:
+			list := make([]interface{}, len(v))
+			for i, upload := range v {
+				list[i] = walk(upload, path+"."+strconv.Itoa(i))
+			}
+			return list
+		case []interface{}:
+			list := make([]interface{}, len(v))
+			for i, item := range v {
+				list[i] = walk(item, path+"."+strconv.Itoa(i))
+			}
+			return list
+		case map[string]interface{}:
+			m := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				m[key] = walk(value, path+"."+key)
+			}
+			return m
+		}
+		return v
+	}
+	if v == nil {
+		return nil, nil
+	}
+	copied := walk(v, path).(map[string]interface{})
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].path < uploads[j].path })
+	return copied, uploads
+}
+
+// graphQLMultipart builds the body of the GraphQL multipart request spec:
+// the operations, the map of files to variables, then the files.
+func graphQLMultipart(operations []byte, uploads []graphQLUpload) ([]byte, string, error) {
+	files := map[string][]string{}
+	fields := []FormDataField{{Name: "operations", Value: string(operations), Text: true}, {Name: "map", Text: true}}
+	for i, upload := range uploads {
+		key := strconv.Itoa(i)
+		files[key] = []string{upload.path}
+		name := upload.upload.FileName
+		if name == "" {
+			name = filepath.Base(upload.upload.Path)
+		}
+		fields = append(fields, FormDataField{Name: key, Value: upload.upload.Path, FileName: name})
+	}
+	fileMap, err := json.Marshal(files)
+	if err != nil {
+		return nil, "", err
+	}
+	fields[1].Value = string(fileMap)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writeFormFields(writer, fields); err != nil {
+		return nil, "", fmt.Errorf("graphql: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
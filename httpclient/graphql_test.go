@@ -0,0 +1,182 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type graphQLUser struct {
+	User *struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+func TestGraphQLQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Client") != "test" {
+			t.Errorf("Expected a JSON request with the client headers, Got: %v", r.Header)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch request.OperationName {
+		case "GetUser":
+			if request.Variables["id"] != float64(7) {
+				t.Errorf("Expected id 7, Got: %v", request.Variables)
+			}
+			io.WriteString(w, `{"data":{"user":{"name":"Ada"}}}`)
+		case "Partial":
+			io.WriteString(w, `{"data":{"user":{"name":"Ada"}},"errors":[{"message":"not allowed","path":["user","friends",0],"locations":[{"line":1,"column":9}],"extensions":{"code":"FORBIDDEN"}},{"message":"second"}]}`)
+		case "Invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"errors":[{"message":"Cannot query field \"nope\""}]}`)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "bad gateway")
+		}
+	}))
+	defer server.Close()
+
+	gql := &GraphQLClient{URL: server.URL, Headers: map[string]string{"X-Client": "test"}}
+	var out graphQLUser
+	err := gql.Do(context.Background(), GraphQLRequest{Query: "query GetUser($id: ID!) { user(id: $id) { name } }", OperationName: "GetUser", Variables: map[string]interface{}{"id": 7}}, &out)
+	if err != nil || out.User == nil || out.User.Name != "Ada" {
+		t.Fatalf("Expected Ada, Got: %+v, %v", out.User, err)
+	}
+
+	out = graphQLUser{}
+	err = gql.Do(context.Background(), GraphQLRequest{Query: "query Partial { user { name friends { name } } }", OperationName: "Partial"}, &out)
+	var errs GraphQLErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected 2 GraphQL errors, Got: %v", err)
+	}
+	if out.User == nil || out.User.Name != "Ada" {
+		t.Errorf("Expected the partial data to be decoded, Got: %+v", out.User)
+	}
+	if errs[0].Code() != "FORBIDDEN" || !reflect.DeepEqual(errs[0].Path, []interface{}{"user", "friends", float64(0)}) || errs[0].Locations[0].Column != 9 {
+		t.Errorf("Expected the error details, Got: %+v", errs[0])
+	}
+	if expected := "graphql: not allowed at user.friends.0 (and 1 more errors)"; err.Error() != expected {
+		t.Errorf("Expected %q, Got: %q", expected, err.Error())
+	}
+
+	err = gql.Do(context.Background(), GraphQLRequest{Query: "{ nope }", OperationName: "Invalid"}, nil)
+	if !errors.As(err, &errs) || !strings.Contains(errs[0].Message, "nope") {
+		t.Errorf("Expected a GraphQL error for a 400, Got: %v", err)
+	}
+	var statusErr *StatusError
+	err = gql.Query(context.Background(), "{ user { name } }", nil, nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a 502 StatusError, Got: %v", err)
+	}
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	const query = "{ user { name } }"
+	const hash = "52978b222d1c8b138bf928ca07288c01cd4c553a1a8fe1322526b4ef6c6f6b48"
+	known := map[string]bool{}
+	var requests []GraphQLRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request GraphQLRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		persisted, _ := request.Extensions["persistedQuery"].(map[string]interface{})
+		sha, _ := persisted["sha256Hash"].(string)
+		if request.Query != "" {
+			known[sha] = true
+		}
+		if !known[sha] {
+			io.WriteString(w, `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"user":{"name":"Ada"}}}`)
+	}))
+	defer server.Close()
+
+	gql := &GraphQLClient{URL: server.URL, PersistedQueries: true}
+	for i := 0; i < 2; i++ {
+		var out graphQLUser
+		if err := gql.Query(context.Background(), query, nil, &out); err != nil || out.User.Name != "Ada" {
+			t.Fatalf("Expected Ada, Got: %+v, %v", out.User, err)
+		}
+	}
+
+	if len(requests) != 3 || requests[0].Query != "" || requests[1].Query != query || requests[2].Query != "" {
+		t.Fatalf("Expected hash, query and hash requests, Got: %+v", requests)
+	}
+	sha := requests[0].Extensions["persistedQuery"].(map[string]interface{})["sha256Hash"]
+	if sha != hash {
+		t.Errorf("Expected %s, Got: %v", hash, sha)
+	}
+}
+
+func TestGraphQLUpload(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "alpha", "b.txt": "beta", "c.txt": "gamma"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		var operations GraphQLRequest
+		json.Unmarshal([]byte(r.FormValue("operations")), &operations)
+		expectedVariables := map[string]interface{}{"file": nil, "input": map[string]interface{}{"files": []interface{}{nil, nil}, "title": "docs"}}
+		if !reflect.DeepEqual(operations.Variables, expectedVariables) {
+			t.Errorf("Expected %v, Got: %v", expectedVariables, operations.Variables)
+		}
+		var fileMap map[string][]string
+		json.Unmarshal([]byte(r.FormValue("map")), &fileMap)
+		expectedMap := map[string][]string{"0": {"variables.file"}, "1": {"variables.input.files.0"}, "2": {"variables.input.files.1"}}
+		if !reflect.DeepEqual(fileMap, expectedMap) {
+			t.Errorf("Expected %v, Got: %v", expectedMap, fileMap)
+		}
+		for key, expected := range map[string]string{"0": "alpha|a.txt", "1": "beta|b.txt", "2": "gamma|renamed.txt"} {
+			file, header, err := r.FormFile(key)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				continue
+			}
+			content, _ := io.ReadAll(file)
+			if got := string(content) + "|" + header.Filename; got != expected {
+				t.Errorf("Expected %s, Got: %s", expected, got)
+			}
+		}
+		io.WriteString(w, `{"data":{"upload":true}}`)
+	}))
+	defer server.Close()
+
+	gql := &GraphQLClient{URL: server.URL}
+	var out struct{ Upload bool }
+	err := gql.Query(context.Background(), "mutation($file: Upload!, $input: Input!) { upload(file: $file, input: $input) }", map[string]interface{}{
+		"file": Upload{Path: filepath.Join(dir, "a.txt")},
+		"input": map[string]interface{}{
+			"title": "docs",
+			"files": []Upload{{Path: filepath.Join(dir, "b.txt")}, {Path: filepath.Join(dir, "c.txt"), FileName: "renamed.txt"}},
+		},
+	}, &out)
+	if err != nil || !out.Upload {
+		t.Errorf("Expected the upload to succeed, Got: %v", err)
+	}
+
+	err = gql.Query(context.Background(), "mutation($file: Upload!) { upload(file: $file) }", map[string]interface{}{"file": &Upload{Path: filepath.Join(dir, "missing.txt")}}, nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file error, Got: %v", err)
+	}
+}
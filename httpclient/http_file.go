@@ -0,0 +1,416 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+	"github.com/mgolfam/gogutils/utils"
+)
+
+// HttpFile is a parsed .http file as used by the REST clients of IntelliJ
+// and VS Code:
+//
+//	@host = https://api.example.com
+//
+//	### login
+//	# @name login
+//	POST {{host}}/login
+//	Content-Type: application/json
+//
+//	< ./login.json
+//
+//	### profile
+//	GET {{host}}/me
+//	Authorization: Bearer {{login.response.body.$.token}}
+//
+// Variables are resolved when a request is turned into an HttpConfig, so
+// later requests can use the responses of earlier ones.
+type HttpFile struct {
+	// Dir is where included files are looked up.
+	Dir string
+	// Vars are the @name = value file variables.
+	Vars     map[string]string
+	Requests []*HttpFileRequest
+}
+
+// HttpFileRequest is one request of an HttpFile, before variables are
+// resolved.
+type HttpFileRequest struct {
+	// Name is set by "# @name" or, failing that, by the "###" separator.
+	Name    string
+	Line    int
+	Method  string
+	URL     string
+	Headers [][2]string
+	// Body keeps "< file" and "<@ file" include lines as they are.
+	Body string
+}
+
+var requestLinePattern = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|TRACE|CONNECT)\s+(\S+)(?:\s+HTTP/[\d.]+)?$`)
+
+func ParseHttpFile(path string) (*HttpFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHttpFileContent(string(data), filepath.Dir(path))
+}
+
+// ParseHttpFileContent parses the content of a .http file, dir is used to
+// resolve included files.
+func ParseHttpFileContent(content string, dir string) (*HttpFile, error) {
+	file := &HttpFile{Dir: dir, Vars: map[string]string{}}
+
+	var (
+		current *HttpFileRequest
+		title   string
+		name    string
+		inBody  bool
+		body    []string
+	)
+	finish := func() {
+		if current != nil {
+			// Trailing blank lines are not part of the body.
+			for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+				body = body[:len(body)-1]
+			}
+			current.Body = strings.Join(body, "\n")
+			file.Requests = append(file.Requests, current)
+		}
+		current, inBody, body, name = nil, false, nil, ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "###") {
+			finish()
+			title = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+		if inBody {
+			// Response handlers and response references are not supported.
+			if strings.HasPrefix(trimmed, "> ") || strings.HasPrefix(trimmed, "<> ") {
+				continue
+			}
+			body = append(body, line)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			if current != nil {
+				inBody = true
+			}
+		case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			comment := strings.TrimSpace(strings.TrimLeft(trimmed, "#/"))
+			if strings.HasPrefix(comment, "@name ") {
+				name = strings.TrimSpace(strings.TrimPrefix(comment, "@name "))
+				if current != nil {
+					current.Name = name
+				}
+			}
+		case current == nil && strings.HasPrefix(trimmed, "@"):
+			key, value, ok := strings.Cut(trimmed[1:], "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected @name = value", lineNo)
+			}
+			file.Vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		case current == nil:
+			method, target := "GET", trimmed
+			if m := requestLinePattern.FindStringSubmatch(trimmed); m != nil {
+				method, target = m[1], m[2]
+			} else if strings.ContainsAny(trimmed, " \t") {
+				return nil, fmt.Errorf("line %d: invalid request line %q", lineNo, trimmed)
+			}
+			current = &HttpFileRequest{Name: name, Line: lineNo, Method: method, URL: target}
+			if current.Name == "" {
+				current.Name = title
+			}
+			title = ""
+		case strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&"):
+			// Query parameters continued on the next lines.
+			current.URL += trimmed
+		default:
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid header %q", lineNo, trimmed)
+			}
+			current.Headers = append(current.Headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+	return file, nil
+}
+
+// Request returns the request called name, or nil.
+func (f *HttpFile) Request(name string) *HttpFileRequest {
+	for _, r := range f.Requests {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Config resolves the variables of req. vars, such as the values of an
+// environment, take precedence over the file variables.
+func (f *HttpFile) Config(req *HttpFileRequest, vars map[string]string) (HttpConfig, error) {
+	return f.config(req, vars, nil)
+}
+
+func (f *HttpFile) config(req *HttpFileRequest, vars map[string]string, responses map[string]*HttpResponse) (HttpConfig, error) {
+	var unresolved []string
+	var expand func(s string, depth int) string
+	expand = func(s string, depth int) string {
+		return utils.ExpandVars(s, func(name string) (string, bool) {
+			if value, ok := vars[name]; ok {
+				return value, true
+			}
+			if value, ok := f.Vars[name]; ok && depth < 8 {
+				return expand(value, depth+1), true
+			}
+			if value, ok := dynamicVar(name); ok {
+				return value, true
+			}
+			if value, ok := responseVar(name, responses); ok {
+				return value, true
+			}
+			unresolved = append(unresolved, name)
+			return "", false
+		})
+	}
+
+	config := HttpConfig{
+		Method:  req.Method,
+		URL:     expand(req.URL, 0),
+		Headers: map[string]string{},
+	}
+	multipart := false
+	for _, h := range req.Headers {
+		config.Headers[h[0]] = expand(h[1], 0)
+		if strings.EqualFold(h[0], "Content-Type") && strings.HasPrefix(strings.ToLower(h[1]), "multipart/") {
+			multipart = true
+		}
+	}
+
+	if req.Body != "" {
+		var parts []string
+		for _, line := range strings.Split(req.Body, "\n") {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(trimmed, "<@ "), strings.HasPrefix(trimmed, "< "):
+				path := strings.TrimSpace(trimmed[strings.Index(trimmed, " "):])
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(f.Dir, path)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return config, fmt.Errorf("line %d: %w", req.Line, err)
+				}
+				// The file is included byte for byte; its own trailing
+				// line break belongs to the content.
+				content := string(data)
+				if strings.HasPrefix(trimmed, "<@") {
+					content = expand(content, 0)
+				}
+				parts = append(parts, content)
+			default:
+				parts = append(parts, expand(line, 0))
+			}
+		}
+
+		// Multipart bodies need CRLF line breaks.
+		separator := "\n"
+		if multipart {
+			separator = "\r\n"
+		}
+		config.Body = []byte(strings.Join(parts, separator))
+		if multipart {
+			config.Body = append(config.Body, "\r\n"...)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return config, fmt.Errorf("line %d: unresolved variables %s", req.Line, strings.Join(unresolved, ", "))
+	}
+	return config, nil
+}
+
+// dynamicVar resolves the REST Client system variables $guid, $timestamp,
+// $isoTimestamp, $randomInt min max and $processEnv NAME.
+func dynamicVar(name string) (string, bool) {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return "", false
+	}
+	switch fields[0] {
+	case "$guid", "$uuid", "$random.uuid":
+		return utils.UUID(), true
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), true
+	case "$randomInt":
+		min, max := 0, 1000
+		if len(fields) == 3 {
+			min, _ = strconv.Atoi(fields[1])
+			max, _ = strconv.Atoi(fields[2])
+		}
+		if max <= min {
+			return strconv.Itoa(min), true
+		}
+		return strconv.Itoa(min + utils.RandomInt(max-min)), true
+	case "$processEnv":
+		if len(fields) == 2 {
+			return os.Getenv(fields[1]), true
+		}
+	}
+	return "", false
+}
+
+// responseVar resolves name.response.body.$.path, name.response.body.*,
+// name.response.headers.Name and name.response.status.
+func responseVar(name string, responses map[string]*HttpResponse) (string, bool) {
+	request, rest, ok := strings.Cut(name, ".response.")
+	resp := responses[request]
+	if !ok || resp == nil {
+		return "", false
+	}
+
+	switch {
+	case rest == "status":
+		return strconv.Itoa(resp.StatusCode), true
+	case rest == "body" || rest == "body.*":
+		return string(resp.Body), true
+	case strings.HasPrefix(rest, "body."):
+		var body interface{}
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			return "", false
+		}
+		value, err := utils.JSONPath(body, strings.TrimPrefix(rest, "body."))
+		if err != nil {
+			return "", false
+		}
+		if s, ok := value.(string); ok {
+			return s, true
+		}
+		data, _ := json.Marshal(value)
+		return string(data), true
+	case strings.HasPrefix(rest, "headers."):
+		header := strings.TrimPrefix(rest, "headers.")
+		if resp.Header != nil {
+			return resp.Header.Get(header), true
+		}
+		return resp.Headers[header], true
+	}
+	return "", false
+}
+
+// HttpFileRunner sends the requests of an HttpFile in order.
+type HttpFileRunner struct {
+	// Client sends the requests, DefaultClient when nil.
+	Client *Client
+	// Vars take precedence over the file variables.
+	Vars    map[string]string
+	Timeout time.Duration
+	// Out receives every request and response when set.
+	Out io.Writer
+	// SaveDir receives one <name>.response file per request when set.
+	SaveDir string
+}
+
+// Run sends the requests of file and returns their responses. It stops at
+// the first request that cannot be built or sent.
+func (r *HttpFileRunner) Run(ctx context.Context, file *HttpFile) ([]*HttpResponse, error) {
+	client := r.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	responses := map[string]*HttpResponse{}
+	var results []*HttpResponse
+	for i, req := range file.Requests {
+		config, err := file.config(req, r.Vars, responses)
+		if err != nil {
+			return results, err
+		}
+		config.Timeout = r.Timeout
+
+		resp, err := client.SendRequestContext(ctx, config)
+		if err != nil {
+			return results, fmt.Errorf("line %d: %w", req.Line, err)
+		}
+		results = append(results, resp)
+		if req.Name != "" {
+			responses[req.Name] = resp
+		}
+
+		if r.Out != nil {
+			fmt.Fprintf(r.Out, "### %s\n%s %s\n\n", req.Name, config.Method, config.URL)
+			writeResponse(r.Out, resp)
+			fmt.Fprintln(r.Out)
+		}
+		if r.SaveDir != "" {
+			if err := r.save(i, req, resp); err != nil {
+				glog.LogL(glog.ERROR, "http file", "saving response", err)
+			}
+		}
+	}
+	return results, nil
+}
+
+func (r *HttpFileRunner) save(index int, req *HttpFileRequest, resp *HttpResponse) error {
+	name := strings.Map(func(c rune) rune {
+		if c == '/' || c == '\\' || c == ':' || c == ' ' {
+			return '_'
+		}
+		return c
+	}, req.Name)
+	if name == "" {
+		name = strconv.Itoa(index + 1)
+	}
+
+	f, err := os.Create(filepath.Join(r.SaveDir, name+".response"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writeResponse(f, resp)
+	return nil
+}
+
+func writeResponse(w io.Writer, resp *HttpResponse) {
+	proto := resp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	fmt.Fprintf(w, "%s %d (%d ms)\n", proto, resp.StatusCode, resp.ElapsedTime)
+
+	keys := make([]string, 0, len(resp.Headers))
+	for key := range resp.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s: %s\n", key, resp.Headers[key])
+	}
+	fmt.Fprintf(w, "\n%s\n", resp.Body)
+}
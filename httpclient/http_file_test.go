@@ -0,0 +1,134 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHttpFile(t *testing.T) {
+	file, err := ParseHttpFile("testdata/api.http")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers int
+	}{
+		{name: "login", method: "POST", url: "{{host}}/login", headers: 1},
+		{name: "profile", method: "GET", url: "{{host}}/me?verbose=true&trace={{$randomInt 5 6}}", headers: 2},
+		{name: "", method: "POST", url: "{{host}}/upload", headers: 1},
+	}
+	if len(file.Requests) != len(tests) {
+		t.Fatalf("Expected %d requests, Got: %d", len(tests), len(file.Requests))
+	}
+	for i, test := range tests {
+		req := file.Requests[i]
+		if req.Name != test.name || req.Method != test.method || req.URL != test.url || len(req.Headers) != test.headers {
+			t.Errorf("Expected %+v, Got: %+v", test, req)
+		}
+	}
+
+	config, err := file.Config(file.Request("login"), map[string]string{"addr": "localhost:8080", "user": "bob"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.URL != "http://localhost:8080/login" || strings.TrimSpace(string(config.Body)) != `{"user": "bob"}` {
+		t.Errorf("Unexpected config: %s %s", config.URL, config.Body)
+	}
+
+	if _, err := file.Config(file.Request("profile"), map[string]string{"addr": "x"}); err == nil || !strings.Contains(err.Error(), "login.response.body.$.token") {
+		t.Errorf("Expected an unresolved variable error, Got: %v", err)
+	}
+
+	if _, err := ParseHttpFileContent("GET http://a b c", "."); err == nil {
+		t.Errorf("Expected an error for an invalid request line")
+	}
+}
+
+func TestHttpFileRunner(t *testing.T) {
+	var upload string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("X-Session", "s1")
+			w.Write([]byte(`{"token": "t0k"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t0k" || r.Header.Get("X-Session") != "s1" || r.URL.Query().Get("trace") != "5" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"name": "bob"}`))
+		case "/upload":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			upload = r.FormValue("title") + ":" + string(data)
+		}
+	}))
+	defer server.Close()
+
+	file, err := ParseHttpFile("testdata/api.http")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	dir := t.TempDir()
+	runner := &HttpFileRunner{
+		Vars:    map[string]string{"addr": strings.TrimPrefix(server.URL, "http://"), "user": "bob"},
+		Out:     &out,
+		SaveDir: dir,
+	}
+	responses, err := runner.Run(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(responses) != 3 || responses[1].StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected responses: %d, profile status %d", len(responses), responses[1].StatusCode)
+	}
+	if upload != "notes:hello file\n" {
+		t.Errorf("Expected the multipart upload, Got: %q", upload)
+	}
+	if !strings.Contains(out.String(), "### profile\nGET "+server.URL+"/me?verbose=true&trace=5") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "login.response"))
+	if err != nil || !strings.Contains(string(saved), `{"token": "t0k"}`) {
+		t.Errorf("Expected the saved login response, Got: %s, %v", saved, err)
+	}
+}
+
+func TestHttpFileMultipartBinary(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+	if err := os.WriteFile(filepath.Join(dir, "image.png"), data, 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file, err := ParseHttpFileContent("POST http://localhost/upload\nContent-Type: multipart/form-data; boundary=XYZ\n\n"+
+		"--XYZ\nContent-Disposition: form-data; name=\"file\"; filename=\"image.png\"\n\n< ./image.png\n--XYZ--\n", dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config, err := file.Config(file.Requests[0], nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\r\n\r\n" + string(data) + "\r\n--XYZ--"
+	if !strings.Contains(string(config.Body), expected) {
+		t.Errorf("Expected the file bytes unchanged, Got: %q", config.Body)
+	}
+}
@@ -13,6 +13,10 @@ import (
 )
 
 func MultipartData(config HttpConfig, textFields map[string]string, fileFields map[string]string) (*HttpResponse, error) {
+	return DefaultClient.MultipartData(config, textFields, fileFields)
+}
+
+func (c *Client) MultipartData(config HttpConfig, textFields map[string]string, fileFields map[string]string) (*HttpResponse, error) {
 	var hresp HttpResponse
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -54,41 +58,40 @@ func MultipartData(config HttpConfig, textFields map[string]string, fileFields m
 
 	request.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
+	client, err := c.httpClient(config.Timeout, false, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
 	glog.LogL(glog.DEBUG, "http ->", "POST", config.URL)
+	timer := newRequestTimer()
 	startTime := time.Now()
-	response, err := client.Do(request)
+	response, err := c.do(client, timer.trace(request), c.authFor(config.Auth))
 	elapsedTime := time.Since(startTime)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
 
-	headers := make(map[string]string)
 	// Parse the response headers into a map
-	for key, values := range response.Header {
-		// Combine multiple header values with a comma (or your preferred delimiter)
-		headers[key] = values[0]
-	}
+	headers := firstHeaderValues(response.Header)
 	var buffer bytes.Buffer
 	_, err = io.Copy(&buffer, response.Body)
 	if err != nil {
 		glog.LogL(glog.DEBUG, "Error reading response body:", err)
 		return nil, err
 	}
+	timing := timer.done()
 	// responseBody := buffer.String()
 	hresp = HttpResponse{
 		StatusCode:  response.StatusCode,
 		Headers:     headers,
 		Body:        []byte{},
 		ElapsedTime: int64(elapsedTime.Milliseconds()),
+		Timing:      timing,
 	}
 
-	if config.LogResponse {
-		glog.LogL(glog.DEBUG, "http <-", hresp.ElapsedTime, hresp.StatusCode, config.Method, config.URL, hresp.Body)
-	} else {
-		glog.LogL(glog.DEBUG, "http <-", hresp.ElapsedTime, hresp.StatusCode, config.Method, config.URL)
-	}
+	logResponse(glog.DEBUG, &hresp, config.Method, config.URL, config.LogResponse)
 
 	// Send the HTTP request
 	// Convert the response body to a string and print it
@@ -98,7 +101,9 @@ func MultipartData(config HttpConfig, textFields map[string]string, fileFields m
 		Headers:     headers,
 		Body:        []byte{},
 		ElapsedTime: int64(elapsedTime.Milliseconds()),
+		Timing:      timing,
 	}
+	hresp.setMeta(response)
 
 	if config.Cache {
 		hresp.CacheTtl = config.CacheTtl
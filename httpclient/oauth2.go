@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2 authorizes requests with an access token obtained from TokenURL,
+// through the refresh_token grant when RefreshToken is set and through the
+// client_credentials grant otherwise. The token is cached and renewed
+// RefreshBefore ahead of its expiry, or after the upstream rejects it.
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RefreshToken string
+	// RefreshBefore defaults to 30 seconds.
+	RefreshBefore time.Duration
+	// CredentialsInBody sends client_id and client_secret as form fields
+	// instead of HTTP Basic authentication.
+	CredentialsInBody bool
+	// Timeout of the token requests, 30 seconds by default.
+	Timeout time.Duration
+	// Client sends the token requests, DefaultClient when nil.
+	Client *Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenType   string
+	expiry      time.Time
+}
+
+// OAuth2Error is returned when the token endpoint rejects a request.
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s (status %d)", e.Code, e.Description, e.StatusCode)
+	}
+	return fmt.Sprintf("oauth2: %s (status %d)", e.Code, e.StatusCode)
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// NewClientCredentials returns a provider for the client_credentials grant.
+func NewClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) *OAuth2 {
+	return &OAuth2{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+	}
+}
+
+// NewRefreshTokenFlow returns a provider for the refresh_token grant.
+func NewRefreshTokenFlow(tokenURL, clientID, clientSecret, refreshToken string) *OAuth2 {
+	return &OAuth2{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+}
+
+func (a *OAuth2) Authorize(request *http.Request) error {
+	token, tokenType, err := a.token()
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", tokenType+" "+token)
+	return nil
+}
+
+// HandleChallenge drops the cached token so the retry fetches a new one.
+func (a *OAuth2) HandleChallenge(request *http.Request, response *http.Response) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.accessToken = ""
+	return true, nil
+}
+
+// Token returns the cached access token, fetching a new one when needed.
+func (a *OAuth2) Token() (string, error) {
+	token, _, err := a.token()
+	return token, err
+}
+
+func (a *OAuth2) token() (string, string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	refreshBefore := a.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = 30 * time.Second
+	}
+
+	if a.accessToken != "" && (a.expiry.IsZero() || time.Now().Add(refreshBefore).Before(a.expiry)) {
+		return a.accessToken, a.tokenType, nil
+	}
+
+	if err := a.fetch(); err != nil {
+		return "", "", err
+	}
+	return a.accessToken, a.tokenType, nil
+}
+
+// fetch requests a new token, a.mu must be held.
+func (a *OAuth2) fetch() error {
+	form := url.Values{}
+	if a.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", a.RefreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+
+	config := HttpConfig{
+		Method: "POST",
+		URL:    a.TokenURL,
+		Headers: map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+			"Accept":       "application/json",
+		},
+		Timeout: a.Timeout,
+		// The client's own provider may be this one, which would lock a.mu
+		// again.
+		Auth: noAuth{},
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if a.CredentialsInBody {
+		form.Set("client_id", a.ClientID)
+		form.Set("client_secret", a.ClientSecret)
+	} else {
+		config.Auth = BasicAuth{Username: url.QueryEscape(a.ClientID), Password: url.QueryEscape(a.ClientSecret)}
+	}
+	config.Body = []byte(form.Encode())
+
+	client := a.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	start := time.Now()
+	response, err := client.SendRequest(config)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		oauthErr := &OAuth2Error{StatusCode: response.StatusCode}
+		if json.Unmarshal(response.Body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = http.StatusText(response.StatusCode)
+		}
+		return oauthErr
+	}
+
+	var token oauth2TokenResponse
+	if err := json.Unmarshal(response.Body, &token); err != nil {
+		return err
+	}
+	if token.AccessToken == "" {
+		return &OAuth2Error{StatusCode: response.StatusCode, Code: "invalid_token_response", Description: "no access_token"}
+	}
+
+	a.accessToken = token.AccessToken
+	a.tokenType = "Bearer"
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		a.tokenType = token.TokenType
+	}
+	a.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		a.expiry = start.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" && a.RefreshToken != "" {
+		a.RefreshToken = token.RefreshToken
+	}
+	return nil
+}
+
+// noAuth sends a request without credentials, overriding the client's
+// provider.
+type noAuth struct{}
+
+func (noAuth) Authorize(request *http.Request) error {
+	return nil
+}
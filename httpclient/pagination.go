@@ -0,0 +1,516 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mgolfam/gogutils/utils"
+)
+
+// Seq2 iterates over values and errors. It has the shape of iter.Seq2 of
+// Go 1.23, so range works on it once the module moves to that version:
+//
+//	for user, err := range paginator.Items(ctx) { ... }
+//
+// Until then call it with a yield function that returns false to stop.
+type Seq2[V any] func(yield func(V, error) bool)
+
+// ErrMaxPages is yielded when a paginator stops at MaxPages while the API
+// has more pages.
+var ErrMaxPages = errors.New("httpclient: max pages reached")
+
+// Paginator walks a paged JSON API and decodes the items of every page
+// into T.
+//
+//	p := &httpclient.Paginator[User]{
+//		Request:   httpclient.HttpConfig{Method: "GET", URL: "https://api.example.com/users"},
+//		ItemsPath: "$.data",
+//		Scheme:    httpclient.Cursor{Param: "after", Path: "$.meta.next"},
+//	}
+//	users, err := p.All(ctx)
+type Paginator[T any] struct {
+	// Client sends the requests, DefaultClient when nil.
+	Client *Client
+	// Request is the request of the first page.
+	Request HttpConfig
+	// ItemsPath selects the items of a page with utils.JSONPath, the whole
+	// body when empty.
+	ItemsPath string
+	Scheme    PageScheme
+	// MaxPages stops the walk with ErrMaxPages, 1000 when zero.
+	MaxPages int
+	// Concurrency fetches this many pages at once with the Offset and
+	// PageNumber schemes. Pages past the last one may be requested, their
+	// items are dropped.
+	Concurrency int
+}
+
+// PageScheme tells the requests of the pages: LinkHeader, Cursor, Offset
+// or PageNumber.
+type PageScheme interface {
+	// first returns the request of the first page.
+	first(base HttpConfig) (HttpConfig, error)
+	// next returns the request of the page after page index, false after
+	// the last page.
+	next(base HttpConfig, page *fetchedPage, index int) (HttpConfig, bool, error)
+}
+
+// indexedScheme requests any page by its index, so pages can be fetched
+// concurrently.
+type indexedScheme interface {
+	PageScheme
+	page(base HttpConfig, index int) (HttpConfig, error)
+	last(page *fetchedPage, index int) bool
+}
+
+type fetchedPage struct {
+	config   HttpConfig
+	response *HttpResponse
+	body     interface{}
+	items    int
+}
+
+// Pages yields the items of every page.
+func (p *Paginator[T]) Pages(ctx context.Context) Seq2[[]T] {
+	return func(yield func([]T, error) bool) {
+		if indexed, ok := p.Scheme.(indexedScheme); ok && p.Concurrency > 1 {
+			p.concurrentPages(ctx, indexed, yield)
+			return
+		}
+
+		config, err := p.Scheme.first(p.Request)
+		for index := 0; err == nil; index++ {
+			var page *fetchedPage
+			var items []T
+			if page, items, err = p.fetch(ctx, config); err != nil {
+				break
+			}
+			if !yield(items, nil) {
+				return
+			}
+			var more bool
+			if config, more, err = p.Scheme.next(p.Request, page, index); err != nil || !more {
+				break
+			}
+			if index+1 >= p.maxPages() {
+				err = ErrMaxPages
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// concurrentPages fetches windows of Concurrency pages and yields them in
+// order.
+func (p *Paginator[T]) concurrentPages(ctx context.Context, scheme indexedScheme, yield func([]T, error) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		page  *fetchedPage
+		items []T
+		err   error
+	}
+	for start := 0; ; start += p.Concurrency {
+		if start >= p.maxPages() {
+			yield(nil, ErrMaxPages)
+			return
+		}
+		count := p.Concurrency
+		if start+count > p.maxPages() {
+			count = p.maxPages() - start
+		}
+
+		results := make([]result, count)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				config, err := scheme.page(p.Request, start+i)
+				if err != nil {
+					results[i].err = err
+					return
+				}
+				results[i].page, results[i].items, results[i].err = p.fetch(ctx, config)
+			}(i)
+		}
+		wg.Wait()
+
+		for i, r := range results {
+			if r.err != nil {
+				yield(nil, r.err)
+				return
+			}
+			if !yield(r.items, nil) || scheme.last(r.page, start+i) {
+				return
+			}
+		}
+	}
+}
+
+// Items yields the items of all pages one by one.
+func (p *Paginator[T]) Items(ctx context.Context) Seq2[T] {
+	return func(yield func(T, error) bool) {
+		p.Pages(ctx)(func(items []T, err error) bool {
+			if err != nil {
+				var zero T
+				return yield(zero, err)
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// All returns the items of all pages.
+func (p *Paginator[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	var failed error
+	p.Pages(ctx)(func(items []T, err error) bool {
+		if err != nil {
+			failed = err
+			return false
+		}
+		all = append(all, items...)
+		return true
+	})
+	return all, failed
+}
+
+// Chan streams the items on a channel, which is closed at the end. The
+// error channel then yields the error that ended the walk, nil when all
+// pages were read. Canceling ctx stops the walk.
+func (p *Paginator[T]) Chan(ctx context.Context) (<-chan T, <-chan error) {
+	items := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		var failed error
+		p.Items(ctx)(func(item T, err error) bool {
+			if err != nil {
+				failed = err
+				return false
+			}
+			select {
+			case items <- item:
+				return true
+			case <-ctx.Done():
+				failed = ctx.Err()
+				return false
+			}
+		})
+		errs <- failed
+	}()
+	return items, errs
+}
+
+func (p *Paginator[T]) maxPages() int {
+	if p.MaxPages > 0 {
+		return p.MaxPages
+	}
+	return 1000
+}
+
+func (p *Paginator[T]) fetch(ctx context.Context, config HttpConfig) (*fetchedPage, []T, error) {
+	client := p.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	if config.Method == "" {
+		config.Method = http.MethodGet
+	}
+	resp, err := client.SendRequestContext(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, &StatusError{Method: config.Method, URL: config.URL, StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+
+	page := &fetchedPage{config: config, response: resp}
+	if err := json.Unmarshal(resp.Body, &page.body); err != nil {
+		return nil, nil, fmt.Errorf("%s %s: decoding page: %w", config.Method, config.URL, err)
+	}
+	selected := page.body
+	if p.ItemsPath != "" {
+		if selected, err = utils.JSONPath(page.body, p.ItemsPath); err != nil {
+			return nil, nil, fmt.Errorf("%s %s: %w", config.Method, config.URL, err)
+		}
+	}
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, fmt.Errorf("%s %s: decoding items: %w", config.Method, config.URL, err)
+	}
+	page.items = len(items)
+	return page, items, nil
+}
+
+// LinkHeader follows the rel="next" links of the RFC 5988 Link header, as
+// sent by GitHub.
+type LinkHeader struct{}
+
+func (LinkHeader) first(base HttpConfig) (HttpConfig, error) {
+	return base, nil
+}
+
+func (LinkHeader) next(base HttpConfig, page *fetchedPage, index int) (HttpConfig, bool, error) {
+	next := NextLink(page.response.Header.Values("Link"))
+	if next == "" {
+		return HttpConfig{}, false, nil
+	}
+	current, err := url.Parse(page.config.URL)
+	if err != nil {
+		return HttpConfig{}, false, err
+	}
+	target, err := current.Parse(next)
+	if err != nil {
+		return HttpConfig{}, false, fmt.Errorf("link %s: %w", next, err)
+	}
+	config := page.config
+	config.URL = target.String()
+	return config, true, nil
+}
+
+// NextLink returns the target of the rel="next" link of Link header
+// values, "" without one.
+func NextLink(values []string) string {
+	for _, value := range values {
+		for _, link := range splitLinks(value) {
+			start, end := strings.IndexByte(link, '<'), strings.IndexByte(link, '>')
+			if start < 0 || end < start {
+				continue
+			}
+			for _, param := range strings.Split(link[end+1:], ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return link[start+1 : end]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// splitLinks splits a Link header value at the commas outside of <> and
+// quotes.
+func splitLinks(value string) []string {
+	var links []string
+	inURL, inQuote, start := false, false, 0
+	for i, r := range value {
+		switch {
+		case r == '<' && !inQuote:
+			inURL = true
+		case r == '>' && !inQuote:
+			inURL = false
+		case r == '"' && !inURL:
+			inQuote = !inQuote
+		case r == ',' && !inURL && !inQuote:
+			links = append(links, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(links, value[start:])
+}
+
+// Cursor sends the cursor found at Path of a page, with utils.JSONPath, as
+// the query parameter Param of the next request. A missing, null or empty
+// cursor ends the walk.
+type Cursor struct {
+	Param string
+	Path  string
+}
+
+func (c Cursor) first(base HttpConfig) (HttpConfig, error) {
+	return base, nil
+}
+
+func (c Cursor) next(base HttpConfig, page *fetchedPage, index int) (HttpConfig, bool, error) {
+	value, err := utils.JSONPath(page.body, c.Path)
+	if err != nil || value == nil {
+		return HttpConfig{}, false, nil
+	}
+	cursor := fmt.Sprint(value)
+	if f, ok := value.(float64); ok {
+		cursor = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if cursor == "" {
+		return HttpConfig{}, false, nil
+	}
+	if previous, _ := queryValue(page.config.URL, c.Param); previous == cursor {
+		return HttpConfig{}, false, fmt.Errorf("cursor %s repeated", cursor)
+	}
+	return withQuery(base, map[string]string{c.Param: cursor})
+}
+
+// Offset pages with offset and limit query parameters. The walk ends with
+// a page shorter than Limit, or at the total found at TotalPath when set.
+type Offset struct {
+	// OffsetParam and LimitParam are "offset" and "limit" by default.
+	OffsetParam string
+	LimitParam  string
+	// Limit is 100 by default.
+	Limit     int
+	TotalPath string
+}
+
+func (o Offset) limit() int {
+	if o.Limit > 0 {
+		return o.Limit
+	}
+	return 100
+}
+
+func (o Offset) first(base HttpConfig) (HttpConfig, error) {
+	return o.page(base, 0)
+}
+
+func (o Offset) page(base HttpConfig, index int) (HttpConfig, error) {
+	offsetParam, limitParam := o.OffsetParam, o.LimitParam
+	if offsetParam == "" {
+		offsetParam = "offset"
+	}
+	if limitParam == "" {
+		limitParam = "limit"
+	}
+	config, _, err := withQuery(base, map[string]string{
+		offsetParam: strconv.Itoa(index * o.limit()),
+		limitParam:  strconv.Itoa(o.limit()),
+	})
+	return config, err
+}
+
+func (o Offset) last(page *fetchedPage, index int) bool {
+	if page.items < o.limit() {
+		return true
+	}
+	total, ok := pathNumber(page.body, o.TotalPath)
+	return ok && (index+1)*o.limit() >= total
+}
+
+func (o Offset) next(base HttpConfig, page *fetchedPage, index int) (HttpConfig, bool, error) {
+	if o.last(page, index) {
+		return HttpConfig{}, false, nil
+	}
+	config, err := o.page(base, index+1)
+	return config, err == nil, err
+}
+
+// PageNumber pages with a page number query parameter. The walk ends with
+// an empty page, a page shorter than Size when set, or at the page count
+// found at TotalPagesPath when set.
+type PageNumber struct {
+	// Param is "page" by default.
+	Param string
+	// ZeroBased numbers the first page 0, as Spring Data does, instead of 1.
+	ZeroBased bool
+	// SizeParam sends Size when both are set.
+	SizeParam      string
+	Size           int
+	TotalPagesPath string
+}
+
+func (n PageNumber) first(base HttpConfig) (HttpConfig, error) {
+	return n.page(base, 0)
+}
+
+func (n PageNumber) page(base HttpConfig, index int) (HttpConfig, error) {
+	param, first := n.Param, 1
+	if param == "" {
+		param = "page"
+	}
+	if n.ZeroBased {
+		first = 0
+	}
+	values := map[string]string{param: strconv.Itoa(first + index)}
+	if n.SizeParam != "" && n.Size > 0 {
+		values[n.SizeParam] = strconv.Itoa(n.Size)
+	}
+	config, _, err := withQuery(base, values)
+	return config, err
+}
+
+func (n PageNumber) last(page *fetchedPage, index int) bool {
+	if page.items == 0 || (n.Size > 0 && page.items < n.Size) {
+		return true
+	}
+	pages, ok := pathNumber(page.body, n.TotalPagesPath)
+	return ok && index+1 >= pages
+}
+
+func (n PageNumber) next(base HttpConfig, page *fetchedPage, index int) (HttpConfig, bool, error) {
+	if n.last(page, index) {
+		return HttpConfig{}, false, nil
+	}
+	config, err := n.page(base, index+1)
+	return config, err == nil, err
+}
+
+// withQuery returns base with the query parameters set.
+func withQuery(base HttpConfig, values map[string]string) (HttpConfig, bool, error) {
+	u, err := url.Parse(base.URL)
+	if err != nil {
+		return HttpConfig{}, false, err
+	}
+	query := u.Query()
+	for key, value := range values {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	base.URL = u.String()
+	return base, true, nil
+}
+
+func queryValue(rawURL, key string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	values, ok := u.Query()[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// pathNumber returns the number at path, false without one.
+func pathNumber(body interface{}, path string) (int, bool) {
+	if path == "" {
+		return 0, false
+	}
+	value, err := utils.JSONPath(body, path)
+	if err != nil {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
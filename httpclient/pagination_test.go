@@ -0,0 +1,213 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+type pageItem struct {
+	ID int `json:"id"`
+}
+
+// pagedItems returns the items with IDs from+1 to from+count, up to total.
+func pagedItems(from, count, total int) []pageItem {
+	items := []pageItem{}
+	for id := from + 1; id <= from+count && id <= total; id++ {
+		items = append(items, pageItem{ID: id})
+	}
+	return items
+}
+
+func itemIDs(items []pageItem) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestPaginatorSchemes(t *testing.T) {
+	const total = 10
+	mux := http.NewServeMux()
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page*3 < total {
+			w.Header().Set("Link", fmt.Sprintf(`</link?page=1>; rel="first", </link?page=%d>; rel="next last"`, page+1))
+		}
+		json.NewEncoder(w).Encode(pagedItems((page-1)*3, 3, total))
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		body := map[string]interface{}{"data": pagedItems(from, 4, total), "meta": map[string]interface{}{}}
+		if from+4 < total {
+			body["meta"] = map[string]interface{}{"next": from + 4}
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": pagedItems(offset, limit, total), "total": total})
+	})
+	mux.HandleFunc("/zero", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"content": pagedItems(page*4, 4, total), "totalPages": 3})
+	})
+	mux.HandleFunc("/pages", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": pagedItems((page-1)*size, size, total)})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		itemsPath string
+		scheme    PageScheme
+	}{
+		{name: "link", path: "/link", scheme: LinkHeader{}},
+		{name: "cursor", path: "/cursor", itemsPath: "$.data", scheme: Cursor{Param: "after", Path: "$.meta.next"}},
+		{name: "offset", path: "/offset", itemsPath: "items", scheme: Offset{Limit: 5, TotalPath: "total"}},
+		{name: "offset short page", path: "/offset", itemsPath: "items", scheme: Offset{Limit: 4}},
+		{name: "zero-based page number", path: "/zero", itemsPath: "content", scheme: PageNumber{ZeroBased: true, TotalPagesPath: "totalPages"}},
+		{name: "page number", path: "/pages", itemsPath: "results", scheme: PageNumber{Param: "p", SizeParam: "size", Size: 3}},
+	}
+
+	want := itemIDs(pagedItems(0, total, total))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Paginator[pageItem]{
+				Request:   HttpConfig{Method: "GET", URL: server.URL + test.path},
+				ItemsPath: test.itemsPath,
+				Scheme:    test.scheme,
+			}
+			items, err := p.All(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := itemIDs(items); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, Got: %v", want, got)
+			}
+
+			var first []int
+			p.Items(context.Background())(func(item pageItem, err error) bool {
+				first = append(first, item.ID)
+				return len(first) < 2
+			})
+			if !reflect.DeepEqual(first, []int{1, 2}) {
+				t.Errorf("Expected to stop after [1 2], Got: %v", first)
+			}
+		})
+	}
+}
+
+func TestPaginatorConcurrency(t *testing.T) {
+	const total = 23
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		json.NewEncoder(w).Encode(pagedItems(offset, limit, total))
+	}))
+	defer server.Close()
+
+	p := &Paginator[pageItem]{
+		Request:     HttpConfig{URL: server.URL + "/?sort=id"},
+		Scheme:      Offset{OffsetParam: "start", LimitParam: "count", Limit: 5},
+		Concurrency: 3,
+	}
+	items, err := p.All(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := itemIDs(items), itemIDs(pagedItems(0, total, total)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, Got: %v", want, got)
+	}
+	// Two windows of three pages, the last one past the end.
+	if n := atomic.LoadInt32(&requests); n != 6 {
+		t.Errorf("Expected 6 requests, Got: %d", n)
+	}
+}
+
+func TestPaginatorErrors(t *testing.T) {
+	endless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		w.Header().Set("Link", fmt.Sprintf(`<?page=%d>; rel="next"`, page+1))
+		json.NewEncoder(w).Encode(pagedItems(page, 1, page+1))
+	}))
+	defer endless.Close()
+
+	p := &Paginator[pageItem]{Request: HttpConfig{URL: endless.URL}, Scheme: LinkHeader{}, MaxPages: 4}
+	items, err := p.All(context.Background())
+	if !errors.Is(err, ErrMaxPages) || len(items) != 4 {
+		t.Errorf("Expected ErrMaxPages after 4 items, Got: %d, %v", len(items), err)
+	}
+	p.Scheme, p.Concurrency = PageNumber{}, 3
+	if items, err := p.All(context.Background()); !errors.Is(err, ErrMaxPages) || len(items) != 4 {
+		t.Errorf("Expected ErrMaxPages after 4 items, Got: %d, %v", len(items), err)
+	}
+
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":1}],"next":"same"}`))
+	}))
+	defer stuck.Close()
+	cursor := &Paginator[pageItem]{Request: HttpConfig{URL: stuck.URL}, ItemsPath: "data", Scheme: Cursor{Param: "c", Path: "next"}}
+	if _, err := cursor.All(context.Background()); err == nil {
+		t.Errorf("Expected an error for a repeated cursor")
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	var statusErr *StatusError
+	failing := &Paginator[pageItem]{Request: HttpConfig{URL: down.URL}, Scheme: LinkHeader{}}
+	if _, err := failing.All(context.Background()); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a 503 StatusError, Got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, errs := (&Paginator[pageItem]{Request: HttpConfig{URL: endless.URL}, Scheme: LinkHeader{}}).Chan(ctx)
+	for item := range stream {
+		if item.ID == 3 {
+			cancel()
+		}
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, Got: %v", err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: []string{`<https://api.example.com/items?page=2>; rel="next"`}, want: "https://api.example.com/items?page=2"},
+		{values: []string{`<a?x=1,2>; rel="prev", <b>; title="a, b"; rel=next`}, want: "b"},
+		{values: []string{`<a>; rel="prev"`, `<c>; REL="last next"`}, want: "c"},
+		{values: []string{`<a>; rel="nextpage"`}, want: ""},
+		{values: nil, want: ""},
+	}
+
+	for _, test := range tests {
+		if got := NextLink(test.values); got != test.want {
+			t.Errorf("Expected %q for %q, Got: %q", test.want, test.values, got)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned, wrapped, when a call is refused because its
+// limit is exhausted and the limit is not in wait mode.
+var ErrRateLimited = errors.New("httpclient: rate limit exceeded")
+
+// RateLimit throttles the calls to one host or URL prefix.
+type RateLimit struct {
+	// Requests per Per, Per defaults to one second. 0 disables the token
+	// bucket so only MaxConcurrent applies.
+	Requests int
+	Per      time.Duration
+	// Burst is the bucket size, Requests when 0.
+	Burst int
+	// MaxConcurrent caps the calls in flight, 0 means no cap. A call is in
+	// flight until its response body is closed.
+	MaxConcurrent int
+	// Wait blocks calls until they may proceed, honoring the context,
+	// instead of failing with ErrRateLimited.
+	Wait bool
+}
+
+// RateLimiter applies RateLimits by host or URL prefix. A pattern with a
+// scheme, such as "https://api.example.com/v2/", matches URLs starting with
+// it; any other pattern, such as "ip-api.com" or "localhost:8080", matches
+// the host. The longest matching pattern wins.
+//
+// Limits adapt to the upstream: Retry-After, X-RateLimit-Remaining with
+// X-RateLimit-Reset, and ip-api.com's X-Rl with X-Ttl pause the limit until
+// the upstream's window resets.
+type RateLimiter struct {
+	mu    sync.Mutex
+	rules []*limitRule
+}
+
+type limitRule struct {
+	pattern string
+	limit   RateLimit
+	slots   chan struct{}
+
+	mu           sync.Mutex
+	tokens       float64
+	capacity     float64
+	perSecond    float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// Set adds or replaces the limit of pattern.
+func (l *RateLimiter) Set(pattern string, limit RateLimit) {
+	rule := &limitRule{pattern: pattern, limit: limit, last: time.Now()}
+	if limit.Requests > 0 {
+		per := limit.Per
+		if per <= 0 {
+			per = time.Second
+		}
+		rule.capacity = float64(limit.Burst)
+		if limit.Burst <= 0 {
+			rule.capacity = float64(limit.Requests)
+		}
+		rule.perSecond = float64(limit.Requests) / per.Seconds()
+		rule.tokens = rule.capacity
+	}
+	if limit.MaxConcurrent > 0 {
+		rule.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, existing := range l.rules {
+		if existing.pattern == pattern {
+			l.rules[i] = rule
+			return
+		}
+	}
+	l.rules = append(l.rules, rule)
+	sort.SliceStable(l.rules, func(a, b int) bool { return len(l.rules[a].pattern) > len(l.rules[b].pattern) })
+}
+
+// match returns the rule for u, or nil.
+func (l *RateLimiter) match(u *url.URL) *limitRule {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	full := u.String()
+	for _, rule := range l.rules {
+		if strings.Contains(rule.pattern, "://") {
+			if strings.HasPrefix(full, rule.pattern) {
+				return rule
+			}
+		} else if strings.EqualFold(rule.pattern, u.Host) || strings.EqualFold(rule.pattern, u.Hostname()) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// acquire waits for, or refuses, permission to send to u. The returned
+// function gives the concurrency slot back.
+func (l *RateLimiter) acquire(ctx context.Context, u *url.URL) (*limitRule, func(), error) {
+	rule := l.match(u)
+	if rule == nil {
+		return nil, func() {}, nil
+	}
+
+	// The slot comes first, so a call refused for concurrency does not
+	// spend a token.
+	release := func() {}
+	if rule.slots != nil {
+		if rule.limit.Wait {
+			select {
+			case rule.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		} else {
+			select {
+			case rule.slots <- struct{}{}:
+			default:
+				return nil, nil, fmt.Errorf("%w: %d calls in flight to %s", ErrRateLimited, rule.limit.MaxConcurrent, rule.pattern)
+			}
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-rule.slots }) }
+	}
+
+	if err := rule.take(ctx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return rule, release, nil
+}
+
+// take consumes one token, waiting for it in wait mode.
+func (r *limitRule) take(ctx context.Context) error {
+	for {
+		wait := r.reserve(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+		if !r.limit.Wait {
+			return fmt.Errorf("%w: %s, retry in %s", ErrRateLimited, r.pattern, wait.Round(time.Millisecond))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long until one is
+// available.
+func (r *limitRule) reserve(now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Before(r.blockedUntil) {
+		return r.blockedUntil.Sub(now)
+	}
+	if r.perSecond == 0 {
+		return 0
+	}
+
+	r.tokens = math.Min(r.capacity, r.tokens+now.Sub(r.last).Seconds()*r.perSecond)
+	r.last = now
+	if r.tokens >= 1 {
+		r.tokens--
+		return 0
+	}
+	return time.Duration((1 - r.tokens) / r.perSecond * float64(time.Second))
+}
+
+// observe adapts the rule to the rate limit headers of a response.
+func (r *limitRule) observe(response *http.Response) {
+	now := time.Now()
+	header := response.Header
+
+	var until time.Time
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(header.Get("Retry-After"), now); ok {
+			until = now.Add(delay)
+		}
+	}
+
+	remaining, hasRemaining := headerInt(header, "X-RateLimit-Remaining", "X-Rl")
+	if hasRemaining && remaining <= 0 {
+		if reset, ok := headerInt(header, "X-RateLimit-Reset", "X-Ttl"); ok {
+			// Large values are unix timestamps, small ones seconds to wait.
+			resetAt := now.Add(time.Duration(reset) * time.Second)
+			if reset > 1e9 {
+				resetAt = time.Unix(reset, 0)
+			}
+			if resetAt.After(until) {
+				until = resetAt
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if until.After(r.blockedUntil) {
+		r.blockedUntil = until
+	}
+	if hasRemaining && r.perSecond > 0 && float64(remaining) < r.tokens {
+		r.tokens = math.Max(float64(remaining), 0)
+	}
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return time.Duration(seconds) * time.Second, seconds >= 0
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now), true
+	}
+	return 0, false
+}
+
+func headerInt(header http.Header, names ...string) (int64, bool) {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// releaseOnClose gives the concurrency slot back when the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+// limit applies the client's rate limiter around send.
+func (c *Client) limit(request *http.Request, send func() (*http.Response, error)) (*http.Response, error) {
+	rule, release, err := c.Limiter.acquire(request.Context(), request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := send()
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if rule != nil {
+		rule.observe(response)
+	}
+	response.Body = &releaseOnClose{ReadCloser: response.Body, release: release}
+	return response, nil
+}
@@ -0,0 +1,180 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter := NewRateLimiter()
+	limiter.Set(hostOf(server.URL), RateLimit{Requests: 2, Per: time.Minute})
+	client := &Client{Limiter: limiter}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, Got: %v", err)
+	}
+
+	// In wait mode calls are spaced out instead of refused.
+	limiter.Set(hostOf(server.URL), RateLimit{Requests: 20, Burst: 1, Wait: true})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected calls to wait for tokens, took: %s", elapsed)
+	}
+
+	// Waiting honors the context.
+	limiter.Set(hostOf(server.URL), RateLimit{Requests: 1, Per: time.Hour, Wait: true})
+	client.SendRequest(HttpConfig{Method: "GET", URL: server.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SendRequestContext(ctx, HttpConfig{Method: "GET", URL: server.URL}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, Got: %v", err)
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter()
+	limiter.Set(server.URL+"/slow/", RateLimit{MaxConcurrent: 2, Wait: true})
+	client := &Client{Limiter: limiter}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.SendRequest(HttpConfig{Method: "GET", URL: server.URL + "/slow/x"})
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Errorf("Expected at most 2 calls in flight, Got: %d", maxInFlight)
+	}
+}
+
+func TestRateLimiterConcurrencyKeepsTokens(t *testing.T) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-unblock
+		}
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter()
+	limiter.Set(hostOf(server.URL), RateLimit{Requests: 2, Per: time.Minute, MaxConcurrent: 1})
+	client := &Client{Limiter: limiter}
+
+	done := make(chan error)
+	go func() {
+		_, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL + "/slow"})
+		done <- err
+	}()
+	<-started
+	// Refused for concurrency, so the second token is left unspent.
+	if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, Got: %v", err)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+		t.Errorf("Expected the second token to be available, Got: %v", err)
+	}
+}
+
+func TestRateLimiterAdaptsToHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+	}{
+		{name: "Retry-After", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}},
+		{name: "X-RateLimit", status: http.StatusOK, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "60"}},
+		{name: "ip-api.com", status: http.StatusOK, headers: map[string]string{"X-Rl": "0", "X-Ttl": "60"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range test.headers {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			limiter := NewRateLimiter()
+			limiter.Set(hostOf(server.URL), RateLimit{Requests: 100})
+			client := &Client{Limiter: limiter}
+
+			if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := client.SendRequest(HttpConfig{Method: "GET", URL: server.URL}); !errors.Is(err, ErrRateLimited) {
+				t.Errorf("Expected ErrRateLimited after the upstream ran out, Got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMatch(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.Set("api.example.com", RateLimit{Requests: 1})
+	limiter.Set("https://api.example.com/v2/", RateLimit{Requests: 2})
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://api.example.com/v1/users", expected: "api.example.com"},
+		{url: "https://api.example.com/v2/users", expected: "https://api.example.com/v2/"},
+		{url: "http://api.example.com:8080/v2/users", expected: "api.example.com"},
+		{url: "https://other.example.com/", expected: ""},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		got := ""
+		if rule := limiter.match(u); rule != nil {
+			got = rule.pattern
+		}
+		if got != test.expected {
+			t.Errorf("Expected %s to match %q, Got: %q", test.url, test.expected, got)
+		}
+	}
+}
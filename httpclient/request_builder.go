@@ -0,0 +1,366 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestBuilder builds an HttpConfig step by step. Errors are kept until
+// Build, so calls can be chained:
+//
+//	config, err := httpclient.NewRequest("GET", "http://ip-api.com").
+//		Path("/json/{ip}").
+//		Param("ip", ip).
+//		Query("fields", "status", "country").
+//		Timeout(10 * time.Second).
+//		Build()
+type RequestBuilder struct {
+	method  string
+	baseURL string
+	path    string
+	params  map[string]string
+	query   url.Values
+	headers map[string]string
+	body    []byte
+	form    url.Values
+	config  HttpConfig
+	client  *Client
+	err     error
+}
+
+func NewRequest(method, baseURL string) *RequestBuilder {
+	return &RequestBuilder{
+		method:  method,
+		baseURL: baseURL,
+		params:  map[string]string{},
+		query:   url.Values{},
+		headers: map[string]string{},
+	}
+}
+
+// Path sets the path template, appended to the base URL. Placeholders such
+// as {id} are replaced by Param values.
+func (b *RequestBuilder) Path(template string) *RequestBuilder {
+	b.path = template
+	return b
+}
+
+// Param sets the value of a path placeholder. It is escaped as one path
+// segment.
+func (b *RequestBuilder) Param(name string, value interface{}) *RequestBuilder {
+	s, err := formatValue(reflect.ValueOf(value))
+	if err != nil {
+		b.setErr(fmt.Errorf("param %s: %w", name, err))
+		return b
+	}
+	b.params[name] = s
+	return b
+}
+
+// Query adds values to the query parameter key. Several values repeat the
+// key, as in ?tag=a&tag=b; slices are expanded the same way.
+func (b *RequestBuilder) Query(key string, values ...interface{}) *RequestBuilder {
+	for _, value := range values {
+		if err := addValues(b.query, key, reflect.ValueOf(value)); err != nil {
+			b.setErr(fmt.Errorf("query %s: %w", key, err))
+		}
+	}
+	return b
+}
+
+// QueryStruct adds the fields of v, a struct or pointer to struct, to the
+// query. Fields are named by their query tag, `query:"name,omitempty"`,
+// or by the field name; `query:"-"` skips a field.
+func (b *RequestBuilder) QueryStruct(v interface{}) *RequestBuilder {
+	if err := encodeStruct(b.query, reflect.ValueOf(v)); err != nil {
+		b.setErr(fmt.Errorf("query: %w", err))
+	}
+	return b
+}
+
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	b.headers[key] = value
+	return b
+}
+
+// Form adds url-encoded form values and makes the form the request body.
+func (b *RequestBuilder) Form(key string, values ...interface{}) *RequestBuilder {
+	if b.form == nil {
+		b.form = url.Values{}
+	}
+	for _, value := range values {
+		if err := addValues(b.form, key, reflect.ValueOf(value)); err != nil {
+			b.setErr(fmt.Errorf("form %s: %w", key, err))
+		}
+	}
+	return b
+}
+
+// FormStruct adds the fields of v to the form, tagged like QueryStruct.
+func (b *RequestBuilder) FormStruct(v interface{}) *RequestBuilder {
+	if b.form == nil {
+		b.form = url.Values{}
+	}
+	if err := encodeStruct(b.form, reflect.ValueOf(v)); err != nil {
+		b.setErr(fmt.Errorf("form: %w", err))
+	}
+	return b
+}
+
+// JSON marshals v as the request body.
+func (b *RequestBuilder) JSON(v interface{}) *RequestBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.setErr(fmt.Errorf("json body: %w", err))
+		return b
+	}
+	return b.Body("application/json", data)
+}
+
+// Body sets a raw request body of the given content type.
+func (b *RequestBuilder) Body(contentType string, body []byte) *RequestBuilder {
+	b.body = body
+	if contentType != "" {
+		b.headers["Content-Type"] = contentType
+	}
+	return b
+}
+
+func (b *RequestBuilder) Timeout(timeout time.Duration) *RequestBuilder {
+	b.config.Timeout = timeout
+	return b
+}
+
+// Cache stores the response for ttl and serves it from the cache while it
+// is fresh.
+func (b *RequestBuilder) Cache(ttl time.Duration) *RequestBuilder {
+	b.config.Cache = true
+	b.config.RetrieveCache = true
+	b.config.CacheTtl = int64(ttl / time.Second)
+	return b
+}
+
+func (b *RequestBuilder) Auth(auth AuthProvider) *RequestBuilder {
+	b.config.Auth = auth
+	return b
+}
+
+func (b *RequestBuilder) TLS(tlsConfig *TLSConfig) *RequestBuilder {
+	b.config.TLS = tlsConfig
+	return b
+}
+
+func (b *RequestBuilder) UseProxy() *RequestBuilder {
+	b.config.UseProxy = true
+	return b
+}
+
+func (b *RequestBuilder) LogResponse() *RequestBuilder {
+	b.config.LogResponse = true
+	return b
+}
+
+// Client sets the client used by Send, DefaultClient by default.
+func (b *RequestBuilder) Client(c *Client) *RequestBuilder {
+	b.client = c
+	return b
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// Build returns the HttpConfig, or the first error of the chain.
+func (b *RequestBuilder) Build() (HttpConfig, error) {
+	if b.err != nil {
+		return HttpConfig{}, b.err
+	}
+
+	var missing []string
+	path := placeholderPattern.ReplaceAllStringFunc(b.path, func(m string) string {
+		name := m[1 : len(m)-1]
+		value, ok := b.params[name]
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return HttpConfig{}, fmt.Errorf("path %s: missing params %s", b.path, strings.Join(missing, ", "))
+	}
+
+	u, err := url.Parse(joinURL(b.baseURL, path))
+	if err != nil {
+		return HttpConfig{}, err
+	}
+	if len(b.query) > 0 {
+		query := u.Query()
+		for key, values := range b.query {
+			query[key] = append(query[key], values...)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	config := b.config
+	config.Method = b.method
+	config.URL = u.String()
+	config.Body = b.body
+	if len(b.headers) > 0 || b.form != nil {
+		config.Headers = make(map[string]string, len(b.headers)+1)
+		for key, value := range b.headers {
+			config.Headers[key] = value
+		}
+	}
+	if b.form != nil {
+		config.Body = []byte(b.form.Encode())
+		config.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+	return config, nil
+}
+
+func (b *RequestBuilder) Send() (*HttpResponse, error) {
+	return b.SendContext(context.Background())
+}
+
+func (b *RequestBuilder) SendContext(ctx context.Context) (*HttpResponse, error) {
+	config, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	client := b.client
+	if client == nil {
+		client = DefaultClient
+	}
+	return client.SendRequestContext(ctx, config)
+}
+
+func (b *RequestBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// addValues adds v to values, expanding slices and skipping nil pointers.
+func addValues(values url.Values, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8) || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if err := addValues(values, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := formatValue(v)
+	if err != nil {
+		return err
+	}
+	values.Add(key, s)
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func formatValue(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+		// Other lists are joined by commas, the simple style of OpenAPI
+		// path parameters.
+		items := make([]string, v.Len())
+		for i := range items {
+			item, err := formatValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return strings.Join(items, ","), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return "", nil
+		}
+		return formatValue(v.Elem())
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func encodeStruct(values url.Values, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("expected a struct, got %s", v.Kind())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("query")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+
+		if field.Anonymous && name == "" && value.Kind() == reflect.Struct && field.Type != timeType {
+			if err := encodeStruct(values, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if opts == "omitempty" && value.IsZero() {
+			continue
+		}
+		if err := addValues(values, name, value); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type searchQuery struct {
+	Term    string    `query:"q"`
+	Tags    []string  `query:"tag"`
+	Page    int       `query:"page,omitempty"`
+	Exact   *bool     `query:"exact"`
+	Since   time.Time `query:"since,omitempty"`
+	Secret  string    `query:"-"`
+	Verbose bool
+}
+
+func TestRequestBuilder(t *testing.T) {
+	exact := true
+	tests := []struct {
+		name        string
+		builder     *RequestBuilder
+		expectedURL string
+		expectError bool
+	}{
+		{
+			name:        "path params are escaped",
+			builder:     NewRequest("GET", "https://api.example.com/v1/").Path("/users/{id}/files/{name}").Param("id", 42).Param("name", "a b/c.txt"),
+			expectedURL: "https://api.example.com/v1/users/42/files/a%20b%2Fc.txt",
+		},
+		{
+			name:        "typed and repeated query params",
+			builder:     NewRequest("GET", "https://api.example.com/search?lang=en").Query("tag", "a", "b").Query("limit", 10).Query("ratio", 0.5).Query("ids", []int{1, 2}),
+			expectedURL: "https://api.example.com/search?ids=1&ids=2&lang=en&limit=10&ratio=0.5&tag=a&tag=b",
+		},
+		{
+			name:        "struct query",
+			builder:     NewRequest("GET", "https://api.example.com/search").QueryStruct(&searchQuery{Term: "go lang", Tags: []string{"x", "y"}, Exact: &exact, Secret: "s"}),
+			expectedURL: "https://api.example.com/search?Verbose=false&exact=true&q=go+lang&tag=x&tag=y",
+		},
+		{
+			name:        "slice path params are joined",
+			builder:     NewRequest("GET", "https://api.example.com").Path("/a/{ids}/{raw}").Param("ids", []string{"a", "b c"}).Param("raw", []byte("x")),
+			expectedURL: "https://api.example.com/a/a%2Cb%20c/x",
+		},
+		{
+			name:        "unsupported param element",
+			builder:     NewRequest("GET", "https://api.example.com").Path("/a/{ids}").Param("ids", []map[string]int{{}}),
+			expectError: true,
+		},
+		{
+			name:        "missing param",
+			builder:     NewRequest("GET", "https://api.example.com").Path("/users/{id}"),
+			expectError: true,
+		},
+		{
+			name:        "unsupported query value",
+			builder:     NewRequest("GET", "https://api.example.com").Query("m", map[string]int{}),
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := test.builder.Build()
+			if test.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.URL != test.expectedURL {
+				t.Errorf("Expected URL: %s, Got: %s", test.expectedURL, config.URL)
+			}
+		})
+	}
+}
+
+func TestRequestBuilderBodies(t *testing.T) {
+	config, err := NewRequest("POST", "https://api.example.com/login").
+		Form("user", "bob").
+		Form("scope", "read", "write").
+		Timeout(5 * time.Second).
+		Cache(time.Hour).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(config.Body) != "scope=read&scope=write&user=bob" || config.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Errorf("Unexpected form body: %s, %v", config.Body, config.Headers)
+	}
+	if config.Timeout != 5*time.Second || !config.Cache || !config.RetrieveCache || config.CacheTtl != 3600 {
+		t.Errorf("Unexpected options: %+v", config)
+	}
+
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &received)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+
+	resp, err := NewRequest("PUT", server.URL).Path("/items/{id}").Param("id", "7").JSON(map[string]int{"qty": 3}).Send()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received["qty"] != float64(3) || resp.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Unexpected JSON request: %v, %v", received, resp.Headers)
+	}
+}
@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HMACAuth signs every request with a shared secret. The signature covers
+// the string built by Canonical and is sent in Header as
+//
+//	HMAC keyId="...", algorithm="hmac-sha256", headers="date host", signature="..."
+type HMACAuth struct {
+	KeyID  string
+	Secret []byte
+	// Hash defaults to sha256.New, Algorithm names it in the header.
+	Hash      func() hash.Hash
+	Algorithm string
+	// Header receives the signature, Authorization by default.
+	Header string
+	// SignedHeaders are added to the canonical string in this order.
+	SignedHeaders []string
+	// Canonical builds the string to sign, HMACCanonicalString when nil.
+	Canonical func(request *http.Request, body []byte, signedHeaders []string) string
+	// Now stamps the Date header when the request has none.
+	Now func() time.Time
+}
+
+// HMACCanonicalString is the default string to sign of HMACAuth: the
+// method, the request URI, the Date header and the hex SHA-256 of the body
+// on their own lines, followed by "name:value" lines of the signed headers.
+func HMACCanonicalString(request *http.Request, body []byte, signedHeaders []string) string {
+	bodyHash := sha256.Sum256(body)
+
+	var sb strings.Builder
+	sb.WriteString(request.Method + "\n")
+	sb.WriteString(request.URL.RequestURI() + "\n")
+	sb.WriteString(request.Header.Get("Date") + "\n")
+	sb.WriteString(hex.EncodeToString(bodyHash[:]) + "\n")
+	for _, name := range signedHeaders {
+		sb.WriteString(strings.ToLower(name) + ":" + strings.TrimSpace(headerValue(request, name)) + "\n")
+	}
+	return sb.String()
+}
+
+func (a *HMACAuth) Authorize(request *http.Request) error {
+	if request.Header.Get("Date") == "" {
+		request.Header.Set("Date", signingTime(a.Now).UTC().Format(http.TimeFormat))
+	}
+
+	body, err := requestBody(request)
+	if err != nil {
+		return err
+	}
+
+	canonical := a.Canonical
+	if canonical == nil {
+		canonical = HMACCanonicalString
+	}
+	newHash, algorithm := a.Hash, a.Algorithm
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	if algorithm == "" {
+		algorithm = "hmac-sha256"
+	}
+
+	mac := hmac.New(newHash, a.Secret)
+	mac.Write([]byte(canonical(request, body, a.SignedHeaders)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	header := a.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	signed := strings.ToLower(strings.Join(a.SignedHeaders, " "))
+	request.Header.Set(header, fmt.Sprintf(`HMAC keyId="%s", algorithm="%s", headers="%s", signature="%s"`,
+		a.KeyID, algorithm, signed, signature))
+	return nil
+}
+
+// AWSSigV4 signs requests with AWS Signature Version 4.
+type AWSSigV4 struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Service         string
+	// SignPayloadHeader adds X-Amz-Content-Sha256, which S3 requires.
+	SignPayloadHeader bool
+	Now               func() time.Time
+}
+
+const awsSigV4Algorithm = "AWS4-HMAC-SHA256"
+
+func (a *AWSSigV4) Authorize(request *http.Request) error {
+	body, err := requestBody(request)
+	if err != nil {
+		return err
+	}
+	payloadHash := sha256.Sum256(body)
+	payload := hex.EncodeToString(payloadHash[:])
+
+	now := signingTime(a.Now).UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + a.Region + "/" + a.Service + "/aws4_request"
+
+	request.Header.Del("Authorization")
+	request.Header.Set("X-Amz-Date", amzDate)
+	if a.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", a.SessionToken)
+	}
+	if a.SignPayloadHeader {
+		request.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		a.canonicalURI(request.URL.EscapedPath()),
+		awsCanonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		awsSigV4Algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, a.Region)
+	key = hmacSHA256(key, a.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigV4Algorithm, a.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// canonicalURI encodes every path segment, twice for all services but S3.
+func (a *AWSSigV4) canonicalURI(escapedPath string) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	if a.Service == "s3" {
+		return escapedPath
+	}
+
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func awsCanonicalQuery(query map[string][]string) string {
+	// Pairs sort by encoded key, then value: sorting the joined strings
+	// puts "a-b=" before "a=".
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{awsEscape(key), awsEscape(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	joined := make([]string, len(pairs))
+	for i, pair := range pairs {
+		joined[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(joined, "&")
+}
+
+// awsEscape percent-encodes everything but the RFC 3986 unreserved
+// characters.
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func headerValue(request *http.Request, name string) string {
+	if strings.EqualFold(name, "host") {
+		if request.Host != "" {
+			return request.Host
+		}
+		return request.URL.Host
+	}
+	return request.Header.Get(name)
+}
+
+func signingTime(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
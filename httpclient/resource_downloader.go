@@ -12,6 +12,12 @@ import (
 
 // Download downloads a file from a remote URL and saves it to a local file.
 func Download(remoteURL string, headers map[string]string, filePath, uagent string) (HttpResponse, error) {
+	return DefaultClient.Download(remoteURL, headers, filePath, uagent)
+}
+
+// Download downloads a file from a remote URL with the client's state, such
+// as its cookie jar, and saves it to a local file.
+func (c *Client) Download(remoteURL string, headers map[string]string, filePath, uagent string) (HttpResponse, error) {
 	resp := HttpResponse{}
 	// Create or open the local file where the content will be saved
 	file, err := os.Create(filePath)
@@ -21,7 +27,11 @@ func Download(remoteURL string, headers map[string]string, filePath, uagent stri
 	defer file.Close()
 
 	// Create a new HTTP client with custom headers
-	client := &http.Client{}
+	client, err := c.httpClient(0, false, nil)
+	if err != nil {
+		return resp, err
+	}
+
 	request, err := http.NewRequest("GET", remoteURL, nil)
 	if err != nil {
 		return resp, err
@@ -39,8 +49,9 @@ func Download(remoteURL string, headers map[string]string, filePath, uagent stri
 
 	// Send the HTTP request
 	glog.LogL(glog.DEBUG, "http ->", "download", request.URL)
+	timer := newRequestTimer()
 	startTime := time.Now()
-	response, err := client.Do(request)
+	response, err := c.do(client, timer.trace(request), c.Auth)
 	elapsedTime := time.Since(startTime)
 	resp.ElapsedTime = int64(elapsedTime.Milliseconds())
 	if err != nil {
@@ -55,6 +66,7 @@ func Download(remoteURL string, headers map[string]string, filePath, uagent stri
 	}
 
 	resBody, err := io.ReadAll(response.Body)
+	resp.Timing = timer.done()
 	// Copy the response body to the local file
 	// _, err = io.Copy(file, response.Body)
 	if err != nil {
@@ -0,0 +1,307 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the last event ID of the stream when the event was dispatched.
+	ID string
+	// Event is the event type, "message" when the server sent none.
+	Event string
+	Data  string
+	// Retry is the reconnection delay the event carried, zero for none.
+	Retry time.Duration
+}
+
+// EventSourceConfig contains the configuration of an SSE subscription.
+type EventSourceConfig struct {
+	URL     string
+	Headers map[string]string
+	// LastEventID resumes the stream after the event with this ID.
+	LastEventID string
+	// Retry is the reconnection delay until the server sends its own,
+	// 3 seconds by default.
+	Retry time.Duration
+	// MaxRetries stops after this many reconnections in a row without an
+	// event, 0 retries forever.
+	MaxRetries int
+	// MaxLine limits the length of a line of the stream, 1 MiB by default.
+	MaxLine  int
+	UseProxy bool
+	Auth     AuthProvider
+	TLS      *TLSConfig
+}
+
+// ErrNotEventStream is returned when the server does not answer with a
+// text/event-stream.
+var ErrNotEventStream = errors.New("httpclient: response is not an event stream")
+
+// Subscribe calls handler for every event of the stream at config.URL
+// with DefaultClient, see Client.Subscribe.
+func Subscribe(ctx context.Context, config EventSourceConfig, handler func(Event) error) error {
+	return DefaultClient.Subscribe(ctx, config, handler)
+}
+
+// Events streams the events at config.URL with DefaultClient, see
+// Client.Events.
+func Events(ctx context.Context, config EventSourceConfig) (<-chan Event, <-chan error) {
+	return DefaultClient.Events(ctx, config)
+}
+
+// Subscribe calls handler for every event of the stream at config.URL until
+// ctx is done, the handler fails or the server ends the stream with 204 No
+// Content. Dropped connections, 429 and 5xx answers are retried after the
+// delay the server asked for, sending the Last-Event-ID header; other
+// statuses end the subscription with a *StatusError. It returns ctx.Err()
+// once ctx is done.
+func (c *Client) Subscribe(ctx context.Context, config EventSourceConfig, handler func(Event) error) error {
+	client, err := c.httpClient(0, config.UseProxy, config.TLS)
+	if err != nil {
+		return err
+	}
+
+	retry := config.Retry
+	if retry <= 0 {
+		retry = 3 * time.Second
+	}
+	lastID := config.LastEventID
+	failures := 0
+	for {
+		received, err := c.stream(ctx, client, config, lastID, func(event Event) error {
+			if event.Retry > 0 {
+				retry = event.Retry
+			}
+			lastID = event.ID
+			if event.Data == "" && event.Event == "" {
+				return nil
+			}
+			return handler(event)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var statusErr *StatusError
+		var fatal *fatalError
+		switch {
+		case err == errStreamDone:
+			return nil
+		case errors.As(err, &fatal):
+			return fatal.err
+		case errors.As(err, &statusErr) && statusErr.StatusCode != http.StatusTooManyRequests && statusErr.StatusCode < 500,
+			err == ErrNotEventStream, errors.Is(err, ErrCircuitOpen):
+			return err
+		}
+
+		if received {
+			failures = 0
+		} else if failures++; config.MaxRetries > 0 && failures > config.MaxRetries {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		glog.LogL(glog.INFO, "sse reconnect in", retry, config.URL, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// Events streams the events of Subscribe on a channel, which is closed when
+// the subscription ends. The error channel then yields its error, nil when
+// the server ended the stream.
+func (c *Client) Events(ctx context.Context, config EventSourceConfig) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		errs <- c.Subscribe(ctx, config, func(event Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return events, errs
+}
+
+// errStreamDone ends a subscription the server closed with 204.
+var errStreamDone = errors.New("event stream done")
+
+// fatalError ends a subscription without reconnecting, such as a failed
+// handler.
+type fatalError struct{ err error }
+
+func (e *fatalError) Error() string { return e.err.Error() }
+
+// stream reads one connection of a subscription. It reports whether an
+// event was received, so failed reconnections can be counted.
+func (c *Client) stream(ctx context.Context, client *http.Client, config EventSourceConfig, lastID string, dispatch func(Event) error) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, config.URL, nil)
+	if err != nil {
+		return false, &fatalError{err}
+	}
+	for key, value := range config.Headers {
+		request.Header.Set(key, value)
+	}
+	request.Header.Set("Accept", "text/event-stream")
+	request.Header.Set("Cache-Control", "no-cache")
+	if lastID != "" {
+		request.Header.Set("Last-Event-ID", lastID)
+	}
+
+	glog.LogL(glog.INFO, "sse ->", config.URL)
+	response, err := c.do(client, request, c.authFor(config.Auth))
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNoContent {
+		return false, errStreamDone
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return false, &StatusError{Method: http.MethodGet, URL: config.URL, StatusCode: response.StatusCode, Body: body}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return false, ErrNotEventStream
+	}
+
+	reader := newEventReader(response.Body, config.MaxLine)
+	reader.lastID = lastID
+	received := false
+	for {
+		event, err := reader.next()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return received, err
+		}
+		received = true
+		if err := dispatch(event); err != nil {
+			return received, &fatalError{err}
+		}
+	}
+}
+
+// eventReader parses the text/event-stream format.
+type eventReader struct {
+	scanner *bufio.Scanner
+	lastID  string
+	started bool
+}
+
+func newEventReader(r io.Reader, maxLine int) *eventReader {
+	if maxLine <= 0 {
+		maxLine = 1 << 20
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLine)
+	scanner.Split(scanEventLines)
+	return &eventReader{scanner: scanner}
+}
+
+// next returns the next event. Blocks carrying only an id or a retry field
+// are returned with empty Event and Data, for their state.
+func (r *eventReader) next() (Event, error) {
+	var event Event
+	var data strings.Builder
+	hasData, hasField := false, false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if !r.started {
+			r.started = true
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+
+		if line == "" {
+			if !hasData && !hasField {
+				event = Event{}
+				continue
+			}
+			event.ID = r.lastID
+			if hasData {
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+			} else {
+				event.Event = ""
+			}
+			return event, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+				hasField = true
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 && strings.Trim(value, "0123456789") == "" {
+				event.Retry = time.Duration(ms) * time.Millisecond
+				hasField = true
+			}
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	// An event without its blank line is discarded at the end of the stream.
+	return Event{}, io.EOF
+}
+
+// scanEventLines splits lines ended by CRLF, LF or CR.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A CR at the end of the buffer may be followed by an LF.
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
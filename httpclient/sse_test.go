@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		expected []Event
+	}{
+		{
+			name:   "fields",
+			stream: "\uFEFF: comment\nevent: update\nid: 1\ndata: a\ndata:b\n\ndata: plain\n\n",
+			expected: []Event{
+				{ID: "1", Event: "update", Data: "a\nb"},
+				{ID: "1", Event: "message", Data: "plain"},
+			},
+		},
+		{
+			name:     "line endings",
+			stream:   "data: crlf\r\n\r\ndata: cr\r\rdata: lf\n\n",
+			expected: []Event{{Event: "message", Data: "crlf"}, {Event: "message", Data: "cr"}, {Event: "message", Data: "lf"}},
+		},
+		{
+			name:     "retry and id only blocks",
+			stream:   "retry: 1500\n\nretry: soon\nid: 7\n\nevent: lost\n\ndata\n\n",
+			expected: []Event{{Retry: 1500 * time.Millisecond}, {ID: "7"}, {ID: "7", Event: "message"}},
+		},
+		{
+			name:     "unterminated event",
+			stream:   "data: kept\n\ndata: dropped",
+			expected: []Event{{Event: "message", Data: "kept"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := newEventReader(strings.NewReader(test.stream), 0)
+			var got []Event
+			for {
+				event, err := reader.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				got = append(got, event)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected %+v, Got: %+v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSubscribeReconnects(t *testing.T) {
+	var connections int32
+	var lastIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		if r.Header.Get("Authorization") != "Bearer t0k" || r.Header.Get("X-Client") != "test" {
+			t.Errorf("Expected the client headers and auth, Got: %v", r.Header)
+		}
+		switch n {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "retry: 10\nid: 1\ndata: one\n\nid: 2\ndata: two\n\n")
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+			io.WriteString(w, "id: 3\nevent: last\ndata: three\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{Auth: BearerToken{Token: "t0k"}}
+	var got []Event
+	start := time.Now()
+	err := client.Subscribe(context.Background(), EventSourceConfig{URL: server.URL, Headers: map[string]string{"X-Client": "test"}, Retry: time.Minute}, func(event Event) error {
+		got = append(got, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the server retry delay to be used, took %v", time.Since(start))
+	}
+	expected := []Event{
+		{ID: "1", Event: "message", Data: "one", Retry: 10 * time.Millisecond},
+		{ID: "2", Event: "message", Data: "two"},
+		{ID: "3", Event: "last", Data: "three"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, Got: %+v", expected, got)
+	}
+	if !reflect.DeepEqual(lastIDs, []string{"", "2", "2", "3"}) {
+		t.Errorf("Expected Last-Event-ID headers [ 2 2 3], Got: %q", lastIDs)
+	}
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, "{}")
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "data: tick\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	var statusErr *StatusError
+	err := Subscribe(context.Background(), EventSourceConfig{URL: server.URL + "/forbidden"}, func(Event) error { return nil })
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a 403 StatusError, Got: %v", err)
+	}
+	err = Subscribe(context.Background(), EventSourceConfig{URL: server.URL + "/json"}, func(Event) error { return nil })
+	if err != ErrNotEventStream {
+		t.Errorf("Expected ErrNotEventStream, Got: %v", err)
+	}
+	err = Subscribe(context.Background(), EventSourceConfig{URL: server.URL + "/down", Retry: time.Millisecond, MaxRetries: 2}, func(Event) error { return nil })
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a 502 StatusError after the retries, Got: %v", err)
+	}
+
+	stop := errors.New("stop")
+	err = Subscribe(context.Background(), EventSourceConfig{URL: server.URL}, func(Event) error { return stop })
+	if err != stop {
+		t.Errorf("Expected the handler error, Got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := Events(ctx, EventSourceConfig{URL: server.URL})
+	if event := <-events; event.Data != "tick" {
+		t.Errorf("Expected tick, Got: %+v", event)
+	}
+	cancel()
+	for range events {
+	}
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, Got: %v", err)
+	}
+}
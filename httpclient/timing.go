@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is the per-phase breakdown of a call, captured with
+// net/http/httptrace. Phases that did not happen, such as DNS for an IP
+// address or everything connection related on a reused connection, are 0.
+// With redirects DNS, Connect and TLSHandshake add up over all hops.
+type Timing struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// ServerProcessing is the time from writing the request to the first
+	// response byte, the server's think-time.
+	ServerProcessing time.Duration
+	// TimeToFirstByte and Total are measured from the moment the request
+	// reaches the transport, after the client's rate limiter, circuit
+	// breaker and token waits.
+	TimeToFirstByte time.Duration
+	// Transfer is the time spent reading the body after the first byte.
+	Transfer     time.Duration
+	Total        time.Duration
+	ConnReused   bool
+	ConnWasIdle  bool
+	ConnIdleTime time.Duration
+}
+
+func (t Timing) String() string {
+	return fmt.Sprintf("dns=%dms connect=%dms tls=%dms server=%dms ttfb=%dms transfer=%dms total=%dms reused=%t",
+		t.DNS.Milliseconds(), t.Connect.Milliseconds(), t.TLSHandshake.Milliseconds(),
+		t.ServerProcessing.Milliseconds(), t.TimeToFirstByte.Milliseconds(),
+		t.Transfer.Milliseconds(), t.Total.Milliseconds(), t.ConnReused)
+}
+
+// requestTimer collects the httptrace events of one call. The hooks may
+// fire from several goroutines when the dialer races connection attempts.
+type requestTimer struct {
+	mu        sync.Mutex
+	start     time.Time
+	sent      bool
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	wrote     time.Time
+	firstByte time.Time
+	timing    Timing
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+// trace returns request with the timer's hooks attached to its context.
+func (rt *requestTimer) trace(request *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		// The first connection lookup is where sending starts; the start
+		// of the call is only a fallback for calls that never got there.
+		GetConn: func(string) {
+			rt.mu.Lock()
+			if !rt.sent {
+				rt.start = time.Now()
+				rt.sent = true
+			}
+			rt.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rt.mu.Lock()
+			rt.dnsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rt.mu.Lock()
+			rt.timing.DNS += time.Since(rt.dnsStart)
+			rt.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			rt.mu.Lock()
+			if rt.connStart.IsZero() {
+				rt.connStart = time.Now()
+			}
+			rt.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			rt.mu.Lock()
+			if err == nil && !rt.connStart.IsZero() {
+				rt.timing.Connect += time.Since(rt.connStart)
+				rt.connStart = time.Time{}
+			}
+			rt.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			rt.mu.Lock()
+			rt.tlsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			rt.mu.Lock()
+			rt.timing.TLSHandshake += time.Since(rt.tlsStart)
+			rt.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			rt.mu.Lock()
+			rt.timing.ConnReused = info.Reused
+			rt.timing.ConnWasIdle = info.WasIdle
+			rt.timing.ConnIdleTime = info.IdleTime
+			rt.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			rt.mu.Lock()
+			rt.wrote = time.Now()
+			rt.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			rt.mu.Lock()
+			rt.firstByte = time.Now()
+			rt.mu.Unlock()
+		},
+	}
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+}
+
+// done closes the measurement once the body has been read and returns the
+// breakdown.
+func (rt *requestTimer) done() Timing {
+	now := time.Now()
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	timing := rt.timing
+	timing.Total = now.Sub(rt.start)
+	if !rt.firstByte.IsZero() {
+		timing.TimeToFirstByte = rt.firstByte.Sub(rt.start)
+		timing.Transfer = now.Sub(rt.firstByte)
+		if !rt.wrote.IsZero() && rt.firstByte.After(rt.wrote) {
+			timing.ServerProcessing = rt.firstByte.Sub(rt.wrote)
+		}
+	}
+	return timing
+}
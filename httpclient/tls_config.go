@@ -0,0 +1,243 @@
+package httpclient
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mgolfam/gogutils/filemanager"
+	"github.com/mgolfam/gogutils/glog"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// TLSConfig describes how the client sets up TLS connections. It is loaded
+// lazily on first use and then reused, so keep one value per configuration
+// and pass it around by pointer.
+type TLSConfig struct {
+	// CAFiles are PEM bundles trusted in addition to the system roots.
+	CAFiles []string
+	// CADir adds every .pem, .crt and .cer file of a directory.
+	CADir string
+	// NoSystemRoots trusts only CAFiles and CADir.
+	NoSystemRoots bool
+
+	// CertFile and KeyFile are a PEM client certificate and its key.
+	CertFile string
+	KeyFile  string
+	// PKCS12File is a client certificate and key bundled as .p12 or .pfx.
+	PKCS12File     string
+	PKCS12Password string
+
+	// MinVersion is "1.0", "1.1", "1.2" or "1.3", "1.2" when empty.
+	MinVersion string
+	// CipherSuites restricts the TLS 1.2 cipher suites, by their Go names
+	// such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+	CipherSuites []string
+	// ServerName overrides the SNI and the name the certificate is
+	// verified against.
+	ServerName string
+	// PinnedSPKI are base64 SHA-256 hashes of the SubjectPublicKeyInfo the
+	// server chain must contain, in the format of curl's --pinnedpubkey
+	// with or without the "sha256//" prefix.
+	PinnedSPKI []string
+	// InsecureSkipVerify disables certificate verification. Pins are still
+	// checked.
+	InsecureSkipVerify bool
+
+	once      sync.Once
+	tlsConfig *tls.Config
+	transport *http.Transport
+	err       error
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Load builds the crypto/tls configuration, reading the certificate files
+// only on the first call.
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	c.load()
+	return c.tlsConfig, c.err
+}
+
+// httpTransport returns the transport shared by all calls using c, so
+// connections are reused across calls.
+func (c *TLSConfig) httpTransport() (*http.Transport, error) {
+	c.load()
+	return c.transport, c.err
+}
+
+func (c *TLSConfig) load() {
+	c.once.Do(func() {
+		c.tlsConfig, c.err = c.build()
+		if c.err == nil {
+			c.transport = http.DefaultTransport.(*http.Transport).Clone()
+			c.transport.TLSClientConfig = c.tlsConfig
+		}
+	})
+}
+
+func (c *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown version %q", c.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if len(c.CipherSuites) > 0 {
+		suites, err := cipherSuiteIDs(c.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = suites
+	}
+
+	if len(c.CAFiles) > 0 || c.CADir != "" || c.NoSystemRoots {
+		pool, err := c.rootPool()
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.PKCS12File != "" {
+		cert, err := c.clientCertificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(c.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(c.PinnedSPKI))
+		for _, pin := range c.PinnedSPKI {
+			pins[strings.TrimPrefix(strings.TrimSpace(pin), "sha256//")] = true
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, cert := range state.PeerCertificates {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if pins[base64.StdEncoding.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+			return errors.New("tls: no certificate of the server matches the pinned public keys")
+		}
+	}
+
+	if c.InsecureSkipVerify {
+		glog.LogL(glog.WARN, "tls: certificate verification is disabled, connections are open to interception")
+	}
+
+	return config, nil
+}
+
+func (c *TLSConfig) rootPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !c.NoSystemRoots {
+		if system, err := x509.SystemCertPool(); err == nil {
+			pool = system
+		}
+	}
+
+	files := append([]string{}, c.CAFiles...)
+	if c.CADir != "" {
+		entries, err := os.ReadDir(c.CADir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".pem", ".crt", ".cer":
+				files = append(files, filepath.Join(c.CADir, entry.Name()))
+			}
+		}
+	}
+
+	for _, file := range files {
+		data, err := filemanager.ReadFileBytes(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("tls: no certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// Certificate loads the client certificate and key of CertFile and KeyFile
+// or PKCS12File, for uses beyond TLS such as signing SOAP messages.
+func (c *TLSConfig) Certificate() (tls.Certificate, error) {
+	return c.clientCertificate()
+}
+
+func (c *TLSConfig) clientCertificate() (tls.Certificate, error) {
+	if c.PKCS12File == "" {
+		keyFile := c.KeyFile
+		if keyFile == "" {
+			// The key may be bundled in the certificate file, as curl allows.
+			keyFile = c.CertFile
+		}
+		return tls.LoadX509KeyPair(c.CertFile, keyFile)
+	}
+
+	data, err := filemanager.ReadFileBytes(c.PKCS12File)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	key, leaf, chain, err := pkcs12.DecodeChain(data, c.PKCS12Password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("tls: %s: %w", c.PKCS12File, err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	for _, ca := range chain {
+		cert.Certificate = append(cert.Certificate, ca.Raw)
+	}
+	return cert, nil
+}
+
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		DNSNames:              []string{name},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	keyDer, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certPath, keyPath
+}
+
+func TestTLSConfigMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, true)
+	serverCert := newTestCert(t, "api.partner.test", ca, false)
+	clientCert := newTestCert(t, "client", ca, false)
+
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	clientCertFile, clientKeyFile := clientCert.writePEM(t, dir, "client")
+	p12, err := pkcs12.Modern.Encode(clientCert.key, clientCert.cert, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p12File := filepath.Join(dir, "client.p12")
+	os.WriteFile(p12File, p12, 0600)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.cert.Raw}, PrivateKey: serverCert.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	serverPin := sha256.Sum256(serverCert.cert.RawSubjectPublicKeyInfo)
+
+	tests := []struct {
+		name      string
+		tlsConfig *TLSConfig
+		expectErr bool
+	}{
+		{name: "PEM client certificate", tlsConfig: &TLSConfig{CAFiles: []string{caFile}, CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "api.partner.test"}},
+		{name: "PKCS12 client certificate", tlsConfig: &TLSConfig{CADir: dir, PKCS12File: p12File, PKCS12Password: "secret", ServerName: "api.partner.test", MinVersion: "1.3"}},
+		{name: "matching pin", tlsConfig: &TLSConfig{CAFiles: []string{caFile}, CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "api.partner.test", PinnedSPKI: []string{"sha256//" + base64.StdEncoding.EncodeToString(serverPin[:])}}},
+		{name: "insecure", tlsConfig: &TLSConfig{InsecureSkipVerify: true, CertFile: clientCertFile, KeyFile: clientKeyFile}},
+		{name: "unknown CA", tlsConfig: &TLSConfig{CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "api.partner.test"}, expectErr: true},
+		{name: "wrong SNI", tlsConfig: &TLSConfig{CAFiles: []string{caFile}, CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "other.test"}, expectErr: true},
+		{name: "no client certificate", tlsConfig: &TLSConfig{CAFiles: []string{caFile}, ServerName: "api.partner.test"}, expectErr: true},
+		{name: "pin mismatch", tlsConfig: &TLSConfig{InsecureSkipVerify: true, CertFile: clientCertFile, KeyFile: clientKeyFile, PinnedSPKI: []string{"AAAA"}}, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := SendRequest(HttpConfig{Method: "GET", URL: server.URL, Timeout: 5 * time.Second, TLS: test.tlsConfig})
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(resp.Body) != "client" {
+				t.Errorf("Expected client certificate CN: client, Got: %s", resp.Body)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommandTLS(t *testing.T) {
+	tests := []struct {
+		name        string
+		curlCommand string
+		expectedTLS *TLSConfig
+	}{
+		{
+			name:        "no TLS flags",
+			curlCommand: `curl https://example.com`,
+			expectedTLS: nil,
+		},
+		{
+			name:        "insecure with CA bundle",
+			curlCommand: `curl -k --cacert /etc/ca.pem --tlsv1.3 https://example.com`,
+			expectedTLS: &TLSConfig{InsecureSkipVerify: true, CAFiles: []string{"/etc/ca.pem"}, MinVersion: "1.3"},
+		},
+		{
+			name:        "PEM client certificate",
+			curlCommand: `curl --cert client.crt --key client.key https://example.com`,
+			expectedTLS: &TLSConfig{CertFile: "client.crt", KeyFile: "client.key"},
+		},
+		{
+			name:        "PKCS12 client certificate with password",
+			curlCommand: `curl --cert client.p12:s3cret --pinnedpubkey 'sha256//abc=;sha256//def=' https://example.com`,
+			expectedTLS: &TLSConfig{PKCS12File: "client.p12", PKCS12Password: "s3cret", PinnedSPKI: []string{"sha256//abc=", "sha256//def="}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := ParseCurlCommand(test.curlCommand)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (conf.TLS == nil) != (test.expectedTLS == nil) {
+				t.Fatalf("Expected TLS: %+v, Got: %+v", test.expectedTLS, conf.TLS)
+			}
+			if conf.TLS == nil {
+				return
+			}
+
+			got, expected := conf.TLS, test.expectedTLS
+			if got.InsecureSkipVerify != expected.InsecureSkipVerify || got.MinVersion != expected.MinVersion ||
+				got.CertFile != expected.CertFile || got.KeyFile != expected.KeyFile ||
+				got.PKCS12File != expected.PKCS12File || got.PKCS12Password != expected.PKCS12Password ||
+				len(got.CAFiles) != len(expected.CAFiles) || len(got.PinnedSPKI) != len(expected.PinnedSPKI) {
+				t.Errorf("Expected TLS: %+v, Got: %+v", expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span as defined by W3C Trace Context.
+type SpanContext struct {
+	TraceID    string
+	SpanID     string
+	Sampled    bool
+	TraceState string
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a context carrying sc. Calls made with that
+// context become children of sc.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanFromContext returns the span carried by ctx.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// ContextFromHeaders continues the trace of an incoming request, it returns
+// ctx unchanged when header has no valid traceparent.
+func ContextFromHeaders(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get("traceparent"))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = header.Get("tracestate")
+	return ContextWithSpan(ctx, sc)
+}
+
+// ParseTraceparent parses a version 00 traceparent header.
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, errors.New("traceparent: invalid format")
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, errors.New("traceparent: invalid format")
+	}
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return SpanContext{}, errors.New("traceparent: invalid trace id")
+	}
+	if !isLowerHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return SpanContext{}, errors.New("traceparent: invalid parent id")
+	}
+	if !isLowerHex(flags, 2) {
+		return SpanContext{}, errors.New("traceparent: invalid flags")
+	}
+
+	flagBits, _ := hex.DecodeString(flags)
+	return SpanContext{TraceID: traceID, SpanID: spanID, Sampled: flagBits[0]&1 == 1}, nil
+}
+
+// Traceparent formats sc as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// Span describes one outbound call.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Method       string
+	URL          string
+	Start        time.Time
+	End          time.Time
+	StatusCode   int
+	Err          error
+}
+
+// SpanHook is told about the start and the end of every traced call. It is
+// called synchronously, so implementations should be quick.
+type SpanHook interface {
+	SpanStart(span *Span)
+	SpanEnd(span *Span)
+}
+
+// JSONSpanExporter is a SpanHook that writes every finished span as one
+// JSON line.
+type JSONSpanExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONSpanExporter(w io.Writer) *JSONSpanExporter {
+	return &JSONSpanExporter{w: w}
+}
+
+type jsonSpan struct {
+	TraceID      string    `json:"trace_id"`
+	SpanID       string    `json:"span_id"`
+	ParentSpanID string    `json:"parent_span_id,omitempty"`
+	Name         string    `json:"name"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	DurationMs   float64   `json:"duration_ms"`
+}
+
+func (e *JSONSpanExporter) SpanStart(span *Span) {}
+
+func (e *JSONSpanExporter) SpanEnd(span *Span) {
+	line := jsonSpan{
+		TraceID:      span.TraceID,
+		SpanID:       span.SpanID,
+		ParentSpanID: span.ParentSpanID,
+		Name:         span.Name,
+		Method:       span.Method,
+		URL:          span.URL,
+		StatusCode:   span.StatusCode,
+		Start:        span.Start,
+		End:          span.End,
+		DurationMs:   float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+	}
+	if span.Err != nil {
+		line.Error = span.Err.Error()
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(data, '\n'))
+}
+
+// startSpan injects the trace context into request and reports the span
+// start. Calls are only traced when the client has a Tracer or the context
+// already carries a span; otherwise startSpan returns nil.
+func (c *Client) startSpan(request *http.Request) *Span {
+	parent, hasParent := SpanFromContext(request.Context())
+	if c.Tracer == nil && !hasParent {
+		return nil
+	}
+
+	span := &Span{
+		SpanID: newTraceID(8),
+		Name:   "HTTP " + request.Method,
+		Method: request.Method,
+		URL:    request.URL.Redacted(),
+		Start:  time.Now(),
+	}
+
+	sc := SpanContext{SpanID: span.SpanID, Sampled: true}
+	if hasParent {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+		sc.Sampled = parent.Sampled
+		sc.TraceState = parent.TraceState
+	} else {
+		span.TraceID = newTraceID(16)
+	}
+	sc.TraceID = span.TraceID
+
+	request.Header.Set("traceparent", sc.Traceparent())
+	if sc.TraceState != "" {
+		request.Header.Set("tracestate", sc.TraceState)
+	}
+
+	if c.Tracer != nil {
+		c.Tracer.SpanStart(span)
+	}
+	return span
+}
+
+// endSpan reports the outcome of a call started with startSpan.
+func (c *Client) endSpan(span *Span, response *http.Response, err error) {
+	if span == nil {
+		return
+	}
+
+	span.End = time.Now()
+	span.Err = err
+	if response != nil {
+		span.StatusCode = response.StatusCode
+	}
+	if c.Tracer != nil {
+		c.Tracer.SpanEnd(span)
+	}
+}
+
+// newTraceID returns n random bytes hex encoded.
+func newTraceID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    SpanContext
+		expectError bool
+	}{
+		{
+			name:     "sampled",
+			value:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected: SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		},
+		{
+			name:     "not sampled",
+			value:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expected: SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expectError: true},
+		{name: "upper case", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", expectError: true},
+		{name: "extra field in version 00", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x", expectError: true},
+		{name: "empty", value: "", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(test.value)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sc != test.expected {
+				t.Errorf("Expected: %+v, Got: %+v", test.expected, sc)
+			}
+			if sc.Traceparent() != test.value {
+				t.Errorf("Expected Traceparent: %s, Got: %s", test.value, sc.Traceparent())
+			}
+		})
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	client := &Client{Tracer: NewJSONSpanExporter(&out)}
+
+	incoming := http.Header{}
+	incoming.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set("tracestate", "vendor=abc")
+	ctx := ContextFromHeaders(context.Background(), incoming)
+
+	if _, err := client.SendRequestContext(ctx, HttpConfig{Method: "POST", URL: server.URL + "/orders", Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sent, err := ParseTraceparent(received.Get("traceparent"))
+	if err != nil {
+		t.Fatalf("Unexpected traceparent %q: %v", received.Get("traceparent"), err)
+	}
+	if sent.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sent.SpanID == "00f067aa0ba902b7" || !sent.Sampled {
+		t.Errorf("Expected a child span of the incoming trace, Got: %+v", sent)
+	}
+	if received.Get("tracestate") != "vendor=abc" {
+		t.Errorf("Expected tracestate: vendor=abc, Got: %s", received.Get("tracestate"))
+	}
+
+	var span map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &span); err != nil {
+		t.Fatalf("Expected one JSON line, Got: %s", out.String())
+	}
+	if span["span_id"] != sent.SpanID || span["parent_span_id"] != "00f067aa0ba902b7" ||
+		span["method"] != "POST" || span["status_code"] != float64(http.StatusAccepted) {
+		t.Errorf("Unexpected span: %s", out.String())
+	}
+
+	// Without tracer or incoming span nothing is injected.
+	received = nil
+	SendRequest(HttpConfig{Method: "GET", URL: server.URL, Timeout: 5 * time.Second})
+	if received.Get("traceparent") != "" {
+		t.Errorf("Expected no traceparent, Got: %s", received.Get("traceparent"))
+	}
+
+	// Failed calls end their span with the error.
+	out.Reset()
+	client.SendRequest(HttpConfig{Method: "GET", URL: "http://127.0.0.1:1/", Timeout: time.Second})
+	if !strings.Contains(out.String(), `"error":`) {
+		t.Errorf("Expected an error in the span, Got: %s", out.String())
+	}
+}
@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+)
+
+// Balance selects how an Upstream picks its endpoint.
+type Balance int
+
+const (
+	// RoundRobin cycles through the endpoints.
+	RoundRobin Balance = iota
+	// Weighted spreads calls in proportion to the endpoint weights.
+	Weighted
+	// Priority sends everything to the healthy endpoints with the lowest
+	// Priority value and fails over to the next level.
+	Priority
+)
+
+// Endpoint is one base URL of an Upstream.
+type Endpoint struct {
+	URL string
+	// Weight is used by Weighted, 1 when 0.
+	Weight int
+	// Priority is used by Priority, lower values are preferred.
+	Priority int
+}
+
+// Upstream is a logical service reachable through several base URLs.
+// Requests carry a path relative to the base URL, such as "/users?page=2".
+//
+// Endpoints failing MaxFailures times in a row are ejected for EjectFor.
+// Idempotent requests fail over to the next endpoint on transport errors
+// and 5xx responses, and are hedged when HedgeAfter is set.
+type Upstream struct {
+	Name    string
+	Balance Balance
+	// MaxFailures defaults to 3 and EjectFor to 30 seconds.
+	MaxFailures int
+	EjectFor    time.Duration
+	// HedgeAfter fires a second attempt at another endpoint when an
+	// idempotent request has not completed after that long. The first
+	// successful response wins and the other attempt is canceled.
+	HedgeAfter time.Duration
+	// Client sends the requests, DefaultClient when nil.
+	Client *Client
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+type endpoint struct {
+	Endpoint
+	current      int
+	failures     int
+	ejectedUntil time.Time
+}
+
+// ErrNoEndpoints is returned by an Upstream without endpoints.
+var ErrNoEndpoints = errors.New("httpclient: upstream has no endpoints")
+
+func NewUpstream(name string, balance Balance, endpoints ...Endpoint) *Upstream {
+	u := &Upstream{Name: name, Balance: balance}
+	for _, e := range endpoints {
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+		u.endpoints = append(u.endpoints, &endpoint{Endpoint: e})
+	}
+	return u
+}
+
+func (u *Upstream) SendRequest(config HttpConfig) (*HttpResponse, error) {
+	return u.SendRequestContext(context.Background(), config)
+}
+
+// SendRequestContext sends config, whose URL is relative to the endpoints'
+// base URLs.
+func (u *Upstream) SendRequestContext(ctx context.Context, config HttpConfig) (*HttpResponse, error) {
+	if len(u.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if !isIdempotent(config.Method) {
+		e := u.pick(nil)
+		return u.attempt(ctx, e, config)
+	}
+	if u.HedgeAfter > 0 && len(u.endpoints) > 1 {
+		return u.hedge(ctx, config)
+	}
+
+	var (
+		resp *HttpResponse
+		err  error
+	)
+	tried := map[*endpoint]bool{}
+	for e := u.pick(tried); e != nil; e = u.pick(tried) {
+		tried[e] = true
+		resp, err = u.attempt(ctx, e, config)
+		if !failed(resp, err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return resp, err
+}
+
+type upstreamResult struct {
+	resp *HttpResponse
+	err  error
+}
+
+func (u *Upstream) hedge(ctx context.Context, config HttpConfig) (*HttpResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan upstreamResult, 2)
+	tried := map[*endpoint]bool{}
+	launch := func() bool {
+		e := u.pick(tried)
+		if e == nil {
+			return false
+		}
+		tried[e] = true
+		go func() {
+			resp, err := u.attempt(ctx, e, config)
+			results <- upstreamResult{resp, err}
+		}()
+		return true
+	}
+
+	launch()
+	pending := 1
+	hedged := false
+	timer := time.NewTimer(u.HedgeAfter)
+	defer timer.Stop()
+
+	var last upstreamResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !hedged && launch() {
+				glog.LogL(glog.DEBUG, "upstream", u.Name, "hedging", config.Method, config.URL)
+				pending++
+			}
+			hedged = true
+		case r := <-results:
+			pending--
+			if !failed(r.resp, r.err) {
+				return r.resp, r.err
+			}
+			last = r
+			// Fail over right away instead of waiting for the hedge.
+			if !hedged && ctx.Err() == nil {
+				hedged = true
+				if launch() {
+					pending++
+				}
+			}
+		}
+	}
+	return last.resp, last.err
+}
+
+// attempt sends config to e and feeds the outcome to passive ejection.
+func (u *Upstream) attempt(ctx context.Context, e *endpoint, config HttpConfig) (*HttpResponse, error) {
+	config.URL = joinURL(e.URL, config.URL)
+
+	client := u.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.SendRequestContext(ctx, config)
+	if ctx.Err() == nil {
+		u.report(e, failed(resp, err))
+	}
+	return resp, err
+}
+
+// pick returns the endpoint for the next call, skipping the ones in tried.
+// Ejected endpoints are only picked when no other is left, the one coming
+// back soonest first.
+func (u *Upstream) pick(tried map[*endpoint]bool) *endpoint {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	var healthy []*endpoint
+	var fallback *endpoint
+	for _, e := range u.endpoints {
+		if tried[e] {
+			continue
+		}
+		if now.Before(e.ejectedUntil) {
+			if fallback == nil || e.ejectedUntil.Before(fallback.ejectedUntil) {
+				fallback = e
+			}
+			continue
+		}
+		healthy = append(healthy, e)
+	}
+	if len(healthy) == 0 {
+		return fallback
+	}
+
+	switch u.Balance {
+	case Weighted:
+		// Smooth weighted round robin as done by nginx.
+		total := 0
+		var best *endpoint
+		for _, e := range healthy {
+			e.current += e.Weight
+			total += e.Weight
+			if best == nil || e.current > best.current {
+				best = e
+			}
+		}
+		best.current -= total
+		return best
+	case Priority:
+		top := healthy[:0:0]
+		for _, e := range healthy {
+			if len(top) == 0 || e.Priority < top[0].Priority {
+				top = []*endpoint{e}
+			} else if e.Priority == top[0].Priority {
+				top = append(top, e)
+			}
+		}
+		healthy = top
+	}
+
+	e := healthy[u.next%len(healthy)]
+	u.next++
+	return e
+}
+
+func (u *Upstream) report(e *endpoint, failure bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !failure {
+		e.failures = 0
+		return
+	}
+
+	maxFailures := u.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 3
+	}
+	ejectFor := u.EjectFor
+	if ejectFor <= 0 {
+		ejectFor = 30 * time.Second
+	}
+
+	e.failures++
+	if e.failures >= maxFailures {
+		e.failures = 0
+		e.ejectedUntil = time.Now().Add(ejectFor)
+		glog.LogL(glog.WARN, "upstream", u.Name, "ejected", e.URL, "for", ejectFor)
+	}
+}
+
+func failed(resp *HttpResponse, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= 500
+}
+
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// joinURL appends the relative path to base with exactly one slash between.
+func joinURL(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if strings.HasPrefix(path, "?") {
+		return base + path
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,116 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newNamedServer(name string, status int, delay time.Duration, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(name + " " + r.URL.RequestURI()))
+	}))
+}
+
+func TestUpstreamBalance(t *testing.T) {
+	var callsA, callsB int32
+	a := newNamedServer("a", http.StatusOK, 0, &callsA)
+	defer a.Close()
+	b := newNamedServer("b", http.StatusOK, 0, &callsB)
+	defer b.Close()
+
+	tests := []struct {
+		name      string
+		balance   Balance
+		endpoints []Endpoint
+		expectedA int32
+		expectedB int32
+	}{
+		{name: "round robin", balance: RoundRobin, endpoints: []Endpoint{{URL: a.URL}, {URL: b.URL}}, expectedA: 4, expectedB: 4},
+		{name: "weighted", balance: Weighted, endpoints: []Endpoint{{URL: a.URL, Weight: 3}, {URL: b.URL}}, expectedA: 6, expectedB: 2},
+		{name: "priority", balance: Priority, endpoints: []Endpoint{{URL: a.URL, Priority: 1}, {URL: b.URL}}, expectedA: 0, expectedB: 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&callsA, 0)
+			atomic.StoreInt32(&callsB, 0)
+			upstream := NewUpstream("api", test.balance, test.endpoints...)
+			for i := 0; i < 8; i++ {
+				if _, err := upstream.SendRequest(HttpConfig{Method: "GET", URL: "/users"}); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if callsA != test.expectedA || callsB != test.expectedB {
+				t.Errorf("Expected calls: %d/%d, Got: %d/%d", test.expectedA, test.expectedB, callsA, callsB)
+			}
+		})
+	}
+}
+
+func TestUpstreamFailoverAndEjection(t *testing.T) {
+	var callsPrimary, callsSecondary int32
+	primary := newNamedServer("primary", http.StatusServiceUnavailable, 0, &callsPrimary)
+	defer primary.Close()
+	secondary := newNamedServer("secondary", http.StatusOK, 0, &callsSecondary)
+	defer secondary.Close()
+
+	upstream := NewUpstream("api", Priority, Endpoint{URL: primary.URL + "/v1/"}, Endpoint{URL: secondary.URL + "/v1", Priority: 1})
+	upstream.MaxFailures = 2
+
+	for i := 0; i < 4; i++ {
+		resp, err := upstream.SendRequest(HttpConfig{Method: "GET", URL: "/users?id=7"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(resp.Body) != "secondary /v1/users?id=7" {
+			t.Errorf("Expected the secondary to answer, Got: %s", resp.Body)
+		}
+	}
+	if callsPrimary != 2 {
+		t.Errorf("Expected the primary to be ejected after 2 failures, Got: %d calls", callsPrimary)
+	}
+
+	// Non idempotent requests are not retried elsewhere.
+	atomic.StoreInt32(&callsSecondary, 0)
+	upstream.endpoints[0].ejectedUntil = time.Time{}
+	resp, _ := upstream.SendRequest(HttpConfig{Method: "POST", URL: "/users"})
+	if resp.StatusCode != http.StatusServiceUnavailable || callsSecondary != 0 {
+		t.Errorf("Expected the POST to fail without failover, Got: %d, %d secondary calls", resp.StatusCode, callsSecondary)
+	}
+}
+
+func TestUpstreamHedging(t *testing.T) {
+	var callsSlow, callsFast int32
+	slow := newNamedServer("slow", http.StatusOK, 2*time.Second, &callsSlow)
+	defer slow.Close()
+	fast := newNamedServer("fast", http.StatusOK, 0, &callsFast)
+	defer fast.Close()
+
+	upstream := NewUpstream("api", Priority, Endpoint{URL: slow.URL}, Endpoint{URL: fast.URL, Priority: 1})
+	upstream.HedgeAfter = 50 * time.Millisecond
+
+	start := time.Now()
+	resp, err := upstream.SendRequest(HttpConfig{Method: "GET", URL: "/"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(resp.Body) != "fast /" {
+		t.Errorf("Expected the hedged attempt to win, Got: %s", resp.Body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the hedge to cut the latency, took: %s", elapsed)
+	}
+	if upstream.endpoints[0].failures != 0 {
+		t.Errorf("Expected the canceled attempt not to count as a failure")
+	}
+}
@@ -0,0 +1,435 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketConfig contains the configuration of a WebSocket connection.
+type WebSocketConfig struct {
+	// URL is a ws:// or wss:// address.
+	URL string
+	// Origin is sent with the handshake, the http(s) address of the host
+	// by default.
+	Origin    string
+	Protocols []string
+	Headers   map[string]string
+	Auth      AuthProvider
+	TLS       *TLSConfig
+	// Timeout bounds the dial and handshake of reconnections, 30 seconds
+	// by default. The first connection is bounded by the context of
+	// DialWebSocket.
+	Timeout time.Duration
+
+	// PingInterval is the time between pings, 30 seconds by default and
+	// negative to disable them. A connection that has not read anything,
+	// pongs included, for PingInterval plus PongTimeout is dropped. Pongs
+	// are read by Receive, so keep a receiver running while pinging.
+	PingInterval time.Duration
+	// PongTimeout is 10 seconds by default.
+	PongTimeout time.Duration
+	// MaxMessageSize limits the size of a received frame, 1 MiB by
+	// default. Larger frames are skipped with websocket.ErrFrameTooLarge.
+	MaxMessageSize int
+
+	// Reconnect redials dropped connections, waiting MinBackoff (500ms by
+	// default) after the first failed attempt and doubling up to
+	// MaxBackoff (30 seconds by default).
+	Reconnect  bool
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries gives up after this many failed dials in a row, 0 retries
+	// forever.
+	MaxRetries int
+}
+
+// ErrWebSocketClosed is returned by the calls on a closed WebSocket.
+var ErrWebSocketClosed = errors.New("httpclient: websocket is closed")
+
+// WebSocket is a client connection, safe for one receiver and any number
+// of concurrent senders.
+type WebSocket struct {
+	client *Client
+	config WebSocketConfig
+
+	// ctx is canceled by Close, to stop reconnections.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	stop chan struct{}
+}
+
+// DialWebSocket connects to config.URL with DefaultClient, see
+// Client.DialWebSocket.
+func DialWebSocket(ctx context.Context, config WebSocketConfig) (*WebSocket, error) {
+	return DefaultClient.DialWebSocket(ctx, config)
+}
+
+// DialWebSocket connects to config.URL. The handshake carries the config
+// headers, the cookies of the client's jar and the authorization of
+// config.Auth or the client's provider.
+func (c *Client) DialWebSocket(ctx context.Context, config WebSocketConfig) (*WebSocket, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.PingInterval == 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.PongTimeout <= 0 {
+		config.PongTimeout = 10 * time.Second
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = 1 << 20
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+
+	ws := &WebSocket{client: c, config: config}
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+	conn, activity, err := ws.dial(ctx)
+	if err != nil {
+		ws.cancel()
+		return nil, err
+	}
+	ws.use(conn, activity)
+	return ws, nil
+}
+
+// Send sends v as a JSON text message.
+func (ws *WebSocket) Send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.SendMessage(data, false)
+}
+
+// SendMessage sends data as a text message, or a binary one. A failed send
+// drops the connection, so it is redialed when Reconnect is set; the
+// message is not sent again.
+func (ws *WebSocket) SendMessage(data []byte, binary bool) error {
+	conn, err := ws.current()
+	if err != nil {
+		return err
+	}
+	payloadType := byte(websocket.TextFrame)
+	if binary {
+		payloadType = websocket.BinaryFrame
+	}
+	err = frameCodec.Send(conn, frame{payloadType: payloadType, data: data})
+	if err != nil {
+		ws.drop(conn)
+	}
+	return err
+}
+
+// Receive decodes the next JSON message into v, see ReceiveMessage.
+func (ws *WebSocket) Receive(v interface{}) error {
+	data, err := ws.ReceiveMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ReceiveMessage returns the next text or binary message. When Reconnect is
+// set a dropped or closed connection is redialed and the message is read
+// from the new one; messages sent in between are lost.
+func (ws *WebSocket) ReceiveMessage() ([]byte, error) {
+	for {
+		conn, err := ws.current()
+		if err != nil {
+			return nil, err
+		}
+		var message frame
+		err = frameCodec.Receive(conn, &message)
+		if err == nil || err == websocket.ErrFrameTooLarge {
+			return message.data, err
+		}
+		if ws.ctx.Err() != nil {
+			return nil, ErrWebSocketClosed
+		}
+		if !ws.config.Reconnect {
+			ws.drop(conn)
+			return nil, err
+		}
+		glog.LogL(glog.INFO, "ws dropped", ws.config.URL, err)
+		if err := ws.reconnect(conn); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close sends a normal closure to the server and closes the connection.
+// It stops the reconnections, and the calls waiting on them return
+// ErrWebSocketClosed.
+func (ws *WebSocket) Close() error {
+	if ws.ctx.Err() != nil {
+		return nil
+	}
+	ws.cancel()
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn == nil {
+		return nil
+	}
+	close(ws.stop)
+	ws.conn.SetWriteDeadline(time.Now().Add(ws.config.PongTimeout))
+	err := ws.conn.Close()
+	ws.conn = nil
+	return err
+}
+
+// current returns the open connection, redialing a dropped one when
+// Reconnect is set.
+func (ws *WebSocket) current() (*websocket.Conn, error) {
+	for {
+		ws.mu.Lock()
+		conn := ws.conn
+		ws.mu.Unlock()
+		switch {
+		case ws.ctx.Err() != nil:
+			return nil, ErrWebSocketClosed
+		case conn != nil:
+			return conn, nil
+		case !ws.config.Reconnect:
+			return nil, net.ErrClosed
+		}
+		if err := ws.reconnect(nil); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// use makes conn the open connection, ws.mu must be held or not needed.
+func (ws *WebSocket) use(conn *websocket.Conn, activity *activityConn) {
+	ws.conn = conn
+	ws.stop = make(chan struct{})
+	if ws.config.PingInterval > 0 {
+		go ws.keepalive(conn, activity, ws.stop)
+	}
+}
+
+// drop closes conn unless it was replaced already.
+func (ws *WebSocket) drop(failed *websocket.Conn) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn == failed {
+		close(ws.stop)
+		failed.Close()
+		ws.conn = nil
+	}
+}
+
+// reconnect replaces the failed connection, unless a sender replaced it
+// already.
+func (ws *WebSocket) reconnect(failed *websocket.Conn) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn != nil && ws.conn != failed {
+		return nil
+	}
+	if ws.conn != nil {
+		close(ws.stop)
+		ws.conn.Close()
+		ws.conn = nil
+	}
+
+	backoff := ws.config.MinBackoff
+	for failures := 1; ; failures++ {
+		ctx, cancel := context.WithTimeout(ws.ctx, ws.config.Timeout)
+		conn, activity, err := ws.dial(ctx)
+		cancel()
+		if err == nil {
+			ws.use(conn, activity)
+			return nil
+		}
+		if ws.ctx.Err() != nil {
+			return ErrWebSocketClosed
+		}
+		if ws.config.MaxRetries > 0 && failures >= ws.config.MaxRetries {
+			return err
+		}
+		glog.LogL(glog.INFO, "ws reconnect in", backoff, ws.config.URL, err)
+		select {
+		case <-ws.ctx.Done():
+			return ErrWebSocketClosed
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > ws.config.MaxBackoff {
+			backoff = ws.config.MaxBackoff
+		}
+	}
+}
+
+// keepalive pings the server and drops conn when nothing was read for
+// too long.
+func (ws *WebSocket) keepalive(conn *websocket.Conn, activity *activityConn, stop chan struct{}) {
+	ticker := time.NewTicker(ws.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if time.Since(activity.lastRead()) > ws.config.PingInterval+ws.config.PongTimeout {
+			glog.LogL(glog.INFO, "ws pong timeout", ws.config.URL)
+			activity.Close()
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(ws.config.PongTimeout))
+		if err := frameCodec.Send(conn, frame{payloadType: websocket.PingFrame}); err != nil {
+			activity.Close()
+			return
+		}
+		conn.SetWriteDeadline(time.Time{})
+	}
+}
+
+// dial opens a connection, canceling the handshake with ctx.
+func (ws *WebSocket) dial(ctx context.Context) (*websocket.Conn, *activityConn, error) {
+	location, err := url.Parse(ws.config.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpURL := *location
+	switch location.Scheme {
+	case "ws":
+		httpURL.Scheme = "http"
+	case "wss":
+		httpURL.Scheme = "https"
+	default:
+		return nil, nil, websocket.ErrBadScheme
+	}
+
+	origin := ws.config.Origin
+	if origin == "" {
+		origin = httpURL.Scheme + "://" + location.Host
+	}
+	config, err := websocket.NewConfig(ws.config.URL, origin)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.Protocol = ws.config.Protocols
+
+	// The handshake headers are built on a request, for the providers.
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpURL.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, value := range ws.config.Headers {
+		request.Header.Set(key, value)
+	}
+	if ws.client.Jar != nil {
+		for _, cookie := range ws.client.Jar.Cookies(&httpURL) {
+			request.AddCookie(cookie)
+		}
+	}
+	if auth := ws.client.authFor(ws.config.Auth); auth != nil {
+		if err := auth.Authorize(request); err != nil {
+			return nil, nil, err
+		}
+	}
+	config.Header = request.Header
+
+	tlsConfig := ws.config.TLS
+	if tlsConfig == nil {
+		tlsConfig = ws.client.TLS
+	}
+	dialer := &net.Dialer{}
+	var rwc net.Conn
+	address := location.Host
+	if location.Port() == "" {
+		address = net.JoinHostPort(location.Hostname(), map[string]string{"ws": "80", "wss": "443"}[location.Scheme])
+	}
+	if location.Scheme == "wss" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer}
+		if tlsConfig != nil {
+			if tlsDialer.Config, err = tlsConfig.Load(); err != nil {
+				return nil, nil, err
+			}
+		}
+		rwc, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		rwc, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	glog.LogL(glog.INFO, "ws ->", ws.config.URL)
+	activity := &activityConn{Conn: rwc}
+	activity.touch()
+	stop := context.AfterFunc(ctx, func() { rwc.Close() })
+	conn, err := websocket.NewClient(config, activity)
+	if !stop() {
+		if err == nil {
+			conn.Close()
+		}
+		return nil, nil, ctx.Err()
+	}
+	if err != nil {
+		rwc.Close()
+		return nil, nil, err
+	}
+	conn.MaxPayloadBytes = ws.config.MaxMessageSize
+	return conn, activity, nil
+}
+
+// activityConn records the time of the last read, for the keepalive.
+type activityConn struct {
+	net.Conn
+	last atomic.Int64
+}
+
+func (c *activityConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.touch()
+	}
+	return n, err
+}
+
+func (c *activityConn) touch() {
+	c.last.Store(time.Now().UnixNano())
+}
+
+func (c *activityConn) lastRead() time.Time {
+	return time.Unix(0, c.last.Load())
+}
+
+// frame is a message with its payload type.
+type frame struct {
+	payloadType byte
+	data        []byte
+}
+
+var frameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		f := v.(frame)
+		return f.data, f.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		*v.(*frame) = frame{payloadType: payloadType, data: data}
+		return nil
+	},
+}
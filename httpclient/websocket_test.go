@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+type wsMessage struct {
+	Seq  int    `json:"seq"`
+	Text string `json:"text"`
+}
+
+func webSocketURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		request := conn.Request()
+		if request.Header.Get("Authorization") != "Bearer t0k" || request.Header.Get("X-Client") != "test" {
+			t.Errorf("Expected the handshake headers and auth, Got: %v", request.Header)
+		}
+		for {
+			var data []byte
+			if err := websocket.Message.Receive(conn, &data); err != nil {
+				closed <- err
+				return
+			}
+			websocket.Message.Send(conn, string(data))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{Auth: BearerToken{Token: "t0k"}}
+	ws, err := client.DialWebSocket(context.Background(), WebSocketConfig{
+		URL:          webSocketURL(server),
+		Headers:      map[string]string{"X-Client": "test"},
+		PingInterval: 10 * time.Millisecond,
+		PongTimeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := ws.Send(wsMessage{Seq: i, Text: "hello"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var got wsMessage
+		if err := ws.Receive(&got); err != nil || got.Seq != i || got.Text != "hello" {
+			t.Errorf("Expected message %d, Got: %+v, %v", i, got, err)
+		}
+		// Pings keep going, answered pongs keep the connection.
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := <-closed; err != io.EOF {
+		t.Errorf("Expected the server to see a close, Got: %v", err)
+	}
+	if err := ws.Send(wsMessage{}); err != ErrWebSocketClosed {
+		t.Errorf("Expected ErrWebSocketClosed, Got: %v", err)
+	}
+}
+
+func TestWebSocketMessageLimit(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		websocket.Message.Send(conn, strings.Repeat("x", 2048))
+		websocket.Message.Send(conn, "small")
+		io.Copy(io.Discard, conn)
+	}))
+	defer server.Close()
+
+	ws, err := DialWebSocket(context.Background(), WebSocketConfig{URL: webSocketURL(server), MaxMessageSize: 1024, PingInterval: -1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer ws.Close()
+
+	if _, err := ws.ReceiveMessage(); err != websocket.ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, Got: %v", err)
+	}
+	if data, err := ws.ReceiveMessage(); err != nil || string(data) != "small" {
+		t.Errorf("Expected small, Got: %q, %v", data, err)
+	}
+}
+
+func TestWebSocketReconnect(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		switch n {
+		case 1:
+			// Closes right away.
+			websocket.JSON.Send(conn, wsMessage{Seq: 1})
+		case 2:
+			// Never reads, so pings go unanswered.
+			time.Sleep(time.Second)
+		default:
+			websocket.JSON.Send(conn, wsMessage{Seq: int(n)})
+			io.Copy(io.Discard, conn)
+		}
+	}))
+	defer server.Close()
+
+	ws, err := DialWebSocket(context.Background(), WebSocketConfig{
+		URL:          webSocketURL(server),
+		Reconnect:    true,
+		MinBackoff:   time.Millisecond,
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got wsMessage
+	for _, expected := range []int{1, 3} {
+		if err := ws.Receive(&got); err != nil || got.Seq != expected {
+			t.Errorf("Expected message %d, Got: %+v, %v", expected, got, err)
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 3 {
+		t.Errorf("Expected 3 connections, Got: %d", n)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ws.Close()
+	}()
+	if err := ws.Receive(&got); err != ErrWebSocketClosed {
+		t.Errorf("Expected ErrWebSocketClosed, Got: %v", err)
+	}
+}
+
+func TestWebSocketDialErrors(t *testing.T) {
+	if _, err := DialWebSocket(context.Background(), WebSocketConfig{URL: "http://localhost"}); err != websocket.ErrBadScheme {
+		t.Errorf("Expected ErrBadScheme, Got: %v", err)
+	}
+
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {}))
+	server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := DialWebSocket(ctx, WebSocketConfig{URL: webSocketURL(server)})
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a dial error, Got: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+// HTTPTransport posts every message to URL.
+type HTTPTransport struct {
+	URL string
+	// Client sends the requests, httpclient.DefaultClient when nil.
+	Client  *httpclient.Client
+	Headers map[string]string
+	Timeout time.Duration
+	Auth    httpclient.AuthProvider
+	TLS     *httpclient.TLSConfig
+}
+
+// NewHTTPClient returns a client posting to url, with a 30 seconds
+// timeout.
+func NewHTTPClient(url string) *Client {
+	return NewClient(&HTTPTransport{URL: url, Timeout: 30 * time.Second})
+}
+
+// RoundTrip posts message. A non 2xx status is returned as a
+// *httpclient.StatusError unless its body is a JSON-RPC response, as some
+// servers send errors with 4xx and 5xx statuses.
+func (t *HTTPTransport) RoundTrip(ctx context.Context, message []byte, wait bool) ([]byte, error) {
+	config := httpclient.HttpConfig{
+		Method:  http.MethodPost,
+		URL:     t.URL,
+		Headers: map[string]string{"Content-Type": "application/json", "Accept": "application/json"},
+		Body:    message,
+		Timeout: t.Timeout,
+		Auth:    t.Auth,
+		TLS:     t.TLS,
+	}
+	for key, value := range t.Headers {
+		config.Headers[key] = value
+	}
+
+	client := t.Client
+	if client == nil {
+		client = httpclient.DefaultClient
+	}
+	resp, err := client.SendRequestContext(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.TrimSpace(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(body) == 0 || (body[0] != '{' && body[0] != '[') {
+			return nil, &httpclient.StatusError{Method: http.MethodPost, URL: t.URL, StatusCode: resp.StatusCode, Body: resp.Body}
+		}
+	}
+	if !wait {
+		return nil, nil
+	}
+	return body, nil
+}
+
+// Close does nothing, HTTP connections are pooled by net/http.
+func (t *HTTPTransport) Close() error {
+	return nil
+}
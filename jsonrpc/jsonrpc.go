@@ -0,0 +1,265 @@
+// Package jsonrpc is a JSON-RPC 2.0 client over HTTP, through httpclient,
+// and over TCP or Unix sockets with newline delimited messages.
+//
+//	client := jsonrpc.NewHTTPClient("http://localhost:8080/rpc")
+//	var sum int
+//	err := client.Call(ctx, "add", []int{1, 2}, &sum)
+//
+//	client, err := jsonrpc.Dial(ctx, "unix:///run/calc.sock")
+//	defer client.Close()
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// The error codes of the specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is the error object of a response.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("jsonrpc: %d %s: %s", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("jsonrpc: %d %s", e.Code, e.Message)
+}
+
+// DataAs decodes the data of the error into v.
+func (e *Error) DataAs(v interface{}) error {
+	if len(e.Data) == 0 {
+		return errors.New("jsonrpc: error has no data")
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// Transport carries messages, a request or a batch, to the server.
+type Transport interface {
+	// RoundTrip sends message and returns the response message, nil when
+	// wait is false because the message holds notifications only.
+	RoundTrip(ctx context.Context, message []byte, wait bool) ([]byte, error)
+	Close() error
+}
+
+// Client sends calls, notifications and batches through a Transport. It
+// is safe for concurrent use.
+type Client struct {
+	transport Transport
+	lastID    atomic.Int64
+}
+
+// NewClient returns a client sending through transport.
+func NewClient(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+// Close closes the transport.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      *int64          `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// newRequest builds a request, a call with a new ID or a notification.
+func (c *Client) newRequest(method string, params interface{}, call bool) (request, error) {
+	r := request{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return r, fmt.Errorf("jsonrpc: %s params: %w", method, err)
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || (trimmed[0] != '[' && trimmed[0] != '{' && string(trimmed) != "null") {
+			return r, fmt.Errorf("jsonrpc: %s params must be an array or an object", method)
+		}
+		if string(data) != "null" {
+			r.Params = data
+		}
+	}
+	if call {
+		id := c.lastID.Add(1)
+		r.ID = &id
+	}
+	return r, nil
+}
+
+// Call calls method and decodes the result into result, which may be nil.
+// params is marshaled to an array or an object, nil sends none. A failed
+// call returns an *Error.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	r, err := c.newRequest(method, params, true)
+	if err != nil {
+		return err
+	}
+	message, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data, err := c.transport.RoundTrip(ctx, message, true)
+	if err != nil {
+		return err
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("jsonrpc: %s: decoding response: %w", method, err)
+	}
+	return resp.decode(result)
+}
+
+// Notify sends a notification, which gets no response.
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	r, err := c.newRequest(method, params, false)
+	if err != nil {
+		return err
+	}
+	message, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = c.transport.RoundTrip(ctx, message, false)
+	return err
+}
+
+func (r *response) decode(result interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("jsonrpc: decoding result: %w", err)
+	}
+	return nil
+}
+
+// BatchCall is a call of a batch. Error is set by Batch.Send.
+type BatchCall struct {
+	Method string
+	Result interface{}
+	Error  error
+	id     int64
+}
+
+// Batch collects calls and notifications sent in one message.
+type Batch struct {
+	client   *Client
+	requests []request
+	calls    []*BatchCall
+	err      error
+}
+
+// Batch starts an empty batch.
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// Call adds a call whose result is decoded into result.
+func (b *Batch) Call(method string, params, result interface{}) *BatchCall {
+	call := &BatchCall{Method: method, Result: result}
+	r, err := b.client.newRequest(method, params, true)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		call.Error = err
+		return call
+	}
+	call.id = *r.ID
+	b.requests = append(b.requests, r)
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// Notify adds a notification.
+func (b *Batch) Notify(method string, params interface{}) {
+	r, err := b.client.newRequest(method, params, false)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return
+	}
+	b.requests = append(b.requests, r)
+}
+
+// Send sends the batch and sets the result or the Error of every call,
+// matching the responses by ID. It returns an error when the batch could
+// not be sent or its response not be read; the errors of single calls are
+// left in their BatchCall.
+func (b *Batch) Send(ctx context.Context) error {
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.requests) == 0 {
+		return errors.New("jsonrpc: empty batch")
+	}
+	message, err := json.Marshal(b.requests)
+	if err != nil {
+		return err
+	}
+	data, err := b.client.transport.RoundTrip(ctx, message, len(b.calls) > 0)
+	if err != nil || len(b.calls) == 0 {
+		return err
+	}
+
+	var responses []response
+	if err := json.Unmarshal(data, &responses); err != nil {
+		// A server that rejects the whole batch answers with one error.
+		var single response
+		if json.Unmarshal(data, &single) == nil && single.Error != nil {
+			return single.Error
+		}
+		return fmt.Errorf("jsonrpc: decoding batch response: %w", err)
+	}
+	byID := map[string]*response{}
+	for i := range responses {
+		byID[idKey(responses[i].ID)] = &responses[i]
+	}
+	for _, call := range b.calls {
+		resp, ok := byID[strconv.FormatInt(call.id, 10)]
+		if !ok {
+			call.Error = fmt.Errorf("jsonrpc: %s: no response", call.Method)
+			continue
+		}
+		call.Error = resp.decode(call.Result)
+	}
+	return nil
+}
+
+// idKey is the key of an ID, numbers and strings holding them alike.
+func idKey(id json.RawMessage) string {
+	var s string
+	if json.Unmarshal(id, &s) == nil {
+		return s
+	}
+	return string(bytes.TrimSpace(id))
+}
@@ -0,0 +1,290 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+type testRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// calculator answers one request, nil for notifications.
+type calculator struct {
+	notified int32
+}
+
+func (c *calculator) handle(r testRequest) interface{} {
+	result := map[string]interface{}{"jsonrpc": "2.0", "id": r.ID}
+	switch r.Method {
+	case "add", "slow_add":
+		var args []int
+		if err := json.Unmarshal(r.Params, &args); err != nil {
+			result["error"] = map[string]interface{}{"code": CodeInvalidParams, "message": "Invalid params"}
+			break
+		}
+		if r.Method == "slow_add" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		sum := 0
+		for _, arg := range args {
+			sum += arg
+		}
+		result["result"] = sum
+	case "greet":
+		var args struct{ Name string }
+		json.Unmarshal(r.Params, &args)
+		result["result"] = "hello " + args.Name
+	case "log":
+		atomic.AddInt32(&c.notified, 1)
+	case "fail":
+		result["error"] = map[string]interface{}{"code": 42, "message": "failed", "data": map[string]string{"reason": "testing"}}
+	default:
+		result["error"] = map[string]interface{}{"code": CodeMethodNotFound, "message": "Method not found"}
+	}
+	if r.ID == nil {
+		return nil
+	}
+	return result
+}
+
+// serve answers a request or a batch message, nil when nothing is due.
+func (c *calculator) serve(message []byte) []byte {
+	if len(message) > 0 && message[0] == '[' {
+		var batch []testRequest
+		json.Unmarshal(message, &batch)
+		var responses []interface{}
+		for i := len(batch) - 1; i >= 0; i-- {
+			if response := c.handle(batch[i]); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		data, _ := json.Marshal(responses)
+		return data
+	}
+	var r testRequest
+	json.Unmarshal(message, &r)
+	if response := c.handle(r); response != nil {
+		data, _ := json.Marshal(response)
+		return data
+	}
+	return nil
+}
+
+func (c *calculator) listen(t *testing.T, network, address string) net.Listener {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var writeMu sync.Mutex
+				decoder := json.NewDecoder(conn)
+				for {
+					var message json.RawMessage
+					if err := decoder.Decode(&message); err != nil {
+						return
+					}
+					go func() {
+						if response := c.serve(message); response != nil {
+							writeMu.Lock()
+							conn.Write(append(response, '\n'))
+							writeMu.Unlock()
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func testClient(t *testing.T, client *Client, calc *calculator) {
+	ctx := context.Background()
+	var sum int
+	if err := client.Call(ctx, "add", []int{1, 2, 3}, &sum); err != nil || sum != 6 {
+		t.Errorf("Expected 6, Got: %d, %v", sum, err)
+	}
+	var greeting string
+	if err := client.Call(ctx, "greet", map[string]string{"name": "Ada"}, &greeting); err != nil || greeting != "hello Ada" {
+		t.Errorf("Expected hello Ada, Got: %q, %v", greeting, err)
+	}
+
+	var rpcErr *Error
+	err := client.Call(ctx, "fail", nil, nil)
+	var data struct{ Reason string }
+	if !errors.As(err, &rpcErr) || rpcErr.Code != 42 || rpcErr.DataAs(&data) != nil || data.Reason != "testing" {
+		t.Errorf("Expected error 42 with data, Got: %v", err)
+	}
+	if err := client.Call(ctx, "add", 7, nil); err == nil {
+		t.Errorf("Expected an error for scalar params")
+	}
+
+	if err := client.Notify(ctx, "log", []string{"x"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	batch := client.Batch()
+	first := batch.Call("add", []int{1, 1}, new(int))
+	missing := batch.Call("nope", nil, nil)
+	batch.Notify("log", nil)
+	second := batch.Call("greet", map[string]string{"name": "Bob"}, new(string))
+	if err := batch.Send(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Error != nil || *first.Result.(*int) != 2 || second.Error != nil || *second.Result.(*string) != "hello Bob" {
+		t.Errorf("Expected the batch results matched by ID, Got: %v %v, %v %v", first.Error, first.Result, second.Error, second.Result)
+	}
+	if !errors.As(missing.Error, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Expected method not found, Got: %v", missing.Error)
+	}
+
+	notifications := client.Batch()
+	notifications.Notify("log", nil)
+	if err := notifications.Send(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&calc.notified) != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&calc.notified); n != 3 {
+		t.Errorf("Expected 3 notifications, Got: %d", n)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	calc := &calculator{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON request, Got: %v", r.Header)
+		}
+		message, _ := io.ReadAll(r.Body)
+		response := calc.serve(message)
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	testClient(t, NewHTTPClient(server.URL), calc)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	var statusErr *httpclient.StatusError
+	if err := NewHTTPClient(down.URL).Call(context.Background(), "add", nil, nil); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a 503 StatusError, Got: %v", err)
+	}
+}
+
+func TestStreamClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address func(t *testing.T) string
+	}{
+		{name: "tcp", network: "tcp", address: func(*testing.T) string { return "127.0.0.1:0" }},
+		{name: "unix", network: "unix", address: func(t *testing.T) string { return filepath.Join(t.TempDir(), "rpc.sock") }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := &calculator{}
+			listener := calc.listen(t, test.network, test.address(t))
+			defer listener.Close()
+
+			client, err := Dial(context.Background(), test.network+"://"+listener.Addr().String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer client.Close()
+			testClient(t, client, calc)
+
+			// A slow call answered after a fast one still gets its own result.
+			var wg sync.WaitGroup
+			results := make([]int, 2)
+			for i, method := range []string{"slow_add", "add"} {
+				wg.Add(1)
+				go func(i int, method string) {
+					defer wg.Done()
+					if err := client.Call(context.Background(), method, []int{i, 10}, &results[i]); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+				}(i, method)
+			}
+			wg.Wait()
+			if results[0] != 10 || results[1] != 11 {
+				t.Errorf("Expected [10 11], Got: %v", results)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if err := client.Call(ctx, "slow_add", []int{1}, nil); err != context.DeadlineExceeded {
+				t.Errorf("Expected context.DeadlineExceeded, Got: %v", err)
+			}
+
+			client.Close()
+			if err := client.Call(context.Background(), "add", []int{1}, nil); !errors.Is(err, ErrClosed) {
+				t.Errorf("Expected ErrClosed, Got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStreamNullIDError(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		decoder := json.NewDecoder(server)
+		for {
+			var message json.RawMessage
+			if err := decoder.Decode(&message); err != nil {
+				return
+			}
+			server.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}` + "\n"))
+		}
+	}()
+	client := NewClient(NewStreamTransport(conn))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var rpcErr *Error
+	if err := client.Call(ctx, "add", []int{1}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidRequest {
+		t.Errorf("Expected an invalid request error, Got: %v", err)
+	}
+
+	batch := client.Batch()
+	batch.Call("add", []int{1}, nil)
+	batch.Call("add", []int{2}, nil)
+	if err := batch.Send(ctx); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidRequest {
+		t.Errorf("Expected the batch to be rejected, Got: %v", err)
+	}
+}
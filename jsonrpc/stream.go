@@ -0,0 +1,214 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/mgolfam/gogutils/glog"
+	"github.com/mgolfam/gogutils/utils"
+)
+
+// ErrClosed is returned for calls on a closed stream and for the calls
+// waiting when the connection broke, wrapping its error.
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// StreamTransport sends messages over a connection as newline delimited
+// JSON, and matches the responses to the waiting calls by ID so calls may
+// run concurrently.
+type StreamTransport struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]*pendingMessage
+	err     error
+	done    chan struct{}
+}
+
+// pendingMessage is a request or batch waiting for its response.
+type pendingMessage struct {
+	ids      []string
+	response chan []byte
+}
+
+// Dial connects to a TCP or Unix socket address, see
+// utils.ParseNetworkAddress, and returns a client on the connection.
+func Dial(ctx context.Context, address string) (*Client, error) {
+	conn, err := utils.DialAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(NewStreamTransport(conn)), nil
+}
+
+// NewStreamTransport starts reading the responses of conn.
+func NewStreamTransport(conn io.ReadWriteCloser) *StreamTransport {
+	t := &StreamTransport{conn: conn, pending: map[string]*pendingMessage{}, done: make(chan struct{})}
+	go t.read()
+	return t
+}
+
+func (t *StreamTransport) RoundTrip(ctx context.Context, message []byte, wait bool) ([]byte, error) {
+	var pending *pendingMessage
+	if wait {
+		pending = &pendingMessage{ids: messageIDs(message), response: make(chan []byte, 1)}
+		t.mu.Lock()
+		if t.err != nil {
+			t.mu.Unlock()
+			return nil, t.err
+		}
+		for _, id := range pending.ids {
+			t.pending[id] = pending
+		}
+		t.mu.Unlock()
+		defer t.forget(pending)
+	}
+
+	t.writeMu.Lock()
+	_, err := t.conn.Write(append(message, '\n'))
+	t.writeMu.Unlock()
+	if err != nil {
+		t.fail(err)
+		return nil, err
+	}
+	if !wait {
+		return nil, nil
+	}
+
+	select {
+	case response := <-pending.response:
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, t.err
+	}
+}
+
+// Close closes the connection, failing the waiting calls.
+func (t *StreamTransport) Close() error {
+	err := t.conn.Close()
+	t.fail(nil)
+	return err
+}
+
+func (t *StreamTransport) forget(pending *pendingMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range pending.ids {
+		if t.pending[id] == pending {
+			delete(t.pending, id)
+		}
+	}
+}
+
+// fail stops the transport once, with ErrClosed wrapping cause.
+func (t *StreamTransport) fail(cause error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = ErrClosed
+	if cause != nil && cause != io.EOF {
+		t.err = errors.Join(ErrClosed, cause)
+	}
+	close(t.done)
+}
+
+// read delivers the responses until the connection ends.
+func (t *StreamTransport) read() {
+	decoder := json.NewDecoder(t.conn)
+	for {
+		var message json.RawMessage
+		if err := decoder.Decode(&message); err != nil {
+			t.conn.Close()
+			t.fail(err)
+			return
+		}
+
+		ids := messageIDs(message)
+		if len(ids) == 0 && isErrorResponse(message) {
+			t.failPending(message)
+			continue
+		}
+
+		t.mu.Lock()
+		var pending *pendingMessage
+		for _, id := range ids {
+			if pending = t.pending[id]; pending != nil {
+				break
+			}
+		}
+		if pending != nil {
+			for _, id := range pending.ids {
+				delete(t.pending, id)
+			}
+		}
+		t.mu.Unlock()
+		if pending == nil {
+			glog.LogL(glog.DEBUG, "jsonrpc: dropped response", string(message))
+			continue
+		}
+		pending.response <- message
+	}
+}
+
+// failPending delivers an error response without an ID, such as a parse
+// error or a rejected batch, to every waiting call: the server could not
+// tell which request it answers.
+func (t *StreamTransport) failPending(message []byte) {
+	t.mu.Lock()
+	delivered := map[*pendingMessage]bool{}
+	for id, pending := range t.pending {
+		delete(t.pending, id)
+		delivered[pending] = true
+	}
+	t.mu.Unlock()
+
+	if len(delivered) == 0 {
+		glog.LogL(glog.DEBUG, "jsonrpc: dropped response", string(message))
+	}
+	for pending := range delivered {
+		pending.response <- message
+	}
+}
+
+// isErrorResponse reports whether message is a single response with an
+// error.
+func isErrorResponse(message []byte) bool {
+	var resp response
+	if json.Unmarshal(message, &resp) != nil {
+		return false
+	}
+	return resp.Error != nil
+}
+
+// messageIDs returns the keys of the IDs of a request, a response or a
+// batch of them.
+func messageIDs(message []byte) []string {
+	type withID struct {
+		ID json.RawMessage `json:"id"`
+	}
+	var items []withID
+	if trimmed := bytes.TrimSpace(message); len(trimmed) > 0 && trimmed[0] == '[' {
+		json.Unmarshal(trimmed, &items)
+	} else {
+		var item withID
+		json.Unmarshal(trimmed, &item)
+		items = append(items, item)
+	}
+
+	var ids []string
+	for _, item := range items {
+		if len(item.ID) > 0 && string(item.ID) != "null" {
+			ids = append(ids, idKey(item.ID))
+		}
+	}
+	return ids
+}
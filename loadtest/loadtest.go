@@ -0,0 +1,149 @@
+// Package loadtest sends one request repeatedly, at a target rate or with a
+// fixed number of workers, and reports throughput, status codes, errors and
+// latency percentiles.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+// Latencies are recorded in microseconds up to an hour with three
+// significant digits.
+const (
+	highestLatency = int64(time.Hour / time.Microsecond)
+	sigfigs        = 3
+)
+
+// Runner describes a load test. The test stops after Duration or after
+// Requests requests, whichever comes first; at least one must be set.
+type Runner struct {
+	Config httpclient.HttpConfig
+	// Client sends the requests, a new client with its own metrics registry
+	// when nil so the test does not touch the metrics or limits of
+	// httpclient.DefaultClient.
+	Client *httpclient.Client
+	// Rate is the target number of requests per second. Requests are
+	// scheduled at fixed intervals and their latency is measured from the
+	// scheduled time, so a stalling server is not hidden by fewer requests
+	// (coordinated omission). 0 sends as fast as Concurrency allows.
+	Rate float64
+	// Concurrency is the number of workers, 10 when 0. With Rate it caps
+	// the requests in flight.
+	Concurrency int
+	Duration    time.Duration
+	Requests    int
+}
+
+// Run runs the test. Canceling ctx stops it early and aborts the requests
+// in flight; the report covers the requests completed by then.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	if r.Duration <= 0 && r.Requests <= 0 {
+		return nil, errors.New("loadtest: Duration or Requests must be set")
+	}
+	if r.Config.URL == "" {
+		return nil, errors.New("loadtest: no URL")
+	}
+	client := r.Client
+	if client == nil {
+		client = httpclient.NewClient()
+		client.Metrics = httpclient.NewClientMetrics(metrics.NewRegistry())
+	}
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	config := r.Config
+	config.Cache, config.RetrieveCache, config.LogResponse = false, false, false
+
+	report := &Report{
+		URL:       config.URL,
+		Method:    config.Method,
+		Status:    map[int]int64{},
+		Errors:    map[string]int64{},
+		Histogram: metrics.NewHDRHistogram(highestLatency, sigfigs),
+	}
+	var mu sync.Mutex
+	record := func(latency time.Duration, resp *httpclient.HttpResponse, err error) {
+		report.Histogram.Record(int64(latency / time.Microsecond))
+		mu.Lock()
+		defer mu.Unlock()
+		report.Requests++
+		if err != nil {
+			report.Errors[err.Error()]++
+			return
+		}
+		report.Status[resp.StatusCode]++
+		report.Bytes += int64(len(resp.Body))
+	}
+
+	// The scheduler stops handing out work when the test ends; requests
+	// in flight finish on ctx so they are not counted as errors.
+	schedule, stop := ctx, context.CancelFunc(func() {})
+	if r.Duration > 0 {
+		schedule, stop = context.WithTimeout(ctx, r.Duration)
+	}
+	defer stop()
+
+	starts := make(chan time.Time)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for scheduled := range starts {
+				if scheduled.IsZero() {
+					scheduled = time.Now()
+				}
+				resp, err := client.SendRequestContext(ctx, config)
+				record(time.Since(scheduled), resp, err)
+			}
+		}()
+	}
+
+	began := time.Now()
+	r.schedule(schedule, starts, began)
+	close(starts)
+	wg.Wait()
+	report.Duration = time.Since(began)
+	if seconds := report.Duration.Seconds(); seconds > 0 {
+		report.Throughput = float64(report.Requests) / seconds
+	}
+	report.setLatency()
+	return report, ctx.Err()
+}
+
+// schedule feeds the workers until the schedule context ends or the
+// request count is reached. Without a rate it sends zero times, which the
+// workers replace with the moment they pick the request up.
+func (r *Runner) schedule(ctx context.Context, starts chan<- time.Time, began time.Time) {
+	var interval time.Duration
+	if r.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.Rate)
+	}
+	for n := 0; r.Requests <= 0 || n < r.Requests; n++ {
+		var scheduled time.Time
+		if interval > 0 {
+			scheduled = began.Add(time.Duration(n) * interval)
+			if wait := time.Until(scheduled); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case starts <- scheduled:
+		}
+	}
+}
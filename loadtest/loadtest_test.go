@@ -0,0 +1,91 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+func TestRunner(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%10 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		runner Runner
+		check  func(*Report) bool
+	}{
+		{
+			name:   "request count",
+			runner: Runner{Requests: 50, Concurrency: 5},
+			check:  func(r *Report) bool { return r.Requests == 50 && r.Status[200] == 45 && r.Status[503] == 5 },
+		},
+		{
+			name:   "rate and duration",
+			runner: Runner{Rate: 100, Duration: 300 * time.Millisecond},
+			check:  func(r *Report) bool { return r.Requests >= 25 && r.Requests <= 32 },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			test.runner.Config = httpclient.HttpConfig{Method: "GET", URL: server.URL, Timeout: 5 * time.Second}
+			report, err := test.runner.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !test.check(report) {
+				t.Errorf("Unexpected report: %+v", report)
+			}
+			host := strings.TrimPrefix(server.URL, "http://")
+			if sample, ok := metrics.Default.Find("httpclient_requests_total", map[string]string{"host": host, "method": "GET", "status_class": "2xx"}); ok {
+				t.Errorf("Expected no requests in the default metrics, Got: %v", sample.Value)
+			}
+			l := report.Latency
+			if l.Min <= 0 || l.P50 < l.Min || l.P90 < l.P50 || l.P99 < l.P90 || l.Max < l.P99 || report.Throughput <= 0 {
+				t.Errorf("Unexpected latency: %+v", l)
+			}
+		})
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	if _, err := (&Runner{Config: httpclient.HttpConfig{URL: "http://localhost"}}).Run(context.Background()); err == nil {
+		t.Errorf("Expected an error without Duration or Requests")
+	}
+
+	config, err := httpclient.ParseCurlCommand("curl http://127.0.0.1:1/down")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report, err := (&Runner{Config: *config, Requests: 3, Concurrency: 1}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Failures() != 3 || len(report.Errors) != 1 {
+		t.Errorf("Expected 3 connection errors, Got: %+v", report.Errors)
+	}
+
+	var text bytes.Buffer
+	report.WriteText(&text)
+	if !strings.Contains(text.String(), "Error:      3 x ") {
+		t.Errorf("Unexpected text report:\n%s", text.String())
+	}
+}
@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/metrics"
+)
+
+// Report is the outcome of a load test.
+type Report struct {
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Requests int64         `json:"requests"`
+	Duration time.Duration `json:"duration_ns"`
+	// Throughput is in completed requests per second.
+	Throughput float64 `json:"throughput"`
+	// Bytes counts the response bodies.
+	Bytes int64 `json:"bytes"`
+	// Status counts the responses by status code, Errors counts the
+	// requests without a response by error message.
+	Status  map[int]int64    `json:"status"`
+	Errors  map[string]int64 `json:"errors,omitempty"`
+	Latency Latency          `json:"latency"`
+	// Histogram holds every latency in microseconds, for percentiles not
+	// in Latency.
+	Histogram *metrics.HDRHistogram `json:"-"`
+}
+
+// Latency summarizes the latency distribution.
+type Latency struct {
+	Min  time.Duration `json:"min_ns"`
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+func (r *Report) setLatency() {
+	h := r.Histogram
+	us := func(v int64) time.Duration { return time.Duration(v) * time.Microsecond }
+	r.Latency = Latency{
+		Min:  us(h.Min()),
+		Mean: time.Duration(h.Mean() * float64(time.Microsecond)),
+		P50:  us(h.ValueAtPercentile(50)),
+		P90:  us(h.ValueAtPercentile(90)),
+		P99:  us(h.ValueAtPercentile(99)),
+		Max:  us(h.Max()),
+	}
+}
+
+// Failures returns the number of errors and 5xx responses.
+func (r *Report) Failures() int64 {
+	var failures int64
+	for _, n := range r.Errors {
+		failures += n
+	}
+	for code, n := range r.Status {
+		if code >= 500 {
+			failures += n
+		}
+	}
+	return failures
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report for a terminal.
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", r.Method, r.URL)
+	fmt.Fprintf(&b, "Requests:   %d in %s, %.1f req/s, %d bytes\n",
+		r.Requests, r.Duration.Round(time.Millisecond), r.Throughput, r.Bytes)
+	l := r.Latency
+	fmt.Fprintf(&b, "Latency:    min %s  mean %s  p50 %s  p90 %s  p99 %s  max %s\n",
+		round(l.Min), round(l.Mean), round(l.P50), round(l.P90), round(l.P99), round(l.Max))
+
+	codes := make([]int, 0, len(r.Status))
+	for code := range r.Status {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var status []string
+	for _, code := range codes {
+		status = append(status, fmt.Sprintf("%d: %d", code, r.Status[code]))
+	}
+	fmt.Fprintf(&b, "Status:     %s\n", strings.Join(status, ", "))
+
+	messages := make([]string, 0, len(r.Errors))
+	for message := range r.Errors {
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+	for _, message := range messages {
+		fmt.Fprintf(&b, "Error:      %d x %s\n", r.Errors[message], message)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
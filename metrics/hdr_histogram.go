@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// HDRHistogram records integer values, such as latencies in microseconds,
+// with a fixed relative precision over a wide range, in the manner of
+// HdrHistogram. Buckets double in width and each is split linearly into
+// sub-buckets, so a value is stored within 10^-sigfigs of its true value
+// whatever its magnitude. It is safe for concurrent use.
+type HDRHistogram struct {
+	mu            sync.Mutex
+	highest       int64
+	subBucketBits int
+	subBucketHalf int
+	counts        []int64
+	total         int64
+	min           int64
+	max           int64
+	sum           float64
+}
+
+// NewHDRHistogram returns a histogram for values from 0 to highest with
+// sigfigs, 1 to 5, significant decimal digits. Larger values are recorded
+// as highest, Max still reports them.
+func NewHDRHistogram(highest int64, sigfigs int) *HDRHistogram {
+	if sigfigs < 1 {
+		sigfigs = 1
+	}
+	if sigfigs > 5 {
+		sigfigs = 5
+	}
+	if highest < 2 {
+		highest = 2
+	}
+	subBucketBits := int(math.Ceil(math.Log2(2 * math.Pow10(sigfigs))))
+	h := &HDRHistogram{
+		highest:       highest,
+		subBucketBits: subBucketBits,
+		subBucketHalf: 1 << (subBucketBits - 1),
+		min:           math.MaxInt64,
+	}
+	h.counts = make([]int64, h.index(highest)+1)
+	return h
+}
+
+// index returns the slot of v. Bucket 0 covers [0, 2*half) one by one,
+// every following bucket covers twice the range with half slots.
+func (h *HDRHistogram) index(v int64) int {
+	bucket := bits.Len64(uint64(v)) - h.subBucketBits
+	if bucket < 0 {
+		bucket = 0
+	}
+	return bucket*h.subBucketHalf + int(v>>uint(bucket))
+}
+
+// highestEquivalent returns the largest value stored in the slot i.
+func (h *HDRHistogram) highestEquivalent(i int) int64 {
+	count := 2 * h.subBucketHalf
+	if i < count {
+		return int64(i)
+	}
+	bucket := (i-count)/h.subBucketHalf + 1
+	sub := int64((i-count)%h.subBucketHalf + h.subBucketHalf)
+	return (sub+1)<<uint(bucket) - 1
+}
+
+// Record adds one value, negative values count as 0.
+func (h *HDRHistogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.sum += float64(v)
+	h.total++
+	if v > h.highest {
+		v = h.highest
+	}
+	h.counts[h.index(v)]++
+}
+
+// Merge adds the values of other, which must have the same range and
+// precision.
+func (h *HDRHistogram) Merge(other *HDRHistogram) {
+	other.mu.Lock()
+	counts := append([]int64(nil), other.counts...)
+	total, min, max, sum := other.total, other.min, other.max, other.sum
+	other.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := 0; i < len(counts) && i < len(h.counts); i++ {
+		h.counts[i] += counts[i]
+	}
+	h.total += total
+	h.sum += sum
+	if min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+}
+
+func (h *HDRHistogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
+
+// Min returns the smallest value recorded, 0 when empty.
+func (h *HDRHistogram) Min() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return h.min
+}
+
+// Max returns the largest value recorded, 0 when empty.
+func (h *HDRHistogram) Max() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.max
+}
+
+func (h *HDRHistogram) Mean() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// ValueAtPercentile returns the value below or at which percentile, 0 to
+// 100, of the values fall. As with HdrHistogram it is the highest value
+// equivalent to the recorded one, capped at Max.
+func (h *HDRHistogram) ValueAtPercentile(percentile float64) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	if percentile >= 100 {
+		return h.max
+	}
+	target := int64(math.Ceil(percentile / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			if v := h.highestEquivalent(i); v < h.max {
+				return v
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHDRHistogram(t *testing.T) {
+	h := NewHDRHistogram(3600*1000*1000, 3)
+	for v := int64(1); v <= 10000; v++ {
+		h.Record(v * 100)
+	}
+
+	tests := []struct {
+		percentile float64
+		expected   int64
+	}{
+		{percentile: 0, expected: 100},
+		{percentile: 50, expected: 500000},
+		{percentile: 90, expected: 900000},
+		{percentile: 99, expected: 990000},
+		{percentile: 100, expected: 1000000},
+	}
+	for _, test := range tests {
+		got := h.ValueAtPercentile(test.percentile)
+		if math.Abs(float64(got-test.expected)) > float64(test.expected)/1000 {
+			t.Errorf("Expected p%v near %d, Got: %d", test.percentile, test.expected, got)
+		}
+	}
+	if h.Count() != 10000 || h.Min() != 100 || h.Max() != 1000000 || h.Mean() != 500050 {
+		t.Errorf("Unexpected count %d, min %d, max %d, mean %v", h.Count(), h.Min(), h.Max(), h.Mean())
+	}
+
+	other := NewHDRHistogram(3600*1000*1000, 3)
+	other.Record(7200 * 1000 * 1000)
+	h.Merge(other)
+	if h.Count() != 10001 || h.Max() != 7200*1000*1000 || h.ValueAtPercentile(100) != 7200*1000*1000 {
+		t.Errorf("Unexpected merge: count %d, max %d", h.Count(), h.Max())
+	}
+
+	if empty := NewHDRHistogram(1000, 2); empty.ValueAtPercentile(99) != 0 || empty.Min() != 0 {
+		t.Errorf("Expected zeros from an empty histogram")
+	}
+}
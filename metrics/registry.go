@@ -0,0 +1,393 @@
+// Package metrics is a small dependency free metrics registry with counters,
+// gauges and histograms that renders the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// Default is the registry used by the other packages of this module.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one labeled time series. Counters and gauges use value,
+// histograms use counts, sum and count.
+type series struct {
+	labelValues []string
+	value       atomicFloat
+	counts      []atomic.Uint64
+	sum         atomicFloat
+	count       atomic.Uint64
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(value float64) {
+	f.bits.Store(math.Float64bits(value))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// register returns the family called name, creating it on first use. Asking
+// for an existing name with another type or other labels panics, as that is
+// a programming error.
+func (r *Registry) register(name, help, kind string, buckets []float64, labelNames []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || strings.Join(f.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metrics: %s registered twice with different type or labels", name))
+		}
+		return f
+	}
+
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		if f.kind == TypeHistogram {
+			s.counts = make([]atomic.Uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// CounterVec is a counter family partitioned by labels.
+type CounterVec struct{ f *family }
+
+// Counter is a monotonically increasing value.
+type Counter struct{ s *series }
+
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, TypeCounter, nil, labelNames)}
+}
+
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return &Counter{v.f.with(labelValues)}
+}
+
+func (c *Counter) Inc() {
+	c.s.value.Add(1)
+}
+
+// Add increases the counter, negative values are ignored.
+func (c *Counter) Add(delta float64) {
+	if delta > 0 {
+		c.s.value.Add(delta)
+	}
+}
+
+// GaugeVec is a gauge family partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// Gauge is a value that goes up and down.
+type Gauge struct{ s *series }
+
+func (r *Registry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, TypeGauge, nil, labelNames)}
+}
+
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return &Gauge{v.f.with(labelValues)}
+}
+
+func (g *Gauge) Inc()              { g.s.value.Add(1) }
+func (g *Gauge) Dec()              { g.s.value.Add(-1) }
+func (g *Gauge) Add(delta float64) { g.s.value.Add(delta) }
+func (g *Gauge) Set(value float64) { g.s.value.Set(value) }
+
+// HistogramVec is a histogram family partitioned by labels.
+type HistogramVec struct{ f *family }
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// Histogram registers a histogram family, DefaultBuckets are used when
+// buckets is empty.
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{r.register(name, help, TypeHistogram, buckets, labelNames)}
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return &Histogram{s: v.f.with(labelValues), buckets: v.f.buckets}
+}
+
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.buckets, value)
+	if i < len(h.buckets) {
+		h.s.counts[i].Add(1)
+	}
+	h.s.sum.Add(value)
+	h.s.count.Add(1)
+}
+
+// Family is a snapshot of a metric family.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Sample is a snapshot of one series. Value is set for counters and
+// gauges; Buckets, Sum and Count for histograms.
+type Sample struct {
+	Labels  map[string]string
+	Value   float64
+	Buckets []Bucket
+	Sum     float64
+	Count   uint64
+}
+
+// Bucket holds the cumulative count of observations <= UpperBound.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Snapshot returns the current values of all families sorted by name, and
+// their series sorted by label values.
+func (r *Registry) Snapshot() []Family {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(a, b int) bool { return families[a].name < families[b].name })
+
+	result := make([]Family, 0, len(families))
+	for _, f := range families {
+		result = append(result, f.snapshot())
+	}
+	return result
+}
+
+// Find returns the sample of family name with exactly the given labels.
+func (r *Registry) Find(name string, labels map[string]string) (Sample, bool) {
+	for _, f := range r.Snapshot() {
+		if f.Name != name {
+			continue
+		}
+		for _, sample := range f.Samples {
+			if sameLabels(sample.Labels, labels) {
+				return sample, true
+			}
+		}
+	}
+	return Sample{}, false
+}
+
+func sameLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if b[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *family) snapshot() Family {
+	f.mu.Lock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(all, func(a, b int) bool {
+		return strings.Join(all[a].labelValues, "\xff") < strings.Join(all[b].labelValues, "\xff")
+	})
+
+	family := Family{Name: f.name, Help: f.help, Type: f.kind}
+	for _, s := range all {
+		sample := Sample{Labels: make(map[string]string, len(f.labelNames))}
+		for i, name := range f.labelNames {
+			sample.Labels[name] = s.labelValues[i]
+		}
+
+		if f.kind == TypeHistogram {
+			var cumulative uint64
+			for i, bound := range f.buckets {
+				cumulative += s.counts[i].Load()
+				sample.Buckets = append(sample.Buckets, Bucket{UpperBound: bound, Count: cumulative})
+			}
+			sample.Sum = s.sum.Load()
+			sample.Count = s.count.Load()
+		} else {
+			sample.Value = s.value.Load()
+		}
+		family.Samples = append(family.Samples, sample)
+	}
+	return family
+}
+
+// WriteText renders all families in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	for _, f := range r.Snapshot() {
+		labelNames := r.labelNames(f.Name)
+
+		if f.Help != "" {
+			fmt.Fprintf(&sb, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		}
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", f.Name, f.Type)
+
+		for _, sample := range f.Samples {
+			if f.Type != TypeHistogram {
+				fmt.Fprintf(&sb, "%s%s %s\n", f.Name, formatLabels(labelNames, sample.Labels, "", ""), formatFloat(sample.Value))
+				continue
+			}
+
+			for _, bucket := range sample.Buckets {
+				fmt.Fprintf(&sb, "%s_bucket%s %d\n", f.Name,
+					formatLabels(labelNames, sample.Labels, "le", formatFloat(bucket.UpperBound)), bucket.Count)
+			}
+			fmt.Fprintf(&sb, "%s_bucket%s %d\n", f.Name, formatLabels(labelNames, sample.Labels, "le", "+Inf"), sample.Count)
+			fmt.Fprintf(&sb, "%s_sum%s %s\n", f.Name, formatLabels(labelNames, sample.Labels, "", ""), formatFloat(sample.Sum))
+			fmt.Fprintf(&sb, "%s_count%s %d\n", f.Name, formatLabels(labelNames, sample.Labels, "", ""), sample.Count)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+func (r *Registry) labelNames(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.families[name].labelNames
+}
+
+func formatLabels(names []string, values map[string]string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(values[name])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.Counter("requests_total", "Requests sent.", "host", "code")
+	requests.With("a.example", "2xx").Inc()
+	requests.With("a.example", "2xx").Add(2)
+	requests.With(`b"x`, "5xx").Inc()
+	registry.Gauge("in_flight", "In flight\nrequests.").With().Set(3)
+	latency := registry.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "host")
+	latency.With("a.example").Observe(0.05)
+	latency.With("a.example").Observe(0.5)
+	latency.With("a.example").Observe(7)
+
+	expected := `# HELP in_flight In flight\nrequests.
+# TYPE in_flight gauge
+in_flight 3
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{host="a.example",le="0.1"} 1
+latency_seconds_bucket{host="a.example",le="1"} 2
+latency_seconds_bucket{host="a.example",le="+Inf"} 3
+latency_seconds_sum{host="a.example"} 7.55
+latency_seconds_count{host="a.example"} 3
+# HELP requests_total Requests sent.
+# TYPE requests_total counter
+requests_total{host="a.example",code="2xx"} 3
+requests_total{host="b\"x",code="5xx"} 1
+`
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if got := recorder.Body.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Expected Content-Type: %s, Got: %s", ContentType, got)
+	}
+
+	sample, ok := registry.Find("requests_total", map[string]string{"host": "a.example", "code": "2xx"})
+	if !ok || sample.Value != 3 {
+		t.Errorf("Expected snapshot value 3, Got: %v %v", sample.Value, ok)
+	}
+}
+
+func TestRegistryConflictingRegistration(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("x_total", "", "a")
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "x_total") {
+			t.Errorf("Expected a panic naming x_total, Got: %v", r)
+		}
+	}()
+	registry.Gauge("x_total", "", "a")
+}
@@ -0,0 +1,232 @@
+// Package openapi loads OpenAPI 3.0 and 3.1 documents and turns their
+// operations into request templates for httpclient.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the part of an OpenAPI document needed to build requests.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Server struct {
+	URL         string                    `json:"url"`
+	Description string                    `json:"description"`
+	Variables   map[string]ServerVariable `json:"variables"`
+}
+
+type ServerVariable struct {
+	Default string   `json:"default"`
+	Enum    []string `json:"enum"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Servers    []Server    `json:"servers"`
+	Get        *Operation  `json:"get"`
+	Put        *Operation  `json:"put"`
+	Post       *Operation  `json:"post"`
+	Delete     *Operation  `json:"delete"`
+	Options    *Operation  `json:"options"`
+	Head       *Operation  `json:"head"`
+	Patch      *Operation  `json:"patch"`
+	Trace      *Operation  `json:"trace"`
+}
+
+// operations returns the operations of the item in a fixed order.
+func (p PathItem) operations() []struct {
+	method string
+	op     *Operation
+} {
+	all := []struct {
+		method string
+		op     *Operation
+	}{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch}, {"TRACE", p.Trace},
+	}
+	ops := all[:0]
+	for _, o := range all {
+		if o.op != nil {
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Tags        []string     `json:"tags"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+	Servers     []Server     `json:"servers"`
+}
+
+type Parameter struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *Schema     `json:"schema"`
+	Example  interface{} `json:"example"`
+}
+
+type RequestBody struct {
+	Ref      string               `json:"$ref"`
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema            `json:"schema"`
+	Example  interface{}        `json:"example"`
+	Examples map[string]Example `json:"examples"`
+}
+
+type Example struct {
+	Value interface{} `json:"value"`
+}
+
+// Schema is a JSON schema. Type is a string in 3.0 and may be a list in
+// 3.1, use Types to read it.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties interface{}        `json:"additionalProperties"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Default              interface{}        `json:"default"`
+	Example              interface{}        `json:"example"`
+	Examples             []interface{}      `json:"examples"`
+	AllOf                []*Schema          `json:"allOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	Minimum              *float64           `json:"minimum"`
+}
+
+// Types returns the schema's types, without "null".
+func (s *Schema) Types() []string {
+	var types []string
+	switch t := s.Type.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if name, ok := v.(string); ok && name != "null" {
+				types = append(types, name)
+			}
+		}
+	}
+	return types
+}
+
+type Components struct {
+	Schemas       map[string]*Schema     `json:"schemas"`
+	Parameters    map[string]Parameter   `json:"parameters"`
+	RequestBodies map[string]RequestBody `json:"requestBodies"`
+}
+
+// Load parses a JSON or YAML document.
+func Load(data []byte) (*Document, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		// YAML is converted to JSON so one set of struct tags suffices.
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(normalizeYAML(v)); err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+	}
+
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q, only 3.x is supported", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+func LoadFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// normalizeYAML turns the map[interface{}]interface{} values YAML may
+// produce into JSON compatible maps.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	}
+	return v
+}
+
+// resolveSchema follows a local $ref such as "#/components/schemas/Pet".
+func (d *Document) resolveSchema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < 16; i++ {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (d *Document) resolveParameter(p Parameter) Parameter {
+	if p.Ref != "" {
+		if resolved, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]; ok {
+			return resolved
+		}
+	}
+	return p
+}
+
+func (d *Document) resolveRequestBody(b *RequestBody) *RequestBody {
+	if b != nil && b.Ref != "" {
+		if resolved, ok := d.Components.RequestBodies[strings.TrimPrefix(b.Ref, "#/components/requestBodies/")]; ok {
+			return &resolved
+		}
+	}
+	return b
+}
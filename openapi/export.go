@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+// WriteHTTPFile writes the templates, filled with vars, as a .http file
+// as read by editor REST clients. The base URL becomes the {{baseUrl}}
+// file variable.
+func WriteHTTPFile(w io.Writer, templates []*Template, vars map[string]string) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	baseURL := templates[0].BaseURL
+	if v, ok := vars[BaseURLVar]; ok {
+		baseURL = v
+	}
+	if _, err := fmt.Fprintf(w, "@%s = %s\n", BaseURLVar, baseURL); err != nil {
+		return err
+	}
+
+	for _, t := range templates {
+		config, err := t.Config(vars)
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		b.WriteString("\n### " + t.Name)
+		if t.Summary != "" && t.Summary != t.Name {
+			b.WriteString(" - " + t.Summary)
+		}
+
+		target := config.URL
+		if baseURL != "" && strings.HasPrefix(target, baseURL) {
+			target = "{{" + BaseURLVar + "}}" + strings.TrimPrefix(target, baseURL)
+		}
+		b.WriteString("\n" + config.Method + " " + target + "\n")
+
+		keys := make([]string, 0, len(config.Headers))
+		for key := range config.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b.WriteString(key + ": " + config.Headers[key] + "\n")
+		}
+		if config.Body != nil {
+			b.WriteString("\n" + string(config.Body) + "\n")
+		}
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCurl writes the templates, filled with vars, as curl commands.
+func WriteCurl(w io.Writer, templates []*Template, vars map[string]string) error {
+	for i, t := range templates {
+		config, err := t.Config(vars)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			io.WriteString(w, "\n")
+		}
+		if _, err := fmt.Fprintf(w, "# %s\n%s\n", t.Name, httpclient.ToCurlCommand(config)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
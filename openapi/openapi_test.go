@@ -0,0 +1,148 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	doc, err := LoadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, tmpl := range doc.Templates() {
+		names = append(names, tmpl.Method+" "+tmpl.Name)
+	}
+	expected := []string{"GET listPets", "POST createPet", "GET showPetById", "DELETE deletePet"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected templates: %v, Got: %v", expected, names)
+	}
+
+	tests := []struct {
+		name            string
+		operation       string
+		vars            map[string]string
+		expectedURL     string
+		expectedHeaders map[string]string
+		expectedBody    string
+	}{
+		{
+			name:        "examples fill required params",
+			operation:   "listPets",
+			expectedURL: "https://eu.petstore.example.com/v1/pets?limit=1",
+		},
+		{
+			name:        "vars override examples and add optional params",
+			operation:   "listPets",
+			vars:        map[string]string{"limit": "5", "tag": "dog", BaseURLVar: "http://localhost:9000"},
+			expectedURL: "http://localhost:9000/pets?limit=5&tag=dog",
+		},
+		{
+			name:            "path item params and headers",
+			operation:       "showPetById",
+			vars:            map[string]string{"X-Request-Id": "abc"},
+			expectedURL:     "https://eu.petstore.example.com/v1/pets/42",
+			expectedHeaders: map[string]string{"X-Request-Id": "abc"},
+		},
+		{
+			name:            "body generated from schema",
+			operation:       "createPet",
+			expectedURL:     "https://eu.petstore.example.com/v1/pets",
+			expectedHeaders: map[string]string{"Content-Type": "application/json"},
+			expectedBody:    `{"born":"2024-01-01","name":"Rex","tags":["cute"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := doc.Template(test.operation).Config(test.vars)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if config.URL != test.expectedURL {
+				t.Errorf("Expected URL: %s, Got: %s", test.expectedURL, config.URL)
+			}
+			for key, value := range test.expectedHeaders {
+				if config.Headers[key] != value {
+					t.Errorf("Expected header %s: %s, Got: %s", key, value, config.Headers[key])
+				}
+			}
+			if test.expectedBody != "" {
+				var compact bytes.Buffer
+				json.Compact(&compact, config.Body)
+				if compact.String() != test.expectedBody {
+					t.Errorf("Expected body: %s, Got: %s", test.expectedBody, compact.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadJSON31(t *testing.T) {
+	doc, err := LoadFile("testdata/users.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tmpl := doc.Template("updateUser")
+	if tmpl == nil {
+		t.Fatalf("Expected template updateUser")
+	}
+
+	if config, _ := tmpl.Config(nil); config.URL != "http://localhost:8080/users/string" {
+		t.Errorf("Expected the path parameter to be made up from its schema, Got: %s", config.URL)
+	}
+	tmpl.Path = "/users/{id}/{version}"
+	if _, err := tmpl.Config(map[string]string{"id": "7"}); err == nil {
+		t.Errorf("Expected an error for the undeclared path parameter")
+	}
+	tmpl.Path = "/users/{id}"
+
+	config, err := tmpl.Config(map[string]string{"id": "a/b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Method != "PUT" || config.URL != "http://localhost:8080/users/a%2Fb" || !strings.Contains(string(config.Body), "bob@example.com") {
+		t.Errorf("Unexpected config: %s %s %s", config.Method, config.URL, config.Body)
+	}
+
+	if _, err := Load([]byte(`{"swagger": "2.0"}`)); err == nil {
+		t.Errorf("Expected an error for Swagger 2.0")
+	}
+}
+
+func TestExport(t *testing.T) {
+	doc, err := LoadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	templates := []*Template{doc.Template("listPets"), doc.Template("deletePet")}
+
+	var httpFile bytes.Buffer
+	if err := WriteHTTPFile(&httpFile, templates, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `@baseUrl = https://eu.petstore.example.com/v1
+
+### listPets - List all pets
+GET {{baseUrl}}/pets?limit=1
+
+### deletePet
+DELETE {{baseUrl}}/pets/42
+`
+	if httpFile.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, httpFile.String())
+	}
+
+	var curl bytes.Buffer
+	if err := WriteCurl(&curl, templates, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(curl.String(), "# deletePet\ncurl \\\n  -X DELETE \\\n  https://eu.petstore.example.com/v1/pets/42") {
+		t.Errorf("Unexpected curl export:\n%s", curl.String())
+	}
+}
@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+// Param is a path, query or header parameter of a Template.
+type Param struct {
+	Name     string
+	In       string
+	Required bool
+	// Example is the value used when no variable is given, taken from the
+	// document's examples or made up from the schema.
+	Example string
+}
+
+// Template is a request template for one operation.
+type Template struct {
+	Name    string
+	Summary string
+	Method  string
+	// BaseURL is the first server URL, with its variables at their
+	// defaults. The "baseUrl" variable overrides it.
+	BaseURL string
+	// Path is the path template, such as /pets/{petId}.
+	Path        string
+	Params      []Param
+	ContentType string
+	// Body is an example body generated from the schema, it can be edited
+	// before calling Config.
+	Body []byte
+}
+
+// BaseURLVar is the variable that overrides the BaseURL of a template.
+const BaseURLVar = "baseUrl"
+
+// Templates returns one template per operation, sorted by path and method.
+func (d *Document) Templates() []*Template {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var templates []*Template
+	for _, path := range paths {
+		item := d.Paths[path]
+		for _, o := range item.operations() {
+			templates = append(templates, d.template(path, item, o.method, o.op))
+		}
+	}
+	return templates
+}
+
+// Template returns the template of the operation with the given
+// operationId, or nil.
+func (d *Document) Template(operationID string) *Template {
+	for _, t := range d.Templates() {
+		if t.Name == operationID {
+			return t
+		}
+	}
+	return nil
+}
+
+func (d *Document) template(path string, item PathItem, method string, op *Operation) *Template {
+	t := &Template{Name: op.OperationID, Summary: op.Summary, Method: method, Path: path}
+	if t.Name == "" {
+		t.Name = method + " " + path
+	}
+
+	servers := d.Servers
+	if len(item.Servers) > 0 {
+		servers = item.Servers
+	}
+	if len(op.Servers) > 0 {
+		servers = op.Servers
+	}
+	if len(servers) > 0 {
+		t.BaseURL = serverURL(servers[0])
+	}
+
+	// Operation parameters override path item ones with the same name and
+	// location.
+	seen := map[string]bool{}
+	for _, params := range [][]Parameter{op.Parameters, item.Parameters} {
+		for _, p := range params {
+			p = d.resolveParameter(p)
+			if p.In == "cookie" || seen[p.In+" "+p.Name] {
+				continue
+			}
+			seen[p.In+" "+p.Name] = true
+			t.Params = append(t.Params, Param{
+				Name:     p.Name,
+				In:       p.In,
+				Required: p.Required || p.In == "path",
+				Example:  d.paramExample(p),
+			})
+		}
+	}
+
+	if body := d.resolveRequestBody(op.RequestBody); body != nil {
+		t.ContentType, t.Body = d.bodyExample(body)
+	}
+	return t
+}
+
+func serverURL(s Server) string {
+	u := s.URL
+	for name, v := range s.Variables {
+		u = strings.ReplaceAll(u, "{"+name+"}", v.Default)
+	}
+	return strings.TrimRight(u, "/")
+}
+
+func (d *Document) paramExample(p Parameter) string {
+	value := p.Example
+	if value == nil {
+		value = d.example(p.Schema, 0)
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// bodyExample prefers JSON content and uses the first example given by the
+// document before falling back to one generated from the schema.
+func (d *Document) bodyExample(body *RequestBody) (string, []byte) {
+	types := make([]string, 0, len(body.Content))
+	for contentType := range body.Content {
+		types = append(types, contentType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		ji, jj := strings.Contains(types[i], "json"), strings.Contains(types[j], "json")
+		if ji != jj {
+			return ji
+		}
+		return types[i] < types[j]
+	})
+	if len(types) == 0 {
+		return "", nil
+	}
+
+	contentType := types[0]
+	media := body.Content[contentType]
+	value := media.Example
+	if value == nil {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			value = media.Examples[names[0]].Value
+		}
+	}
+	if value == nil {
+		value = d.example(media.Schema, 0)
+	}
+
+	if strings.Contains(contentType, "x-www-form-urlencoded") {
+		if fields, ok := value.(map[string]interface{}); ok {
+			form := url.Values{}
+			for key, v := range fields {
+				form.Set(key, fmt.Sprint(v))
+			}
+			return contentType, []byte(form.Encode())
+		}
+	}
+	if s, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return contentType, []byte(s)
+	}
+	data, _ := json.MarshalIndent(value, "", "  ")
+	return contentType, data
+}
+
+// example makes up a value matching the schema.
+func (d *Document) example(s *Schema, depth int) interface{} {
+	s = d.resolveSchema(s)
+	if s == nil || depth > 8 {
+		return nil
+	}
+
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case len(s.Examples) > 0:
+		return s.Examples[0]
+	case s.Const != nil:
+		return s.Const
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, part := range s.AllOf {
+			if fields, ok := d.example(part, depth+1).(map[string]interface{}); ok {
+				for key, value := range fields {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	case len(s.OneOf) > 0:
+		return d.example(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return d.example(s.AnyOf[0], depth+1)
+	}
+
+	typ := ""
+	if types := s.Types(); len(types) > 0 {
+		typ = types[0]
+	} else if len(s.Properties) > 0 {
+		typ = "object"
+	} else if s.Items != nil {
+		typ = "array"
+	}
+
+	switch typ {
+	case "object":
+		fields := map[string]interface{}{}
+		for name, prop := range s.Properties {
+			fields[name] = d.example(prop, depth+1)
+		}
+		return fields
+	case "array":
+		if item := d.example(s.Items, depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "integer", "number":
+		if s.Minimum != nil {
+			return *s.Minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		return stringExample(s.Format)
+	}
+	return nil
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date-time":
+		return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "c3RyaW5n"
+	}
+	return "string"
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// Config fills the template with vars and returns the request. Parameters
+// without a variable use their example when required and are left out
+// otherwise.
+func (t *Template) Config(vars map[string]string) (httpclient.HttpConfig, error) {
+	baseURL := t.BaseURL
+	if v, ok := vars[BaseURLVar]; ok {
+		baseURL = v
+	}
+
+	b := httpclient.NewRequest(t.Method, baseURL).Path(t.Path)
+	for _, p := range t.Params {
+		value, ok := vars[p.Name]
+		if !ok {
+			if !p.Required {
+				continue
+			}
+			if p.Example == "" && p.In == "path" {
+				return httpclient.HttpConfig{}, fmt.Errorf("%s: missing path parameter %s", t.Name, p.Name)
+			}
+			value = p.Example
+		}
+
+		switch p.In {
+		case "path":
+			b.Param(p.Name, value)
+		case "query":
+			b.Query(p.Name, value)
+		case "header":
+			b.Header(p.Name, value)
+		}
+	}
+	if t.Body != nil {
+		b.Body(t.ContentType, t.Body)
+	}
+
+	// Parameters the document forgot to declare still need a value.
+	for _, match := range pathParamPattern.FindAllStringSubmatch(t.Path, -1) {
+		if !t.hasParam(match[1]) {
+			value, ok := vars[match[1]]
+			if !ok {
+				return httpclient.HttpConfig{}, fmt.Errorf("%s: missing path parameter %s", t.Name, match[1])
+			}
+			b.Param(match[1], value)
+		}
+	}
+	return b.Build()
+}
+
+func (t *Template) hasParam(name string) bool {
+	for _, p := range t.Params {
+		if p.In == "path" && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
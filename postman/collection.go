@@ -0,0 +1,239 @@
+// Package postman imports Postman v2.1 collections and environments and
+// runs their requests through httpclient.
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Collection is a Postman v2.1 collection.
+type Collection struct {
+	Info     Info       `json:"info"`
+	Items    []*Item    `json:"item"`
+	Variable []Variable `json:"variable"`
+	Auth     *Auth      `json:"auth"`
+	Event    []Event    `json:"event"`
+}
+
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// Item is a request or, when it has Items, a folder.
+type Item struct {
+	Name     string     `json:"name"`
+	Items    []*Item    `json:"item"`
+	Request  *Request   `json:"request"`
+	Event    []Event    `json:"event"`
+	Auth     *Auth      `json:"auth"`
+	Variable []Variable `json:"variable"`
+
+	// Tests and Extract are read from the item's test script, see
+	// ParseScript. More can be added before running the collection.
+	Tests   []Assertion  `json:"-"`
+	Extract []Extraction `json:"-"`
+	// Prepare holds the variables set by the pre-request script.
+	Prepare []Extraction `json:"-"`
+}
+
+type Variable struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+	// Enabled is used by environments instead of Disabled.
+	Enabled *bool `json:"enabled"`
+}
+
+func (v Variable) active() bool {
+	return !v.Disabled && (v.Enabled == nil || *v.Enabled)
+}
+
+type Request struct {
+	Method string     `json:"method"`
+	Header []Variable `json:"header"`
+	URL    URL        `json:"url"`
+	Body   *Body      `json:"body"`
+	Auth   *Auth      `json:"auth"`
+}
+
+// UnmarshalJSON accepts the short form of a request, a plain URL string.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*r = Request{Method: "GET", URL: URL{Raw: raw}}
+		return nil
+	}
+	type plain Request
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// URL is a request URL. Raw is used when set, the parts otherwise.
+type URL struct {
+	Raw      string     `json:"raw"`
+	Protocol string     `json:"protocol"`
+	Host     []string   `json:"host"`
+	Path     []string   `json:"path"`
+	Query    []Variable `json:"query"`
+	Variable []Variable `json:"variable"`
+}
+
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*u = URL{Raw: raw}
+		return nil
+	}
+	type plain URL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// String returns the URL before variables are resolved.
+func (u URL) String() string {
+	s := u.Raw
+	if s == "" {
+		s = strings.Join(u.Host, ".")
+		if u.Protocol != "" {
+			s = u.Protocol + "://" + s
+		}
+		if len(u.Path) > 0 {
+			s += "/" + strings.Join(u.Path, "/")
+		}
+		var query []string
+		for _, q := range u.Query {
+			if q.active() {
+				query = append(query, q.Key+"="+q.Value)
+			}
+		}
+		if len(query) > 0 {
+			s += "?" + strings.Join(query, "&")
+		}
+	}
+
+	// Path variables such as :id.
+	for _, v := range u.Variable {
+		s = strings.Replace(s, "/:"+v.Key, "/"+v.Value, 1)
+	}
+	return s
+}
+
+type Body struct {
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw"`
+	URLEncoded []Variable `json:"urlencoded"`
+	FormData   []FormPart `json:"formdata"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type FormPart struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+	Src      string `json:"src"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Auth is a Postman auth block. Only noauth, basic, bearer and apikey are
+// supported.
+type Auth struct {
+	Type   string     `json:"type"`
+	Basic  []Variable `json:"basic"`
+	Bearer []Variable `json:"bearer"`
+	APIKey []Variable `json:"apikey"`
+}
+
+func (a *Auth) param(params []Variable, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+type Event struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec json.RawMessage `json:"exec"`
+	} `json:"script"`
+}
+
+// Lines returns the script, which Postman stores as a string or as a list
+// of lines.
+func (e Event) Lines() []string {
+	var lines []string
+	if json.Unmarshal(e.Script.Exec, &lines) == nil {
+		return lines
+	}
+	var script string
+	json.Unmarshal(e.Script.Exec, &script)
+	return strings.Split(script, "\n")
+}
+
+// Environment is a Postman environment export.
+type Environment struct {
+	Name   string     `json:"name"`
+	Values []Variable `json:"values"`
+}
+
+// Load parses a collection and the assertions and extractions of its
+// scripts.
+func Load(data []byte) (*Collection, error) {
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("postman: %w", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "v2.1") && !strings.Contains(c.Info.Schema, "v2.0") {
+		return nil, fmt.Errorf("postman: unsupported collection schema %s", c.Info.Schema)
+	}
+
+	var parse func(items []*Item)
+	parse = func(items []*Item) {
+		for _, item := range items {
+			for _, event := range item.Event {
+				script := ParseScript(event.Lines())
+				switch event.Listen {
+				case "test":
+					item.Tests = append(item.Tests, script.Tests...)
+					item.Extract = append(item.Extract, script.Extract...)
+				case "prerequest":
+					item.Prepare = append(item.Prepare, script.Extract...)
+				}
+			}
+			parse(item.Items)
+		}
+	}
+	parse(c.Items)
+	return &c, nil
+}
+
+func LoadFile(path string) (*Collection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+func LoadEnvironment(data []byte) (*Environment, error) {
+	var env Environment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("postman: %w", err)
+	}
+	return &env, nil
+}
+
+func LoadEnvironmentFile(path string) (*Environment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadEnvironment(data)
+}
@@ -0,0 +1,128 @@
+package postman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := ParseScript([]string{
+		`pm.test("ok", function () {`,
+		`    pm.expect(pm.response.code).to.eql(200);`,
+		`    pm.expect(pm.response.json().items[0].id).to.eql('a');`,
+		`});`,
+		`const body = pm.response.json();`,
+		`pm.expect(body.next).to.exist;`,
+		`pm.collectionVariables.set("next", body.next);`,
+		`pm.environment.set("etag", pm.response.headers.get("ETag"));`,
+		`pm.globals.set("n", 3);`,
+		`console.log(body);`,
+	})
+
+	expectedTests := []Assertion{
+		{Name: "ok", Status: 200},
+		{Name: "ok", JSONPath: "$.items[0].id", Equals: "a"},
+		{Name: "pm.expect(body.next).to.exist", JSONPath: "$.next"},
+	}
+	expectedExtract := []Extraction{
+		{Var: "next", JSONPath: "$.next"},
+		{Var: "etag", Header: "ETag"},
+		{Var: "n", Value: "3"},
+	}
+	if !reflect.DeepEqual(script.Tests, expectedTests) {
+		t.Errorf("Expected tests: %+v, Got: %+v", expectedTests, script.Tests)
+	}
+	if !reflect.DeepEqual(script.Extract, expectedExtract) {
+		t.Errorf("Expected extractions: %+v, Got: %+v", expectedExtract, script.Extract)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login":
+			var login map[string]string
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &login)
+			if login["user"] != "bob" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"session": {"token": "t0k"}}`))
+		case r.URL.Path == "/users/7" && r.Header.Get("Authorization") == "Bearer t0k" && r.Header.Get("X-Trace") != "":
+			w.Write([]byte(`{"name": "bob"}`))
+		case r.URL.Path == "/users" && r.Method == "POST":
+			r.ParseForm()
+			if r.PostForm.Encode() != "name=eve" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	collection, err := LoadFile("testdata/collection.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	env, err := LoadEnvironmentFile("testdata/environment.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	runner := NewRunner(env)
+	runner.Vars["baseUrl"] = server.URL
+	report := runner.Run(context.Background(), collection)
+
+	var names []string
+	var passed []bool
+	for _, result := range report.Results {
+		names = append(names, result.Name)
+		passed = append(passed, result.Passed())
+	}
+	if !reflect.DeepEqual(names, []string{"auth/login", "get user", "create user"}) {
+		t.Errorf("Unexpected results: %v", names)
+	}
+	if !reflect.DeepEqual(passed, []bool{true, true, false}) {
+		t.Errorf("Expected only create user to fail, Got: %v", passed)
+	}
+	if runner.Vars["token"] != "t0k" {
+		t.Errorf("Expected token from the login response, Got: %q", runner.Vars["token"])
+	}
+	if report.Failures() != 1 || report.Results[2].Assertions[0].Message != "expected status 201, got 409" {
+		t.Errorf("Unexpected failure: %+v", report.Results[2])
+	}
+
+	var jsonReport bytes.Buffer
+	if err := report.WriteJSON(&jsonReport); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(jsonReport.Bytes(), &decoded); err != nil || len(decoded.Results) != 3 {
+		t.Errorf("Unexpected JSON report: %v\n%s", err, jsonReport.String())
+	}
+
+	var junit bytes.Buffer
+	if err := report.WriteJUnit(&junit); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Suites[0].Cases[2].Failure == nil ||
+		!strings.Contains(suites.Suites[0].Cases[2].Failure.Text, "expected status 201") {
+		t.Errorf("Unexpected JUnit report:\n%s", junit.String())
+	}
+}
@@ -0,0 +1,153 @@
+package postman
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report is the outcome of a collection run.
+type Report struct {
+	Name       string    `json:"name"`
+	Started    time.Time `json:"started"`
+	DurationMs float64   `json:"duration_ms"`
+	Results    []*Result `json:"results"`
+}
+
+// Result is the outcome of one request.
+type Result struct {
+	// Name is the item name prefixed by its folders, as in "users/create".
+	Name       string            `json:"name"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code,omitempty"`
+	DurationMs float64           `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+}
+
+type AssertionResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Passed reports whether the request was sent and all its assertions held.
+func (r *Result) Passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns the number of results that did not pass.
+func (r *Report) Failures() int {
+	failures := 0
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failures++
+		}
+	}
+	return failures
+}
+
+func (r *Report) Passed() bool {
+	return r.Failures() == 0
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, one test case per request.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:      r.Name,
+		Tests:     len(r.Results),
+		Time:      seconds(r.DurationMs),
+		Timestamp: r.Started.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, result := range r.Results {
+		c := junitCase{Name: result.Name, ClassName: r.Name, Time: seconds(result.DurationMs)}
+		if result.Error != "" {
+			suite.Errors++
+			c.Error = &junitMessage{Message: result.Error, Text: result.Method + " " + result.URL}
+		} else if !result.Passed() {
+			suite.Failures++
+			var failed []string
+			for _, a := range result.Assertions {
+				if !a.Passed {
+					failed = append(failed, a.Name+": "+a.Message)
+				}
+			}
+			c.Failure = &junitMessage{Message: failed[0], Text: strings.Join(failed, "\n")}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	suites := junitSuites{
+		Name:     r.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
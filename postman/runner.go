@@ -0,0 +1,333 @@
+package postman
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/glog"
+	"github.com/mgolfam/gogutils/httpclient"
+	"github.com/mgolfam/gogutils/utils"
+)
+
+// Runner executes the requests of a collection in order.
+type Runner struct {
+	// Client sends the requests, httpclient.DefaultClient when nil.
+	Client  *httpclient.Client
+	Timeout time.Duration
+	// Vars override the collection variables and receive the variables
+	// set by the scripts during the run.
+	Vars map[string]string
+}
+
+// NewRunner returns a runner using the values of env, which may be nil.
+func NewRunner(env *Environment) *Runner {
+	r := &Runner{Timeout: 30 * time.Second, Vars: map[string]string{}}
+	if env != nil {
+		for _, v := range env.Values {
+			if v.active() {
+				r.Vars[v.Key] = v.Value
+			}
+		}
+	}
+	return r
+}
+
+// Run sends every request of c and checks its assertions.
+func (r *Runner) Run(ctx context.Context, c *Collection) *Report {
+	if r.Vars == nil {
+		r.Vars = map[string]string{}
+	}
+	for _, v := range c.Variable {
+		if _, ok := r.Vars[v.Key]; !ok && v.active() {
+			r.Vars[v.Key] = v.Value
+		}
+	}
+
+	report := &Report{Name: c.Info.Name, Started: time.Now()}
+	r.runItems(ctx, report, c.Items, "", c.Auth)
+	report.DurationMs = msSince(report.Started)
+	return report
+}
+
+func (r *Runner) runItems(ctx context.Context, report *Report, items []*Item, prefix string, auth *Auth) {
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+		for _, v := range item.Variable {
+			if _, ok := r.Vars[v.Key]; !ok && v.active() {
+				r.Vars[v.Key] = v.Value
+			}
+		}
+
+		name := prefix + item.Name
+		if item.Request == nil {
+			r.runItems(ctx, report, item.Items, name+"/", itemAuth)
+			continue
+		}
+		report.Results = append(report.Results, r.runItem(ctx, item, name, itemAuth))
+	}
+}
+
+func (r *Runner) runItem(ctx context.Context, item *Item, name string, auth *Auth) *Result {
+	result := &Result{Name: name, Method: strings.ToUpper(item.Request.Method)}
+	if result.Method == "" {
+		result.Method = "GET"
+	}
+	for _, e := range item.Prepare {
+		if e.JSONPath == "" && e.Header == "" {
+			r.Vars[e.Var] = r.expand(e.Value)
+		}
+	}
+
+	config, err := r.config(item.Request, auth)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.URL = config.URL
+
+	client := r.Client
+	if client == nil {
+		client = httpclient.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.SendRequestContext(ctx, config)
+	result.DurationMs = msSince(start)
+	if err != nil {
+		result.Error = err.Error()
+		glog.LogL(glog.WARN, "postman", name, err)
+		return result
+	}
+	result.StatusCode = resp.StatusCode
+
+	var body interface{}
+	bodyErr := json.Unmarshal(resp.Body, &body)
+	for _, a := range item.Tests {
+		result.Assertions = append(result.Assertions, check(a, resp, body, bodyErr))
+	}
+	for _, e := range item.Extract {
+		switch {
+		case e.JSONPath != "":
+			value, err := utils.JSONPath(body, e.JSONPath)
+			if err != nil {
+				glog.LogL(glog.WARN, "postman", name, "cannot set", e.Var, err)
+				continue
+			}
+			r.Vars[e.Var] = stringValue(value)
+		case e.Header != "":
+			r.Vars[e.Var] = resp.Header.Get(e.Header)
+		default:
+			r.Vars[e.Var] = r.expand(e.Value)
+		}
+	}
+	return result
+}
+
+func check(a Assertion, resp *httpclient.HttpResponse, body interface{}, bodyErr error) AssertionResult {
+	res := AssertionResult{Name: a.Name, Passed: true}
+	fail := func(format string, args ...interface{}) AssertionResult {
+		res.Passed = false
+		res.Message = fmt.Sprintf(format, args...)
+		return res
+	}
+
+	if a.Status != 0 && resp.StatusCode != a.Status {
+		return fail("expected status %d, got %d", a.Status, resp.StatusCode)
+	}
+	if a.Contains != "" && !strings.Contains(string(resp.Body), a.Contains) {
+		return fail("expected body to contain %q", a.Contains)
+	}
+	if a.JSONPath != "" {
+		if bodyErr != nil {
+			return fail("body is not JSON: %v", bodyErr)
+		}
+		value, err := utils.JSONPath(body, a.JSONPath)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if a.Equals != nil && !reflect.DeepEqual(value, a.Equals) {
+			return fail("expected %s to be %v, got %v", a.JSONPath, a.Equals, value)
+		}
+	}
+	return res
+}
+
+// config resolves the variables of req into an HttpConfig.
+func (r *Runner) config(req *Request, auth *Auth) (httpclient.HttpConfig, error) {
+	config := httpclient.HttpConfig{
+		Method:  strings.ToUpper(req.Method),
+		URL:     r.expand(req.URL.String()),
+		Headers: map[string]string{},
+		Timeout: r.Timeout,
+	}
+	if config.Method == "" {
+		config.Method = "GET"
+	}
+	if !strings.Contains(config.URL, "://") {
+		config.URL = "http://" + config.URL
+	}
+	if strings.Contains(config.URL, "{{") {
+		return config, fmt.Errorf("unresolved variable in %s", config.URL)
+	}
+
+	for _, h := range req.Header {
+		if h.active() {
+			config.Headers[h.Key] = r.expand(h.Value)
+		}
+	}
+
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	if err := r.applyAuth(&config, auth); err != nil {
+		return config, err
+	}
+
+	if body := req.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			config.Body = []byte(r.expand(body.Raw))
+			if body.Options.Raw.Language == "json" && !hasHeader(config.Headers, "Content-Type") {
+				config.Headers["Content-Type"] = "application/json"
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, v := range body.URLEncoded {
+				if v.active() {
+					form.Add(r.expand(v.Key), r.expand(v.Value))
+				}
+			}
+			config.Body = []byte(form.Encode())
+			config.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+		case "formdata":
+			data, contentType, err := r.formData(body.FormData)
+			if err != nil {
+				return config, err
+			}
+			config.Body = data
+			config.Headers["Content-Type"] = contentType
+		}
+	}
+	return config, nil
+}
+
+func (r *Runner) applyAuth(config *httpclient.HttpConfig, auth *Auth) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case "", "noauth":
+	case "basic":
+		credentials := r.expand(auth.param(auth.Basic, "username")) + ":" + r.expand(auth.param(auth.Basic, "password"))
+		config.Headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	case "bearer":
+		config.Headers["Authorization"] = "Bearer " + r.expand(auth.param(auth.Bearer, "token"))
+	case "apikey":
+		key, value := r.expand(auth.param(auth.APIKey, "key")), r.expand(auth.param(auth.APIKey, "value"))
+		if auth.param(auth.APIKey, "in") == "query" {
+			u, err := url.Parse(config.URL)
+			if err != nil {
+				return err
+			}
+			query := u.Query()
+			query.Set(key, value)
+			u.RawQuery = query.Encode()
+			config.URL = u.String()
+		} else {
+			config.Headers[key] = value
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %s", auth.Type)
+	}
+	return nil
+}
+
+func (r *Runner) formData(parts []FormPart) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		if part.Disabled {
+			continue
+		}
+		if part.Type == "file" {
+			data, err := os.ReadFile(r.expand(part.Src))
+			if err != nil {
+				return nil, "", err
+			}
+			fw, err := w.CreateFormFile(part.Key, filepath.Base(part.Src))
+			if err != nil {
+				return nil, "", err
+			}
+			fw.Write(data)
+			continue
+		}
+		w.WriteField(r.expand(part.Key), r.expand(part.Value))
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// expand resolves {{variables}}, including Postman's dynamic $guid,
+// $timestamp, $isoTimestamp and $randomInt.
+func (r *Runner) expand(s string) string {
+	return utils.ExpandVars(s, func(name string) (string, bool) {
+		switch name {
+		case "$guid", "$randomUUID":
+			return utils.UUID(), true
+		case "$timestamp":
+			return strconv.FormatInt(time.Now().Unix(), 10), true
+		case "$isoTimestamp":
+			return time.Now().UTC().Format(time.RFC3339), true
+		case "$randomInt":
+			return strconv.Itoa(utils.RandomInt(1000)), true
+		}
+		value, ok := r.Vars[name]
+		return value, ok
+	})
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringValue formats a JSON value as a variable, objects stay JSON.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
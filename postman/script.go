@@ -0,0 +1,158 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Assertion checks a response. Only the fields that are set are checked.
+type Assertion struct {
+	Name string
+	// Status is the expected status code.
+	Status int
+	// Contains must be part of the body.
+	Contains string
+	// JSONPath must exist in the JSON body and, when Equals is not nil,
+	// hold that value.
+	JSONPath string
+	Equals   interface{}
+}
+
+// Extraction sets a variable from a literal, a response header or a value
+// of the JSON body.
+type Extraction struct {
+	Var      string
+	Value    string
+	Header   string
+	JSONPath string
+}
+
+// Script is what ParseScript understood of a Postman script.
+type Script struct {
+	Tests   []Assertion
+	Extract []Extraction
+}
+
+var (
+	testNamePattern  = regexp.MustCompile(`pm\.test\(\s*(["'])(.+?)["']`)
+	statusPattern    = regexp.MustCompile(`pm\.response\.to\.have\.status\((\d+)\)|pm\.expect\(pm\.response\.code\)\.to\.(?:eql|equal|eq|be\.equal)\((\d+)\)`)
+	includePattern   = regexp.MustCompile(`pm\.expect\(pm\.response\.text\(\)\)\.to\.(?:include|contain|have\.string)\((["'])(.*?)["']\)`)
+	equalPattern     = regexp.MustCompile(`pm\.expect\(([\w$.\[\]'"()]+)\)\.to\.(?:eql|equal|eq|deep\.equal|be\.equal)\((.+?)\)\s*(?:;|\}|$)`)
+	existPattern     = regexp.MustCompile(`pm\.expect\(([\w$.\[\]'"()]+)\)\.to\.(?:exist|not\.be\.undefined|be\.ok)`)
+	setPattern       = regexp.MustCompile(`pm\.(?:environment|collectionVariables|globals|variables)\.set\(\s*(["'])(.+?)["']\s*,\s*(.+?)\)\s*;?\s*$`)
+	aliasPattern     = regexp.MustCompile(`(?:var|let|const)\s+(\w+)\s*=\s*pm\.response\.json\(\)`)
+	headerGetPattern = regexp.MustCompile(`^pm\.response\.headers\.get\(\s*["'](.+?)["']\s*\)$`)
+)
+
+// ParseScript reads the usual one-line forms of Postman test scripts:
+//
+//	pm.response.to.have.status(200)
+//	pm.expect(pm.response.code).to.eql(200)
+//	pm.expect(pm.response.text()).to.include("ok")
+//	pm.expect(pm.response.json().user.id).to.eql(7)
+//	pm.expect(jsonData.token).to.exist
+//	pm.environment.set("token", pm.response.json().token)
+//	pm.collectionVariables.set("id", "42")
+//	pm.environment.set("etag", pm.response.headers.get("ETag"))
+//
+// where jsonData is assigned from pm.response.json(). Assertions are named
+// after the pm.test around them. Anything else is ignored, JavaScript is
+// not executed.
+func ParseScript(lines []string) Script {
+	var script Script
+	aliases := map[string]bool{}
+	testName := ""
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := aliasPattern.FindStringSubmatch(line); m != nil {
+			aliases[m[1]] = true
+		}
+		if m := testNamePattern.FindStringSubmatch(line); m != nil {
+			testName = m[2]
+		}
+
+		name := testName
+		if name == "" {
+			name = strings.TrimSuffix(line, ";")
+		}
+
+		if m := statusPattern.FindStringSubmatch(line); m != nil {
+			code := m[1]
+			if code == "" {
+				code = m[2]
+			}
+			status, _ := strconv.Atoi(code)
+			script.Tests = append(script.Tests, Assertion{Name: name, Status: status})
+		} else if m := includePattern.FindStringSubmatch(line); m != nil {
+			script.Tests = append(script.Tests, Assertion{Name: name, Contains: m[2]})
+		} else if m := equalPattern.FindStringSubmatch(line); m != nil {
+			if path, ok := jsonPathOf(m[1], aliases); ok {
+				script.Tests = append(script.Tests, Assertion{Name: name, JSONPath: path, Equals: literal(m[2])})
+			}
+		} else if m := existPattern.FindStringSubmatch(line); m != nil {
+			if path, ok := jsonPathOf(m[1], aliases); ok {
+				script.Tests = append(script.Tests, Assertion{Name: name, JSONPath: path})
+			}
+		} else if m := setPattern.FindStringSubmatch(line); m != nil {
+			value := strings.TrimSpace(m[3])
+			extraction := Extraction{Var: m[2]}
+			if path, ok := jsonPathOf(value, aliases); ok {
+				extraction.JSONPath = path
+			} else if h := headerGetPattern.FindStringSubmatch(value); h != nil {
+				extraction.Header = h[1]
+			} else if v := literal(value); v != nil {
+				extraction.Value = fmt.Sprint(v)
+			} else {
+				continue
+			}
+			script.Extract = append(script.Extract, extraction)
+		}
+
+		// A line closing the pm.test callback ends the test.
+		if strings.HasPrefix(line, "})") || strings.HasSuffix(line, "});") {
+			testName = ""
+		}
+	}
+	return script
+}
+
+// jsonPathOf turns pm.response.json().a.b, or alias.a.b, into $.a.b.
+func jsonPathOf(expr string, aliases map[string]bool) (string, bool) {
+	rest := ""
+	switch {
+	case strings.HasPrefix(expr, "pm.response.json()"):
+		rest = strings.TrimPrefix(expr, "pm.response.json()")
+	default:
+		name := expr
+		if i := strings.IndexAny(expr, ".["); i >= 0 {
+			name, rest = expr[:i], expr[i:]
+		}
+		if !aliases[name] {
+			return "", false
+		}
+	}
+	if strings.Contains(rest, "(") {
+		return "", false
+	}
+	return "$" + rest, true
+}
+
+// literal parses a JavaScript literal, returning nil when it is not one.
+func literal(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = strconv.Quote(s[1 : len(s)-1])
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"github.com/mgolfam/gogutils/dto"
+)
+
+//go:generate go run github.com/mgolfam/gogutils/cmd/httpgen -type IpAPI
+
+// IpAPI is the ip-api.com JSON API, use NewIpAPI for an implementation.
+type IpAPI interface {
+	// Lookup returns the location of ip.
+	//
+	// @GET /json/{ip}
+	Lookup(ctx context.Context, ip string) (*dto.IpInfo, error)
+
+	// Batch looks up to 100 addresses in one call.
+	//
+	// @POST /batch
+	// @body ips
+	Batch(ctx context.Context, ips []string) ([]dto.IpInfo, error)
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/mgolfam/gogutils/httpclient"
@@ -12,24 +11,29 @@ import (
 	"github.com/mgolfam/gogutils/dto"
 )
 
+// ipApiClient keeps GetIpInfo within the free tier of ip-api.com, which
+// allows 45 requests per minute.
+var ipApiClient = newIpApiClient()
+
+func newIpApiClient() *httpclient.Client {
+	limiter := httpclient.NewRateLimiter()
+	limiter.Set("ip-api.com", httpclient.RateLimit{Requests: 45, Per: time.Minute, Wait: true})
+	return &httpclient.Client{Limiter: limiter}
+}
+
 func GetIpInfo(ip string) *dto.IpInfo {
 	if ip == "" || ip == "127.0.0" || ip == "0.0.0.0" {
 		return nil
 	}
 
 	// Make a request to ip-api.com
-	url := fmt.Sprintf("http://ip-api.com/json/%s", ip)
-
-	conf := httpclient.HttpConfig{
-		Method:        "GET",
-		URL:           url,
-		Cache:         true,
-		CacheTtl:      24 * 30 * 6 * 3600, // 6 month
-		RetrieveCache: true,
-		Timeout:       time.Second * 10,
-	}
-
-	response, err := httpclient.SendRequest(conf)
+	response, err := httpclient.NewRequest("GET", "http://ip-api.com").
+		Path("/json/{ip}").
+		Param("ip", ip).
+		Cache(24 * 30 * 6 * time.Hour). // 6 month
+		Timeout(time.Second * 10).
+		Client(ipApiClient).
+		Send()
 	if err != nil {
 		glog.LogL(glog.ERROR, "Error getting IP info:", err)
 		return nil
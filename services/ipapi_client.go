@@ -0,0 +1,42 @@
+// Code generated by httpgen -type IpAPI; DO NOT EDIT.
+
+package services
+
+import (
+	"context"
+
+	"github.com/mgolfam/gogutils/dto"
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+type ipAPIClient struct {
+	api *httpclient.APIClient
+}
+
+// NewIpAPI returns an implementation of IpAPI sending its
+// requests through api.
+func NewIpAPI(api *httpclient.APIClient) IpAPI {
+	return &ipAPIClient{api: api}
+}
+
+func (c *ipAPIClient) Lookup(ctx context.Context, ip string) (*dto.IpInfo, error) {
+	req := c.api.NewRequest("GET", "/json/{ip}")
+	req.Param("ip", ip)
+
+	var out *dto.IpInfo
+	if err := c.api.Do(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ipAPIClient) Batch(ctx context.Context, ips []string) ([]dto.IpInfo, error) {
+	req := c.api.NewRequest("POST", "/batch")
+	req.JSON(ips)
+
+	var out []dto.IpInfo
+	if err := c.api.Do(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package soap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+// Client calls the operations of one SOAP endpoint.
+type Client struct {
+	URL     string
+	Version Version
+	// HTTP sends the calls, httpclient.DefaultClient when nil.
+	HTTP *httpclient.Client
+	// Header blocks are sent with every call, before the call's own.
+	Header []interface{}
+	// Headers are extra HTTP headers of every call.
+	Headers map[string]string
+	Timeout time.Duration
+	Auth    httpclient.AuthProvider
+	TLS     *httpclient.TLSConfig
+	// Log adds the response envelope to the call log.
+	Log bool
+	// Security marshals the envelopes in place of Envelope.Marshal, to add
+	// WS-Security headers and signatures, see package wsse.
+	Security Security
+}
+
+// Security secures outgoing envelopes.
+type Security interface {
+	Secure(env *Envelope) ([]byte, error)
+}
+
+func NewClient(url string, version Version) *Client {
+	return &Client{URL: url, Version: version, Timeout: 30 * time.Second}
+}
+
+// Call sends body as action and decodes the response body into out, which
+// may be nil. Faults are returned as *Fault, other non 2xx responses as
+// *httpclient.StatusError.
+func (c *Client) Call(ctx context.Context, action string, body, out interface{}) error {
+	_, err := c.Send(ctx, action, &Envelope{Body: body}, nil, out)
+	return err
+}
+
+// Send is Call with header blocks and a response header. The client's
+// Version and Header blocks are applied to a copy of env, which may be
+// reused.
+func (c *Client) Send(ctx context.Context, action string, env *Envelope, header, out interface{}) (*httpclient.SoapResponse, error) {
+	e := *env
+	env = &e
+	env.Version = c.Version
+	if len(c.Header) > 0 {
+		env.Header = append(append([]interface{}{}, c.Header...), env.Header...)
+	}
+	var data []byte
+	var err error
+	if c.Security != nil {
+		data, err = c.Security.Secure(env)
+	} else {
+		data, err = env.Marshal()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	headers := c.Version.Headers(action)
+	for key, value := range c.Headers {
+		headers[key] = value
+	}
+	client := c.HTTP
+	if client == nil {
+		client = httpclient.DefaultClient
+	}
+	resp, err := client.SoapCallContext(ctx, httpclient.SoapConfig{
+		URL:     c.URL,
+		Headers: headers,
+		Body:    string(data),
+		Timeout: c.Timeout,
+		LogSoap: c.Log,
+		Auth:    c.Auth,
+		TLS:     c.TLS,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = UnmarshalEnvelope([]byte(resp.Body), header, out)
+	var fault *Fault
+	if errors.As(err, &fault) {
+		fault.StatusCode = resp.StatusCode
+		return resp, fault
+	}
+	if !resp.IsSuccess() {
+		return resp, &httpclient.StatusError{Method: "POST", URL: c.URL, StatusCode: resp.StatusCode, Body: []byte(resp.Body)}
+	}
+	return resp, err
+}
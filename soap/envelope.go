@@ -0,0 +1,204 @@
+// Package soap builds SOAP 1.1 and 1.2 envelopes from Go values, decodes
+// responses into typed structs and turns faults into errors. Bodies and
+// header blocks are marshaled with encoding/xml, so their namespaces come
+// from the XMLName of the structs:
+//
+//	type GetUser struct {
+//		XMLName xml.Name `xml:"urn:users GetUser"`
+//		ID      int      `xml:"id"`
+//	}
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+)
+
+// Version is the SOAP version of a message.
+type Version int
+
+const (
+	V11 Version = iota
+	V12
+)
+
+const (
+	Namespace11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	Namespace12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+func (v Version) String() string {
+	if v == V12 {
+		return "SOAP 1.2"
+	}
+	return "SOAP 1.1"
+}
+
+// Namespace returns the envelope namespace of v.
+func (v Version) Namespace() string {
+	if v == V12 {
+		return Namespace12
+	}
+	return Namespace11
+}
+
+// Headers returns the HTTP headers of a call to action. SOAP 1.1 sends the
+// action in the SOAPAction header, SOAP 1.2 as a Content-Type parameter.
+func (v Version) Headers(action string) map[string]string {
+	if v == V12 {
+		params := map[string]string{"charset": "utf-8"}
+		if action != "" {
+			params["action"] = action
+		}
+		return map[string]string{"Content-Type": mime.FormatMediaType("application/soap+xml", params)}
+	}
+	return map[string]string{
+		"Content-Type": "text/xml; charset=utf-8",
+		"SOAPAction":   `"` + action + `"`,
+	}
+}
+
+// Raw is XML written into an envelope as is, for bodies and header blocks
+// that are not worth a struct.
+type Raw string
+
+// Envelope is an outgoing message.
+type Envelope struct {
+	Version Version
+	// Header holds the header blocks, structs or Raw values.
+	Header []interface{}
+	// Body is the payload, a struct or Raw, nil for an empty body.
+	Body interface{}
+	// BodyAttrs are written on the Body element, with Name.Space as the
+	// prefix, such as the wsu:Id a signature refers to. Their namespaces
+	// must be declared by the attributes as well.
+	BodyAttrs []xml.Attr
+}
+
+// Marshal returns the XML document of the envelope.
+func (e *Envelope) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<soap:Envelope xmlns:soap="%s">`, e.Version.Namespace())
+	if len(e.Header) > 0 {
+		buf.WriteString("<soap:Header>")
+		for _, block := range e.Header {
+			if err := marshalPart(&buf, block); err != nil {
+				return nil, fmt.Errorf("soap: header: %w", err)
+			}
+		}
+		buf.WriteString("</soap:Header>")
+	}
+	buf.WriteString("<soap:Body")
+	for _, attr := range e.BodyAttrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		buf.WriteString(" " + name + `="`)
+		xml.EscapeText(&buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(">")
+	if e.Body != nil {
+		if err := marshalPart(&buf, e.Body); err != nil {
+			return nil, fmt.Errorf("soap: body: %w", err)
+		}
+	}
+	buf.WriteString("</soap:Body></soap:Envelope>")
+	return buf.Bytes(), nil
+}
+
+func marshalPart(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case Raw:
+		buf.WriteString(string(v))
+		return nil
+	case []byte:
+		buf.Write(v)
+		return nil
+	}
+	return xml.NewEncoder(buf).Encode(v)
+}
+
+// Unmarshal decodes the body of a response envelope into body, see
+// UnmarshalEnvelope.
+func Unmarshal(data []byte, body interface{}) error {
+	return UnmarshalEnvelope(data, nil, body)
+}
+
+// UnmarshalEnvelope decodes the Header element of a response envelope into
+// header and the first element of its Body into body; either may be nil.
+// A Fault body is returned as a *Fault error. A *Raw body receives the
+// inner XML of the first Body element.
+func UnmarshalEnvelope(data []byte, header, body interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	version := V11
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return errors.New("soap: no Body in the envelope")
+		}
+		if err != nil {
+			return fmt.Errorf("soap: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				if t.Name.Local != "Envelope" {
+					return fmt.Errorf("soap: expected Envelope, got %s", t.Name.Local)
+				}
+				if t.Name.Space == Namespace12 {
+					version = V12
+				}
+			case depth == 2 && t.Name.Local == "Header":
+				if header != nil {
+					if err := decoder.DecodeElement(header, &t); err != nil {
+						return fmt.Errorf("soap: header: %w", err)
+					}
+				} else if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("soap: %w", err)
+				}
+				depth--
+			case depth == 2 && t.Name.Local != "Body":
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("soap: %w", err)
+				}
+				depth--
+			case depth == 3:
+				if t.Name.Local == "Fault" && (t.Name.Space == Namespace11 || t.Name.Space == Namespace12) {
+					return decodeFault(decoder, &t, version)
+				}
+				if body == nil {
+					return nil
+				}
+				if raw, ok := body.(*Raw); ok {
+					var inner struct {
+						XML string `xml:",innerxml"`
+					}
+					err := decoder.DecodeElement(&inner, &t)
+					*raw = Raw(inner.XML)
+					return err
+				}
+				if err := decoder.DecodeElement(body, &t); err != nil {
+					return fmt.Errorf("soap: body: %w", err)
+				}
+				return nil
+			}
+		case xml.EndElement:
+			depth--
+			// An empty Body.
+			if depth == 1 && t.Name.Local == "Body" {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Fault is a SOAP fault returned as an error. The SOAP 1.1 faultcode,
+// faultstring and faultactor map to Code, Reason and Role.
+type Fault struct {
+	Version Version
+	// Code is the fault code as sent, such as "soap:Server" or
+	// "env:Receiver". Subcode is the first SOAP 1.2 subcode.
+	Code    string
+	Subcode string
+	Reason  string
+	Role    string
+	Node    string
+	// Detail is the inner XML of the detail element, see DetailAs.
+	Detail string
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+}
+
+func (f *Fault) Error() string {
+	code := f.Code
+	if f.Subcode != "" {
+		code += "/" + f.Subcode
+	}
+	return fmt.Sprintf("soap fault %s: %s", code, f.Reason)
+}
+
+// LocalCode returns Code without its namespace prefix, such as "Server".
+func (f *Fault) LocalCode() string {
+	return f.Code[strings.LastIndex(f.Code, ":")+1:]
+}
+
+// DetailAs unmarshals the first element of the detail into v. Namespace
+// prefixes declared outside the detail are not resolved, so v should name
+// its elements without a namespace.
+func (f *Fault) DetailAs(v interface{}) error {
+	return xml.Unmarshal([]byte(f.Detail), v)
+}
+
+// fault holds the elements of both versions, element names are matched
+// regardless of their namespace.
+type fault struct {
+	FaultCode   string `xml:"faultcode"`
+	FaultString string `xml:"faultstring"`
+	FaultActor  string `xml:"faultactor"`
+	Code        struct {
+		Value   string `xml:"Value"`
+		Subcode struct {
+			Value string `xml:"Value"`
+		} `xml:"Subcode"`
+	} `xml:"Code"`
+	Reason struct {
+		Text []string `xml:"Text"`
+	} `xml:"Reason"`
+	Node     string   `xml:"Node"`
+	Role     string   `xml:"Role"`
+	Detail   innerXML `xml:"detail"`
+	Detail12 innerXML `xml:"Detail"`
+}
+
+type innerXML struct {
+	XML string `xml:",innerxml"`
+}
+
+func decodeFault(decoder *xml.Decoder, start *xml.StartElement, version Version) error {
+	var f fault
+	if err := decoder.DecodeElement(&f, start); err != nil {
+		return fmt.Errorf("soap: fault: %w", err)
+	}
+	if version == V12 {
+		reason := ""
+		if len(f.Reason.Text) > 0 {
+			reason = f.Reason.Text[0]
+		}
+		return &Fault{
+			Version: V12,
+			Code:    strings.TrimSpace(f.Code.Value),
+			Subcode: strings.TrimSpace(f.Code.Subcode.Value),
+			Reason:  strings.TrimSpace(reason),
+			Role:    strings.TrimSpace(f.Role),
+			Node:    strings.TrimSpace(f.Node),
+			Detail:  strings.TrimSpace(f.Detail12.XML),
+		}
+	}
+	return &Fault{
+		Version: V11,
+		Code:    strings.TrimSpace(f.FaultCode),
+		Reason:  strings.TrimSpace(f.FaultString),
+		Role:    strings.TrimSpace(f.FaultActor),
+		Detail:  strings.TrimSpace(f.Detail.XML),
+	}
+}
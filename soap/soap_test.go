@@ -0,0 +1,160 @@
+package soap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+type getUser struct {
+	XMLName xml.Name `xml:"urn:users GetUser"`
+	ID      int      `xml:"id"`
+}
+
+type getUserResponse struct {
+	XMLName xml.Name `xml:"urn:users GetUserResponse"`
+	Name    string   `xml:"user>name"`
+}
+
+type session struct {
+	XMLName xml.Name `xml:"urn:auth Session"`
+	Token   string   `xml:"token"`
+}
+
+type responseHeader struct {
+	RequestID string `xml:"urn:trace RequestID"`
+}
+
+func TestEnvelopeMarshal(t *testing.T) {
+	env := &Envelope{Version: V12, Header: []interface{}{session{Token: "t0k"}, Raw(`<x:Trace xmlns:x="urn:x">1</x:Trace>`)}, Body: getUser{ID: 7}}
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := xml.Header + `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Header>` +
+		`<Session xmlns="urn:auth"><token>t0k</token></Session><x:Trace xmlns:x="urn:x">1</x:Trace></soap:Header>` +
+		`<soap:Body><GetUser xmlns="urn:users"><id>7</id></GetUser></soap:Body></soap:Envelope>`
+	if string(data) != expected {
+		t.Errorf("Expected %s\nGot: %s", expected, data)
+	}
+
+	tests := []struct {
+		version  Version
+		expected map[string]string
+	}{
+		{version: V11, expected: map[string]string{"Content-Type": "text/xml; charset=utf-8", "SOAPAction": `"urn:GetUser"`}},
+		{version: V12, expected: map[string]string{"Content-Type": `application/soap+xml; action="urn:GetUser"; charset=utf-8`}},
+	}
+	for _, test := range tests {
+		headers := test.version.Headers("urn:GetUser")
+		if len(headers) != len(test.expected) {
+			t.Errorf("Expected %v, Got: %v", test.expected, headers)
+		}
+		for key, value := range test.expected {
+			if headers[key] != value {
+				t.Errorf("%s: expected %s %q, Got: %q", test.version, key, value, headers[key])
+			}
+		}
+	}
+}
+
+const (
+	response11 = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:u="urn:users" xmlns:t="urn:trace">
+  <s:Header><t:RequestID>r-1</t:RequestID></s:Header>
+  <s:Body><u:GetUserResponse><u:user><u:name>bob</u:name></u:user></u:GetUserResponse></s:Body>
+</s:Envelope>`
+	fault11 = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+  <faultcode>s:Client</faultcode><faultstring>unknown user</faultstring>
+  <detail><error><id>7</id></error></detail>
+</s:Fault></s:Body></s:Envelope>`
+	fault12 = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>
+  <env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>m:BadID</env:Value></env:Subcode></env:Code>
+  <env:Reason><env:Text xml:lang="en">bad id</env:Text></env:Reason>
+  <env:Detail><error><id>x</id></error></env:Detail>
+</env:Fault></env:Body></env:Envelope>`
+)
+
+func TestClientCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		contentType := r.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "application/soap+xml") && strings.Contains(contentType, `action="urn:GetUser"`):
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fault12))
+		case r.Header.Get("SOAPAction") != `"urn:GetUser"` || strings.Count(string(data), "<token>t0k</token>") != 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad request"))
+		case strings.Contains(string(data), "<id>7</id>"):
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fault11))
+		default:
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(response11))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, V11)
+	client.Header = []interface{}{session{Token: "t0k"}}
+
+	var out getUserResponse
+	var header responseHeader
+	// The envelope is left alone, so it can be sent again.
+	env := &Envelope{Body: getUser{ID: 1}}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(context.Background(), "urn:GetUser", env, &header, &out); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(env.Header) != 0 {
+		t.Errorf("Expected the envelope unchanged, Got: %+v", env)
+	}
+	if out.Name != "bob" || header.RequestID != "r-1" {
+		t.Errorf("Unexpected response: %+v, header %+v", out, header)
+	}
+
+	err := client.Call(context.Background(), "urn:GetUser", getUser{ID: 7}, &out)
+	var fault *Fault
+	if !errors.As(err, &fault) {
+		t.Fatalf("Expected a fault, Got: %v", err)
+	}
+	var detail struct {
+		ID int `xml:"id"`
+	}
+	if fault.LocalCode() != "Client" || fault.Reason != "unknown user" || fault.StatusCode != 500 || fault.DetailAs(&detail) != nil || detail.ID != 7 {
+		t.Errorf("Unexpected fault: %+v, detail %+v", fault, detail)
+	}
+
+	err = client.Call(context.Background(), "urn:Other", getUser{ID: 1}, nil)
+	var statusErr *httpclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a status error, Got: %v", err)
+	}
+
+	client12 := NewClient(server.URL, V12)
+	err = client12.Call(context.Background(), "urn:GetUser", getUser{ID: 1}, &out)
+	if !errors.As(err, &fault) || fault.Version != V12 || fault.Code != "env:Sender" || fault.Subcode != "m:BadID" || fault.Reason != "bad id" ||
+		err.Error() != "soap fault env:Sender/m:BadID: bad id" || !strings.Contains(fault.Detail, "<id>x</id>") {
+		t.Errorf("Unexpected SOAP 1.2 fault: %+v", err)
+	}
+}
+
+func TestUnmarshalRaw(t *testing.T) {
+	var raw Raw
+	if err := Unmarshal([]byte(response11), &raw); err != nil || !strings.Contains(string(raw), "<u:name>bob</u:name>") {
+		t.Errorf("Unexpected raw body %q: %v", raw, err)
+	}
+	if err := Unmarshal([]byte(`<html></html>`), nil); err == nil {
+		t.Errorf("Expected an error for a document that is not an envelope")
+	}
+}
@@ -0,0 +1,159 @@
+package wsdl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mgolfam/gogutils/soap"
+)
+
+// Operation is a SOAP operation as offered by one port of a service.
+type Operation struct {
+	Service string
+	Port    string
+	Name    string
+	Doc     string
+	Version soap.Version
+	// Action is the SOAPAction, often empty for SOAP 1.2.
+	Action  string
+	Address string
+	// Style is "document" or "rpc".
+	Style string
+	// Namespace is the namespace of the rpc wrapper elements.
+	Namespace string
+	Input     *Message
+	Output    *Message
+	Faults    []*Message
+}
+
+// Wrapper returns the names of the rpc style wrapper elements of the input
+// and the output, the operation name and the name with "Response".
+func (op *Operation) Wrapper() (input, output string) {
+	return op.Name, op.Name + "Response"
+}
+
+// Operations lists the SOAP operations of every port of every service, in
+// document order.
+func (d *Definitions) Operations() ([]*Operation, error) {
+	var operations []*Operation
+	for _, service := range d.Services {
+		for _, port := range service.Ports {
+			ops, err := d.PortOperations(service, port)
+			if err != nil {
+				return nil, err
+			}
+			operations = append(operations, ops...)
+		}
+	}
+	return operations, nil
+}
+
+// PortOperations lists the operations of one port. Ports without a SOAP
+// address, such as HTTP bindings, have none.
+func (d *Definitions) PortOperations(service *Service, port *Port) ([]*Operation, error) {
+	version, address := soap.V11, port.Address
+	if address == nil {
+		version, address = soap.V12, port.Address12
+	}
+	if address == nil {
+		return nil, nil
+	}
+	binding := d.Binding(port.Binding)
+	if binding == nil {
+		return nil, fmt.Errorf("wsdl: port %s: binding %s not found", port.Name, port.Binding)
+	}
+	portType := d.PortType(binding.Type)
+	if portType == nil {
+		return nil, fmt.Errorf("wsdl: binding %s: port type %s not found", binding.Name, binding.Type)
+	}
+	style := "document"
+	for _, b := range []*SOAPBinding{binding.SOAP, binding.SOAP12} {
+		if b != nil && b.Style != "" {
+			style = b.Style
+		}
+	}
+
+	var operations []*Operation
+	for _, bop := range binding.Operations {
+		abstract := portType.Operation(bop.Name)
+		if abstract == nil {
+			return nil, fmt.Errorf("wsdl: binding %s: operation %s not in port type %s", binding.Name, bop.Name, portType.Name)
+		}
+		op := &Operation{
+			Service: service.Name,
+			Port:    port.Name,
+			Name:    bop.Name,
+			Doc:     abstract.Doc,
+			Version: version,
+			Address: address.Location,
+			Style:   style,
+		}
+		for _, soapOp := range []*SOAPOperation{bop.SOAP, bop.SOAP12} {
+			if soapOp == nil {
+				continue
+			}
+			op.Action = soapOp.Action
+			if soapOp.Style != "" {
+				op.Style = soapOp.Style
+			}
+		}
+		if bop.Input != nil {
+			for _, body := range []*SOAPBody{bop.Input.SOAP, bop.Input.SOAP12} {
+				if body != nil && body.Namespace != "" {
+					op.Namespace = body.Namespace
+				}
+			}
+		}
+		if op.Namespace == "" {
+			op.Namespace = d.TargetNamespace
+		}
+
+		var err error
+		if op.Input, err = d.messageOf(abstract.Input); err != nil {
+			return nil, err
+		}
+		if op.Output, err = d.messageOf(abstract.Output); err != nil {
+			return nil, err
+		}
+		for _, fault := range abstract.Faults {
+			m, err := d.messageOf(fault)
+			if err != nil {
+				return nil, err
+			}
+			op.Faults = append(op.Faults, m)
+		}
+		operations = append(operations, op)
+	}
+	return operations, nil
+}
+
+func (d *Definitions) messageOf(ref *MessageRef) (*Message, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	m := d.Message(ref.Message)
+	if m == nil {
+		return nil, fmt.Errorf("wsdl: message %s not found", ref.Message)
+	}
+	return m, nil
+}
+
+// Operation returns the operation named name, preferring SOAP 1.1 ports
+// when a service offers both versions.
+func (d *Definitions) Operation(name string) (*Operation, error) {
+	operations, err := d.Operations()
+	if err != nil {
+		return nil, err
+	}
+	var found []*Operation
+	for _, op := range operations {
+		if op.Name == name {
+			found = append(found, op)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("wsdl: operation %s not found", name)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Version < found[j].Version })
+	return found[0], nil
+}
@@ -0,0 +1,131 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/mgolfam/gogutils/soap"
+)
+
+// maxSampleDepth stops recursive types.
+const maxSampleDepth = 8
+
+// SampleEnvelope returns a request envelope for op with every element
+// present once and placeholder values by type, a starting point for
+// hand-written calls.
+func (d *Definitions) SampleEnvelope(op *Operation) ([]byte, error) {
+	body, err := d.SampleBody(op)
+	if err != nil {
+		return nil, err
+	}
+	env := soap.Envelope{Version: op.Version, Body: soap.Raw(body)}
+	return env.Marshal()
+}
+
+// SampleBody returns the content of the request Body of op.
+func (d *Definitions) SampleBody(op *Operation) ([]byte, error) {
+	var buf bytes.Buffer
+	if op.Input == nil {
+		return nil, nil
+	}
+	if op.Style == "rpc" {
+		input, _ := op.Wrapper()
+		fmt.Fprintf(&buf, `<%s xmlns="%s">`, input, escape(op.Namespace))
+		for _, part := range op.Input.Parts {
+			fmt.Fprintf(&buf, `<%s xmlns="">`, part.Name)
+			if t := d.ComplexType(part.Type); t != nil {
+				d.sampleChildren(&buf, t, "", 1)
+			} else {
+				buf.WriteString(escape(d.sampleValue(d.BuiltinType(part.Type), d.SimpleType(part.Type))))
+			}
+			fmt.Fprintf(&buf, `</%s>`, part.Name)
+		}
+		fmt.Fprintf(&buf, `</%s>`, input)
+		return buf.Bytes(), nil
+	}
+
+	for _, part := range op.Input.Parts {
+		if part.Element == "" {
+			return nil, fmt.Errorf("wsdl: %s: document part %s has no element", op.Name, part.Name)
+		}
+		e := d.Element(part.Element)
+		if e == nil {
+			return nil, fmt.Errorf("wsdl: %s: element %s not found", op.Name, part.Element)
+		}
+		d.sampleElement(&buf, e, "\x00", 0)
+	}
+	return buf.Bytes(), nil
+}
+
+// sampleElement writes e. defaultNS is the default namespace in scope, a
+// namespace declaration is only written when the element's differs.
+func (d *Definitions) sampleElement(buf *bytes.Buffer, e *Element, defaultNS string, depth int) {
+	e = d.Deref(e)
+	ns := e.Namespace()
+	buf.WriteString("<" + e.Name)
+	if ns != defaultNS {
+		fmt.Fprintf(buf, ` xmlns="%s"`, escape(ns))
+	}
+	buf.WriteString(">")
+	if t := d.TypeOf(e); t != nil {
+		if depth < maxSampleDepth {
+			d.sampleChildren(buf, t, ns, depth+1)
+		}
+	} else {
+		simple := e.SimpleType
+		if simple == nil && e.Type != "" {
+			simple = d.SimpleType(e.Type)
+		}
+		buf.WriteString(escape(d.sampleValue(d.builtinOf(e), simple)))
+	}
+	buf.WriteString("</" + e.Name + ">")
+}
+
+func (d *Definitions) sampleChildren(buf *bytes.Buffer, t *ComplexType, defaultNS string, depth int) {
+	for _, child := range d.Children(t) {
+		d.sampleElement(buf, child, defaultNS, depth)
+	}
+}
+
+// builtinOf returns the built in type of an element with simple content,
+// anyType when it declares none.
+func (d *Definitions) builtinOf(e *Element) string {
+	if e.SimpleType != nil && e.SimpleType.Restriction != nil {
+		return d.BuiltinType(e.SimpleType.Restriction.Base)
+	}
+	if e.Type == "" {
+		return "anyType"
+	}
+	return d.BuiltinType(e.Type)
+}
+
+func (d *Definitions) sampleValue(builtin string, simple *SimpleType) string {
+	if simple != nil && simple.Restriction != nil && len(simple.Restriction.Enumerations) > 0 {
+		return simple.Restriction.Enumerations[0].Value
+	}
+	switch builtin {
+	case "boolean":
+		return "false"
+	case "int", "integer", "long", "short", "byte", "nonNegativeInteger", "positiveInteger",
+		"unsignedInt", "unsignedLong", "unsignedShort", "unsignedByte":
+		return "0"
+	case "decimal", "float", "double":
+		return "0.0"
+	case "dateTime":
+		return "2006-01-02T15:04:05Z"
+	case "date":
+		return "2006-01-02"
+	case "time":
+		return "15:04:05"
+	case "base64Binary":
+		return ""
+	}
+	return "?"
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
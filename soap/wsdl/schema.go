@@ -0,0 +1,262 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// Schema is the subset of XML Schema used to describe SOAP messages:
+// elements, complex types made of sequences, alls and choices, extensions
+// of other complex types and simple type restrictions. Attributes are not
+// modeled.
+type Schema struct {
+	TargetNamespace    string         `xml:"targetNamespace,attr"`
+	ElementFormDefault string         `xml:"elementFormDefault,attr"`
+	Elements           []*Element     `xml:"http://www.w3.org/2001/XMLSchema element"`
+	ComplexTypes       []*ComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+	SimpleTypes        []*SimpleType  `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+}
+
+type Element struct {
+	Name        string       `xml:"name,attr"`
+	Type        string       `xml:"type,attr"`
+	Ref         string       `xml:"ref,attr"`
+	Form        string       `xml:"form,attr"`
+	MinOccurs   string       `xml:"minOccurs,attr"`
+	MaxOccurs   string       `xml:"maxOccurs,attr"`
+	Nillable    bool         `xml:"nillable,attr"`
+	ComplexType *ComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+	SimpleType  *SimpleType  `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+
+	schema *Schema
+	global bool
+}
+
+type ComplexType struct {
+	Name           string          `xml:"name,attr"`
+	Sequence       *Group          `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	All            *Group          `xml:"http://www.w3.org/2001/XMLSchema all"`
+	Choice         *Group          `xml:"http://www.w3.org/2001/XMLSchema choice"`
+	ComplexContent *ComplexContent `xml:"http://www.w3.org/2001/XMLSchema complexContent"`
+
+	schema *Schema
+}
+
+// Group is a sequence, all or choice, which may nest further groups.
+type Group struct {
+	Elements  []*Element `xml:"http://www.w3.org/2001/XMLSchema element"`
+	Sequences []*Group   `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	Choices   []*Group   `xml:"http://www.w3.org/2001/XMLSchema choice"`
+}
+
+type ComplexContent struct {
+	Extension *Extension `xml:"http://www.w3.org/2001/XMLSchema extension"`
+}
+
+type Extension struct {
+	Base     string `xml:"base,attr"`
+	Sequence *Group `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+}
+
+type SimpleType struct {
+	Name        string       `xml:"name,attr"`
+	Restriction *Restriction `xml:"http://www.w3.org/2001/XMLSchema restriction"`
+}
+
+type Restriction struct {
+	Base         string  `xml:"base,attr"`
+	Enumerations []Facet `xml:"http://www.w3.org/2001/XMLSchema enumeration"`
+}
+
+type Facet struct {
+	Value string `xml:"value,attr"`
+}
+
+// link points every element and complex type at its schema, which holds
+// the namespace and the element form.
+func (s *Schema) link() {
+	for _, e := range s.Elements {
+		e.global = true
+		e.link(s)
+	}
+	for _, t := range s.ComplexTypes {
+		t.link(s)
+	}
+}
+
+func (e *Element) link(s *Schema) {
+	e.schema = s
+	if e.ComplexType != nil {
+		e.ComplexType.link(s)
+	}
+}
+
+func (t *ComplexType) link(s *Schema) {
+	t.schema = s
+	for _, g := range []*Group{t.Sequence, t.All, t.Choice, t.extension()} {
+		g.link(s)
+	}
+}
+
+func (g *Group) link(s *Schema) {
+	if g == nil {
+		return
+	}
+	for _, e := range g.Elements {
+		e.link(s)
+	}
+	for _, sub := range append(append([]*Group{}, g.Sequences...), g.Choices...) {
+		sub.link(s)
+	}
+}
+
+func (t *ComplexType) extension() *Group {
+	if t.ComplexContent == nil || t.ComplexContent.Extension == nil {
+		return nil
+	}
+	return t.ComplexContent.Extension.Sequence
+}
+
+func (g *Group) elements() []*Element {
+	if g == nil {
+		return nil
+	}
+	elements := append([]*Element{}, g.Elements...)
+	for _, sub := range append(append([]*Group{}, g.Sequences...), g.Choices...) {
+		elements = append(elements, sub.elements()...)
+	}
+	return elements
+}
+
+// Namespace returns the namespace of the element's tag: the target
+// namespace for global and qualified elements, none for unqualified ones.
+func (e *Element) Namespace() string {
+	if e.schema == nil {
+		return ""
+	}
+	if e.global || e.Form == "qualified" || (e.Form == "" && e.schema.ElementFormDefault == "qualified") {
+		return e.schema.TargetNamespace
+	}
+	return ""
+}
+
+// Optional reports whether minOccurs is 0.
+func (e *Element) Optional() bool {
+	return e.MinOccurs == "0"
+}
+
+// Repeated reports whether the element may occur more than once.
+func (e *Element) Repeated() bool {
+	if e.MaxOccurs == "unbounded" {
+		return true
+	}
+	n, err := strconv.Atoi(e.MaxOccurs)
+	return err == nil && n > 1
+}
+
+// Element returns the global element named qname.
+func (d *Definitions) Element(qname string) *Element {
+	return d.element(d.resolve(qname))
+}
+
+func (d *Definitions) element(name xml.Name) *Element {
+	for _, s := range d.Types.Schemas {
+		if s.TargetNamespace != name.Space {
+			continue
+		}
+		for _, e := range s.Elements {
+			if e.Name == name.Local {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// Deref returns the global element e refers to, e itself without a ref.
+func (d *Definitions) Deref(e *Element) *Element {
+	if e.Ref == "" {
+		return e
+	}
+	if global := d.Element(e.Ref); global != nil {
+		return global
+	}
+	return e
+}
+
+// ComplexType returns the named complex type, nil for simple and built in
+// types.
+func (d *Definitions) ComplexType(qname string) *ComplexType {
+	name := d.resolve(qname)
+	for _, s := range d.Types.Schemas {
+		if s.TargetNamespace != name.Space {
+			continue
+		}
+		for _, t := range s.ComplexTypes {
+			if t.Name == name.Local {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// SimpleType returns the named simple type, nil for built in types.
+func (d *Definitions) SimpleType(qname string) *SimpleType {
+	name := d.resolve(qname)
+	for _, s := range d.Types.Schemas {
+		if s.TargetNamespace != name.Space {
+			continue
+		}
+		for _, t := range s.SimpleTypes {
+			if t.Name == name.Local {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// TypeOf returns the complex type of e, inline or named, or nil for simple
+// content.
+func (d *Definitions) TypeOf(e *Element) *ComplexType {
+	if e.ComplexType != nil {
+		return e.ComplexType
+	}
+	if e.Type == "" {
+		return nil
+	}
+	return d.ComplexType(e.Type)
+}
+
+// Children returns the elements of t, those of its base type first.
+func (d *Definitions) Children(t *ComplexType) []*Element {
+	var elements []*Element
+	if ext := t.ComplexContent; ext != nil && ext.Extension != nil {
+		if base := d.ComplexType(ext.Extension.Base); base != nil && base != t {
+			elements = append(elements, d.Children(base)...)
+		}
+		elements = append(elements, ext.Extension.Sequence.elements()...)
+	}
+	for _, g := range []*Group{t.Sequence, t.All, t.Choice} {
+		elements = append(elements, g.elements()...)
+	}
+	return elements
+}
+
+// BuiltinType returns the local name of the XML Schema type behind a simple
+// type, following simple type restrictions; "" for complex types.
+func (d *Definitions) BuiltinType(qname string) string {
+	for i := 0; i < 10 && qname != ""; i++ {
+		name := d.resolve(qname)
+		if name.Space == NamespaceXSD {
+			return name.Local
+		}
+		simple := d.SimpleType(qname)
+		if simple == nil || simple.Restriction == nil {
+			return ""
+		}
+		qname = simple.Restriction.Base
+	}
+	return ""
+}
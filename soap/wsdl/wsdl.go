@@ -0,0 +1,257 @@
+// Package wsdl reads WSDL 1.1 documents and lists the SOAP operations they
+// describe, with their actions, endpoint addresses and message schemas.
+// Sample envelopes for an operation come from SampleEnvelope, Go types and
+// clients from cmd/wsdlgen.
+//
+// wsdl:import and xsd:import are not followed; the types used by the
+// operations must be inlined in the document.
+package wsdl
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/httpclient"
+)
+
+const (
+	NamespaceWSDL   = "http://schemas.xmlsoap.org/wsdl/"
+	NamespaceSOAP11 = "http://schemas.xmlsoap.org/wsdl/soap/"
+	NamespaceSOAP12 = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	NamespaceXSD    = "http://www.w3.org/2001/XMLSchema"
+)
+
+// Definitions is the root of a WSDL document. Attribute values holding a
+// QName, such as "tns:GetUser", are kept as written; the prefixes are
+// resolved against the declarations of the whole document.
+type Definitions struct {
+	XMLName         xml.Name    `xml:"http://schemas.xmlsoap.org/wsdl/ definitions"`
+	Name            string      `xml:"name,attr"`
+	TargetNamespace string      `xml:"targetNamespace,attr"`
+	Doc             string      `xml:"http://schemas.xmlsoap.org/wsdl/ documentation"`
+	Types           Types       `xml:"http://schemas.xmlsoap.org/wsdl/ types"`
+	Messages        []*Message  `xml:"http://schemas.xmlsoap.org/wsdl/ message"`
+	PortTypes       []*PortType `xml:"http://schemas.xmlsoap.org/wsdl/ portType"`
+	Bindings        []*Binding  `xml:"http://schemas.xmlsoap.org/wsdl/ binding"`
+	Services        []*Service  `xml:"http://schemas.xmlsoap.org/wsdl/ service"`
+
+	// prefixes maps the namespace prefixes declared in the document, the
+	// first declaration of a prefix wins.
+	prefixes map[string]string
+}
+
+type Types struct {
+	Schemas []*Schema `xml:"http://www.w3.org/2001/XMLSchema schema"`
+}
+
+type Message struct {
+	Name  string  `xml:"name,attr"`
+	Parts []*Part `xml:"http://schemas.xmlsoap.org/wsdl/ part"`
+}
+
+// Part is a message part, described by a schema Element (document style)
+// or a Type (rpc style).
+type Part struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type PortType struct {
+	Name       string           `xml:"name,attr"`
+	Operations []*PortOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+type PortOperation struct {
+	Name   string        `xml:"name,attr"`
+	Doc    string        `xml:"http://schemas.xmlsoap.org/wsdl/ documentation"`
+	Input  *MessageRef   `xml:"http://schemas.xmlsoap.org/wsdl/ input"`
+	Output *MessageRef   `xml:"http://schemas.xmlsoap.org/wsdl/ output"`
+	Faults []*MessageRef `xml:"http://schemas.xmlsoap.org/wsdl/ fault"`
+}
+
+type MessageRef struct {
+	Name    string `xml:"name,attr"`
+	Message string `xml:"message,attr"`
+}
+
+type Binding struct {
+	Name       string              `xml:"name,attr"`
+	Type       string              `xml:"type,attr"`
+	SOAP       *SOAPBinding        `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	SOAP12     *SOAPBinding        `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations []*BindingOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+type SOAPBinding struct {
+	Style     string `xml:"style,attr"`
+	Transport string `xml:"transport,attr"`
+}
+
+type BindingOperation struct {
+	Name   string         `xml:"name,attr"`
+	SOAP   *SOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	SOAP12 *SOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+	Input  *BindingBody   `xml:"http://schemas.xmlsoap.org/wsdl/ input"`
+	Output *BindingBody   `xml:"http://schemas.xmlsoap.org/wsdl/ output"`
+}
+
+type SOAPOperation struct {
+	Action string `xml:"soapAction,attr"`
+	Style  string `xml:"style,attr"`
+}
+
+type BindingBody struct {
+	SOAP   *SOAPBody `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SOAP12 *SOAPBody `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ body"`
+}
+
+type SOAPBody struct {
+	Use       string `xml:"use,attr"`
+	Namespace string `xml:"namespace,attr"`
+	Parts     string `xml:"parts,attr"`
+}
+
+type Service struct {
+	Name  string  `xml:"name,attr"`
+	Doc   string  `xml:"http://schemas.xmlsoap.org/wsdl/ documentation"`
+	Ports []*Port `xml:"http://schemas.xmlsoap.org/wsdl/ port"`
+}
+
+type Port struct {
+	Name      string   `xml:"name,attr"`
+	Binding   string   `xml:"binding,attr"`
+	Address   *Address `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+	Address12 *Address `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+}
+
+type Address struct {
+	Location string `xml:"location,attr"`
+}
+
+// Load parses a WSDL 1.1 document.
+func Load(data []byte) (*Definitions, error) {
+	d := &Definitions{}
+	if err := xml.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("wsdl: %w", err)
+	}
+	prefixes, err := namespacePrefixes(data)
+	if err != nil {
+		return nil, fmt.Errorf("wsdl: %w", err)
+	}
+	d.prefixes = prefixes
+	for _, s := range d.Types.Schemas {
+		s.link()
+	}
+	return d, nil
+}
+
+func LoadFile(path string) (*Definitions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// LoadURL fetches and parses the document at url, usually the service
+// address followed by "?wsdl".
+func LoadURL(ctx context.Context, url string) (*Definitions, error) {
+	resp, err := httpclient.SendRequestContext(ctx, httpclient.HttpConfig{Method: "GET", URL: url, Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &httpclient.StatusError{Method: "GET", URL: url, StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+	return Load(resp.Body)
+}
+
+func namespacePrefixes(data []byte) (map[string]string, error) {
+	prefixes := map[string]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			return prefixes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			prefix := ""
+			switch {
+			case attr.Name.Space == "xmlns":
+				prefix = attr.Name.Local
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			default:
+				continue
+			}
+			if _, ok := prefixes[prefix]; !ok {
+				prefixes[prefix] = attr.Value
+			}
+		}
+	}
+}
+
+// resolve turns a QName attribute value into a namespace and local name.
+func (d *Definitions) resolve(qname string) xml.Name {
+	prefix, name, ok := strings.Cut(qname, ":")
+	if !ok {
+		return xml.Name{Space: d.prefixes[""], Local: qname}
+	}
+	return xml.Name{Space: d.prefixes[prefix], Local: name}
+}
+
+// local strips the prefix of a QName, WSDL components are looked up by
+// local name since a document rarely reuses one across namespaces.
+func local(qname string) string {
+	return qname[strings.LastIndex(qname, ":")+1:]
+}
+
+func (d *Definitions) Message(qname string) *Message {
+	for _, m := range d.Messages {
+		if m.Name == local(qname) {
+			return m
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) PortType(qname string) *PortType {
+	for _, p := range d.PortTypes {
+		if p.Name == local(qname) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) Binding(qname string) *Binding {
+	for _, b := range d.Bindings {
+		if b.Name == local(qname) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (p *PortType) Operation(name string) *PortOperation {
+	for _, op := range p.Operations {
+		if op.Name == name {
+			return op
+		}
+	}
+	return nil
+}
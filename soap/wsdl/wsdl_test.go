@@ -0,0 +1,125 @@
+package wsdl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mgolfam/gogutils/soap"
+)
+
+func TestOperations(t *testing.T) {
+	d, err := LoadFile("testdata/users.wsdl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	operations, err := d.Operations()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		port    string
+		name    string
+		version soap.Version
+		action  string
+		address string
+		style   string
+		input   string
+		faults  int
+	}{
+		{port: "UserPort", name: "GetUser", version: soap.V11, action: "urn:example:users/GetUser", address: "http://localhost:8080/users", style: "document", input: "GetUserRequest", faults: 1},
+		{port: "UserPort", name: "ListUsers", version: soap.V11, action: "urn:example:users/ListUsers", address: "http://localhost:8080/users", style: "document", input: "ListUsersRequest"},
+		{port: "UserPort12", name: "GetUser", version: soap.V12, action: "urn:example:users/GetUser", address: "http://localhost:8080/users12", style: "document", input: "GetUserRequest", faults: 1},
+		{port: "UserPort12", name: "ListUsers", version: soap.V12, action: "urn:example:users/ListUsers", address: "http://localhost:8080/users12", style: "document", input: "ListUsersRequest"},
+		{port: "EchoPort", name: "Echo", version: soap.V11, action: "urn:example:echo#Echo", address: "http://localhost:8080/echo", style: "rpc", input: "EchoRequest"},
+	}
+	if len(operations) != len(tests) {
+		t.Fatalf("Expected %d operations, Got: %d", len(tests), len(operations))
+	}
+	for i, test := range tests {
+		op := operations[i]
+		if op.Port != test.port || op.Name != test.name || op.Version != test.version || op.Action != test.action ||
+			op.Address != test.address || op.Style != test.style || op.Input.Name != test.input || len(op.Faults) != test.faults {
+			t.Errorf("Expected %+v, Got: %+v", test, op)
+		}
+	}
+
+	user := d.ComplexType("tns:User")
+	var names []string
+	for _, e := range d.Children(user) {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") != "id,name,email,roles,status,created,manager" {
+		t.Errorf("Unexpected User elements: %v", names)
+	}
+	if d.BuiltinType("tns:Status") != "string" {
+		t.Errorf("Expected Status to be a string, Got: %q", d.BuiltinType("tns:Status"))
+	}
+}
+
+func TestSampleEnvelope(t *testing.T) {
+	d, err := LoadFile("testdata/users.wsdl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "ListUsers", expected: `<ListUsers xmlns="urn:example:users"><page>0</page><filter><status>active</status><active>false</active></filter></ListUsers>`},
+		{name: "Echo", expected: `<Echo xmlns="urn:example:echo"><message xmlns="">?</message><times xmlns="">0</times></Echo>`},
+	}
+	for _, test := range tests {
+		op, err := d.Operation(test.name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, err := d.SampleBody(op)
+		if err != nil || string(body) != test.expected {
+			t.Errorf("Expected %s, Got: %s, %v", test.expected, body, err)
+		}
+	}
+
+	op, _ := d.Operation("GetUser")
+	env, err := d.SampleEnvelope(op)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded struct {
+		ID int `xml:"urn:example:users id"`
+	}
+	if err := soap.Unmarshal(env, &decoded); err != nil || !strings.Contains(string(env), soap.Namespace11) {
+		t.Errorf("Expected a SOAP 1.1 envelope, Got: %s, %v", env, err)
+	}
+}
+
+func TestLoadURL(t *testing.T) {
+	data, err := os.ReadFile("testdata/users.wsdl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "wsdl" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	d, err := LoadURL(context.Background(), server.URL+"/users?wsdl")
+	if err != nil || len(d.Services) != 2 || d.Services[0].Name != "UserService" {
+		t.Errorf("Unexpected definitions: %v", err)
+	}
+	if _, err := LoadURL(context.Background(), server.URL+"/users"); err == nil {
+		t.Errorf("Expected an error for a 404")
+	}
+	if _, err := Load([]byte(`<definitions/>`)); err == nil {
+		t.Errorf("Expected an error for a document outside the WSDL namespace")
+	}
+}
@@ -0,0 +1,248 @@
+package wsse
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// errNotFound is returned when no element of a document matches.
+var errNotFound = errors.New("element not found")
+
+// matcher reports whether the last element of path, the open elements
+// from the root down, is the one to canonicalize.
+type matcher func(path []xml.StartElement) bool
+
+// canonicalize returns the Exclusive XML Canonicalization, without
+// comments, of the first element of doc that match accepts. Only the
+// namespaces an element or its attributes use are rendered, so the result
+// does not depend on where the element sits in the document.
+func canonicalize(doc []byte, match matcher) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	// declared holds the namespace declarations of the open elements,
+	// rendered those written by the open elements of the output.
+	var declared []map[string]string
+	var rendered []map[string]string
+	var out bytes.Buffer
+	var path []xml.StartElement
+	depth := 0
+
+	lookup := func(scopes []map[string]string, prefix string) (string, bool) {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			if uri, ok := scopes[i][prefix]; ok {
+				return uri, true
+			}
+		}
+		return "", false
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			if depth > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, errNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			scope := map[string]string{}
+			var attrs []xml.Attr
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				default:
+					attrs = append(attrs, attr)
+				}
+			}
+			declared = append(declared, scope)
+			path = append(path, t)
+			if depth == 0 && !match(path) {
+				continue
+			}
+			depth++
+
+			// The namespaces the element and its prefixed attributes use.
+			used := map[string]bool{t.Name.Space: true}
+			for _, attr := range attrs {
+				if attr.Name.Space != "" && attr.Name.Space != "xml" {
+					used[attr.Name.Space] = true
+				}
+			}
+			prefixes := make([]string, 0, len(used))
+			for prefix := range used {
+				prefixes = append(prefixes, prefix)
+			}
+			sort.Strings(prefixes)
+
+			out.WriteString("<" + qualified(t.Name))
+			renderedScope := map[string]string{}
+			for _, prefix := range prefixes {
+				uri, _ := lookup(declared, prefix)
+				if prefix != "" && uri == "" {
+					return nil, errors.New("wsse: undeclared namespace prefix " + prefix)
+				}
+				current, ok := lookup(rendered, prefix)
+				if current == uri && (ok || prefix == "") {
+					continue
+				}
+				renderedScope[prefix] = uri
+				if prefix == "" {
+					out.WriteString(` xmlns="`)
+				} else {
+					out.WriteString(" xmlns:" + prefix + `="`)
+				}
+				out.WriteString(escapeAttr(uri) + `"`)
+			}
+			rendered = append(rendered, renderedScope)
+
+			// Attributes sort by namespace URI, then local name; unqualified
+			// attributes have no URI and come first.
+			uri := func(attr xml.Attr) string {
+				if attr.Name.Space == "xml" {
+					return "http://www.w3.org/XML/1998/namespace"
+				}
+				if attr.Name.Space == "" {
+					return ""
+				}
+				u, _ := lookup(declared, attr.Name.Space)
+				return u
+			}
+			sort.SliceStable(attrs, func(i, j int) bool {
+				if ui, uj := uri(attrs[i]), uri(attrs[j]); ui != uj {
+					return ui < uj
+				}
+				return attrs[i].Name.Local < attrs[j].Name.Local
+			})
+			for _, attr := range attrs {
+				out.WriteString(" " + qualified(attr.Name) + `="` + escapeAttr(attr.Value) + `"`)
+			}
+			out.WriteString(">")
+
+		case xml.EndElement:
+			declared = declared[:len(declared)-1]
+			path = path[:len(path)-1]
+			if depth == 0 {
+				continue
+			}
+			depth--
+			rendered = rendered[:len(rendered)-1]
+			out.WriteString("</" + qualified(t.Name) + ">")
+			if depth == 0 {
+				return out.Bytes(), nil
+			}
+
+		case xml.CharData:
+			if depth > 0 {
+				out.WriteString(escapeText(string(t)))
+			}
+
+		case xml.ProcInst:
+			if depth > 0 {
+				out.WriteString("<?" + t.Target)
+				if len(t.Inst) > 0 {
+					out.WriteString(" " + string(t.Inst))
+				}
+				out.WriteString("?>")
+			}
+		}
+	}
+}
+
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+// byID matches the element whose Id attribute, in any namespace such as
+// wsu:Id, is id. With locals it must also sit at that path of local names
+// from the root, such as Envelope, Body.
+func byID(id string, locals ...string) matcher {
+	return func(path []xml.StartElement) bool {
+		if len(locals) > 0 && !byPath(locals...)(path) {
+			return false
+		}
+		for _, attr := range path[len(path)-1].Attr {
+			if attr.Name.Local == "Id" && attr.Value == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// byName matches the elements with the local name.
+func byName(local string) matcher {
+	return func(path []xml.StartElement) bool {
+		return path[len(path)-1].Name.Local == local
+	}
+}
+
+// byPath matches the element at the path of local names from the root.
+func byPath(locals ...string) matcher {
+	return func(path []xml.StartElement) bool {
+		if len(path) != len(locals) {
+			return false
+		}
+		for i, start := range path {
+			if start.Name.Local != locals[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// checkIDs rejects documents in which an Id occurs more than once, so a
+// reference cannot be resolved to a copy of the signed element placed
+// elsewhere.
+func checkIDs(doc []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	seen := map[string]bool{}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local != "Id" {
+				continue
+			}
+			if seen[attr.Value] {
+				return errors.New("wsse: duplicate Id " + attr.Value)
+			}
+			seen[attr.Value] = true
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package wsse
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoSecurity is returned for envelopes without the header part
+	// being verified.
+	ErrNoSecurity = errors.New("wsse: no security header")
+	// ErrExpired is returned for envelopes whose Timestamp has expired.
+	ErrExpired = errors.New("wsse: timestamp expired")
+	// ErrInvalidSignature is returned when a digest or the signature value
+	// does not match.
+	ErrInvalidSignature = errors.New("wsse: invalid signature")
+	// ErrInvalidPassword is returned for unknown users and wrong passwords.
+	ErrInvalidPassword = errors.New("wsse: invalid username or password")
+)
+
+type received struct {
+	Header struct {
+		Security *receivedSecurity `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	} `xml:"Header"`
+	Body struct {
+		ID string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Id,attr"`
+	} `xml:"Body"`
+}
+
+type receivedSecurity struct {
+	Timestamp *struct {
+		ID      string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Id,attr"`
+		Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+		Expires string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Expires"`
+	} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+	UsernameToken *struct {
+		Username string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Username"`
+		Password struct {
+			Type  string `xml:"Type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Password"`
+		Nonce   string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Nonce"`
+		Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+	} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd UsernameToken"`
+	BinarySecurityToken *struct {
+		ID        string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Id,attr"`
+		ValueType string `xml:"ValueType,attr"`
+		Value     string `xml:",chardata"`
+	} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd BinarySecurityToken"`
+	Signature *struct {
+		SignedInfo struct {
+			CanonicalizationMethod algorithm `xml:"http://www.w3.org/2000/09/xmldsig# CanonicalizationMethod"`
+			SignatureMethod        algorithm `xml:"http://www.w3.org/2000/09/xmldsig# SignatureMethod"`
+			References             []struct {
+				URI          string      `xml:"URI,attr"`
+				Transforms   []algorithm `xml:"http://www.w3.org/2000/09/xmldsig# Transforms>Transform"`
+				DigestMethod algorithm   `xml:"http://www.w3.org/2000/09/xmldsig# DigestMethod"`
+				DigestValue  string      `xml:"http://www.w3.org/2000/09/xmldsig# DigestValue"`
+			} `xml:"http://www.w3.org/2000/09/xmldsig# Reference"`
+		} `xml:"http://www.w3.org/2000/09/xmldsig# SignedInfo"`
+		SignatureValue string `xml:"http://www.w3.org/2000/09/xmldsig# SignatureValue"`
+		KeyInfo        struct {
+			Reference struct {
+				URI string `xml:"URI,attr"`
+			} `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd SecurityTokenReference>Reference"`
+		} `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo"`
+	} `xml:"http://www.w3.org/2000/09/xmldsig# Signature"`
+}
+
+type algorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+func parse(envelope []byte) (*received, error) {
+	var r received
+	if err := xml.Unmarshal(envelope, &r); err != nil {
+		return nil, fmt.Errorf("wsse: %w", err)
+	}
+	if r.Header.Security == nil {
+		return nil, ErrNoSecurity
+	}
+	return &r, nil
+}
+
+// Verify checks the signature of an envelope signed by Security: the
+// digests of the Body and the Timestamp, which both must be signed, the
+// signature value against the BinarySecurityToken, and the expiry of the
+// Timestamp. Envelopes in which an Id occurs twice are rejected. It
+// returns the certificate of the token; trusting it is up to the caller,
+// such as by comparing it with the partner's certificate or with
+// x509.Certificate.Verify.
+func Verify(envelope []byte) (*x509.Certificate, error) {
+	return verify(envelope, time.Now())
+}
+
+func verify(envelope []byte, now time.Time) (*x509.Certificate, error) {
+	r, err := parse(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIDs(envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	security := r.Header.Security
+	signature := security.Signature
+	if signature == nil || security.BinarySecurityToken == nil {
+		return nil, ErrNoSecurity
+	}
+
+	if ts := security.Timestamp; ts != nil && ts.Expires != "" {
+		expires, err := time.Parse(time.RFC3339, strings.TrimSpace(ts.Expires))
+		if err != nil {
+			return nil, fmt.Errorf("wsse: timestamp: %w", err)
+		}
+		if !now.Before(expires) {
+			return nil, ErrExpired
+		}
+	}
+
+	info := signature.SignedInfo
+	if info.CanonicalizationMethod.Algorithm != ExcC14N || info.SignatureMethod.Algorithm != RSASHA256 {
+		return nil, fmt.Errorf("wsse: unsupported signature algorithm %s with %s", info.SignatureMethod.Algorithm, info.CanonicalizationMethod.Algorithm)
+	}
+	signed := map[string]bool{}
+	for _, ref := range info.References {
+		if ref.DigestMethod.Algorithm != SHA256 {
+			return nil, fmt.Errorf("wsse: unsupported digest algorithm %s", ref.DigestMethod.Algorithm)
+		}
+		for _, transform := range ref.Transforms {
+			if transform.Algorithm != ExcC14N {
+				return nil, fmt.Errorf("wsse: unsupported transform %s", transform.Algorithm)
+			}
+		}
+		// The Body and the Timestamp are resolved where they take effect,
+		// not wherever an element carries their Id.
+		id := strings.TrimPrefix(ref.URI, "#")
+		match := byID(id)
+		switch {
+		case id == r.Body.ID:
+			match = byID(id, "Envelope", "Body")
+		case security.Timestamp != nil && id == security.Timestamp.ID:
+			match = byID(id, "Envelope", "Header", "Security", "Timestamp")
+		}
+		canonical, err := canonicalize(envelope, match)
+		if err != nil {
+			return nil, fmt.Errorf("wsse: reference %s: %w", ref.URI, err)
+		}
+		digest := sha256.Sum256(canonical)
+		if base64.StdEncoding.EncodeToString(digest[:]) != strings.TrimSpace(ref.DigestValue) {
+			return nil, ErrInvalidSignature
+		}
+		signed[id] = true
+	}
+	if r.Body.ID == "" || !signed[r.Body.ID] {
+		return nil, errors.New("wsse: body is not signed")
+	}
+	if ts := security.Timestamp; ts != nil && !signed[ts.ID] {
+		return nil, errors.New("wsse: timestamp is not signed")
+	}
+
+	token := security.BinarySecurityToken
+	if token.ValueType != X509v3 || signature.KeyInfo.Reference.URI != "#"+token.ID {
+		return nil, errors.New("wsse: signature does not refer to an X.509 token")
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(token.Value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("wsse: token: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("wsse: token: %w", err)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("wsse: token has no RSA key")
+	}
+
+	value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signature.SignatureValue), ""))
+	if err != nil {
+		return nil, fmt.Errorf("wsse: signature value: %w", err)
+	}
+	canonical, err := canonicalize(envelope, byPath("Envelope", "Header", "Security", "Signature", "SignedInfo"))
+	if err != nil {
+		return nil, fmt.Errorf("wsse: SignedInfo: %w", err)
+	}
+	digest := sha256.Sum256(canonical)
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], value); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return cert, nil
+}
+
+// VerifyUsernameToken checks the UsernameToken of an envelope with the
+// password returned for its username, and returns the username. Both text
+// and digest passwords are accepted; rejecting replayed nonces is up to
+// the caller.
+func VerifyUsernameToken(envelope []byte, password func(username string) (string, bool)) (string, error) {
+	r, err := parse(envelope)
+	if err != nil {
+		return "", err
+	}
+	token := r.Header.Security.UsernameToken
+	if token == nil {
+		return "", ErrNoSecurity
+	}
+	username := strings.TrimSpace(token.Username)
+	expected, ok := password(username)
+	if !ok {
+		return "", ErrInvalidPassword
+	}
+
+	got := token.Password.Value
+	switch token.Password.Type {
+	case PasswordDigest:
+		nonce, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token.Nonce))
+		if err != nil {
+			return "", fmt.Errorf("wsse: nonce: %w", err)
+		}
+		expected = passwordDigest(nonce, strings.TrimSpace(token.Created), expected)
+		got = strings.TrimSpace(got)
+	case "", PasswordText:
+	default:
+		return "", fmt.Errorf("wsse: unsupported password type %s", token.Password.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+		return "", ErrInvalidPassword
+	}
+	return username, nil
+}
@@ -0,0 +1,219 @@
+// Package wsse adds OASIS WS-Security 1.0 headers to SOAP calls: a
+// UsernameToken with a plain or digest password, a Timestamp, and an
+// X.509 signature of the Body and the Timestamp with RSA-SHA256 over
+// Exclusive XML Canonicalization. Verify and VerifyUsernameToken check
+// those headers on the receiving side.
+//
+//	client := soap.NewClient(url, soap.V11)
+//	client.Security = &wsse.Security{
+//		Username:    "partner",
+//		Password:    secret,
+//		Digest:      true,
+//		TTL:         5 * time.Minute,
+//		Certificate: &cert,
+//	}
+//
+// Calls made with httpclient.SoapConfig take the Body returned by Secure.
+package wsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mgolfam/gogutils/soap"
+	"github.com/mgolfam/gogutils/utils"
+)
+
+const (
+	NamespaceWSSE = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	NamespaceWSU  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	NamespaceDS   = "http://www.w3.org/2000/09/xmldsig#"
+
+	PasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	PasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	Base64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+	X509v3         = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3"
+
+	ExcC14N    = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	RSASHA256  = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	SHA256     = "http://www.w3.org/2001/04/xmlenc#sha256"
+	timeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// signatureMarker stands in for the signature value until SignedInfo is
+// canonicalized from the final document.
+const signatureMarker = "wsse-signature-value"
+
+// Security builds the wsse:Security header of every call, see soap.Client.
+// The zero parts are left out: no UsernameToken without Username, no
+// Timestamp without TTL and no signature without Certificate.
+type Security struct {
+	Username string
+	Password string
+	// Digest sends the password as Base64(SHA-1(nonce + created +
+	// password)) instead of in clear text.
+	Digest bool
+	// TTL is the lifetime of the Timestamp, the time between Created and
+	// Expires.
+	TTL time.Duration
+	// Certificate signs the Body and the Timestamp with its RSA key and
+	// is sent as a BinarySecurityToken. Load it with tls.LoadX509KeyPair,
+	// or httpclient.TLSConfig.Certificate for PKCS#12 bundles.
+	Certificate *tls.Certificate
+	// MustUnderstand sets soap:mustUnderstand="1" on the header.
+	MustUnderstand bool
+
+	// now is replaced by the tests.
+	now func() time.Time
+}
+
+func (s *Security) time() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Secure marshals env with the Security header, see soap.Security.
+func (s *Security) Secure(env *soap.Envelope) ([]byte, error) {
+	created := s.time()
+	var header bytes.Buffer
+	mustUnderstand := ""
+	if s.MustUnderstand {
+		mustUnderstand = ` soap:mustUnderstand="1"`
+	}
+	fmt.Fprintf(&header, `<wsse:Security xmlns:wsse="%s" xmlns:wsu="%s"%s>`, NamespaceWSSE, NamespaceWSU, mustUnderstand)
+
+	var references []string
+	tsID := ""
+	if s.TTL > 0 {
+		tsID = "TS-" + utils.UUID()
+		fmt.Fprintf(&header, `<wsu:Timestamp wsu:Id="%s"><wsu:Created>%s</wsu:Created><wsu:Expires>%s</wsu:Expires></wsu:Timestamp>`,
+			tsID, created.Format(timeFormat), created.Add(s.TTL).Format(timeFormat))
+		references = append(references, tsID)
+	}
+
+	if s.Username != "" {
+		token, err := s.usernameToken(created)
+		if err != nil {
+			return nil, err
+		}
+		header.WriteString(token)
+	}
+
+	signed := *env
+	signed.Header = append([]interface{}{nil}, env.Header...)
+	var certID string
+	if s.Certificate != nil {
+		if len(s.Certificate.Certificate) == 0 {
+			return nil, errors.New("wsse: certificate is empty")
+		}
+		if _, ok := s.Certificate.PrivateKey.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("wsse: only RSA keys can sign")
+		}
+		certID = "X509-" + utils.UUID()
+		fmt.Fprintf(&header, `<wsse:BinarySecurityToken EncodingType="%s" ValueType="%s" wsu:Id="%s">%s</wsse:BinarySecurityToken>`,
+			Base64Binary, X509v3, certID, base64.StdEncoding.EncodeToString(s.Certificate.Certificate[0]))
+
+		bodyID := "Body-" + utils.UUID()
+		signed.BodyAttrs = append(append([]xml.Attr{}, env.BodyAttrs...),
+			xml.Attr{Name: xml.Name{Space: "xmlns", Local: "wsu"}, Value: NamespaceWSU},
+			xml.Attr{Name: xml.Name{Space: "wsu", Local: "Id"}, Value: bodyID})
+		references = append([]string{bodyID}, references...)
+	}
+
+	// Without a signature a single pass is enough.
+	if certID == "" {
+		header.WriteString("</wsse:Security>")
+		signed.Header[0] = soap.Raw(header.String())
+		return signed.Marshal()
+	}
+
+	// The first pass fixes the canonical form of the signed elements.
+	signed.Header[0] = soap.Raw(header.String() + "</wsse:Security>")
+	draft, err := signed.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	signedInfo, err := signedInfo(draft, references)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&header, `<ds:Signature xmlns:ds="%s">%s<ds:SignatureValue>%s</ds:SignatureValue>`+
+		`<ds:KeyInfo><wsse:SecurityTokenReference><wsse:Reference URI="#%s" ValueType="%s"></wsse:Reference></wsse:SecurityTokenReference></ds:KeyInfo>`+
+		`</ds:Signature></wsse:Security>`, NamespaceDS, signedInfo, signatureMarker, certID, X509v3)
+	signed.Header[0] = soap.Raw(header.String())
+	data, err := signed.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	canonical, err := canonicalize(data, byPath("Envelope", "Header", "Security", "Signature", "SignedInfo"))
+	if err != nil {
+		return nil, fmt.Errorf("wsse: SignedInfo: %w", err)
+	}
+	digest := sha256.Sum256(canonical)
+	signature, err := s.Certificate.PrivateKey.(*rsa.PrivateKey).Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("wsse: %w", err)
+	}
+	return bytes.Replace(data, []byte(signatureMarker), []byte(base64.StdEncoding.EncodeToString(signature)), 1), nil
+}
+
+func (s *Security) usernameToken(created time.Time) (string, error) {
+	var token strings.Builder
+	fmt.Fprintf(&token, `<wsse:UsernameToken wsu:Id="UT-%s"><wsse:Username>%s</wsse:Username>`, utils.UUID(), escapeText(s.Username))
+	if !s.Digest {
+		fmt.Fprintf(&token, `<wsse:Password Type="%s">%s</wsse:Password></wsse:UsernameToken>`, PasswordText, escapeText(s.Password))
+		return token.String(), nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("wsse: %w", err)
+	}
+	createdText := created.Format(timeFormat)
+	fmt.Fprintf(&token, `<wsse:Password Type="%s">%s</wsse:Password><wsse:Nonce EncodingType="%s">%s</wsse:Nonce><wsu:Created>%s</wsu:Created></wsse:UsernameToken>`,
+		PasswordDigest, passwordDigest(nonce, createdText, s.Password), Base64Binary, base64.StdEncoding.EncodeToString(nonce), createdText)
+	return token.String(), nil
+}
+
+// passwordDigest is Base64(SHA-1(nonce + created + password)).
+func passwordDigest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// signedInfo returns the SignedInfo element with a SHA-256 digest of every
+// referenced element of doc.
+func signedInfo(doc []byte, ids []string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<ds:SignedInfo><ds:CanonicalizationMethod Algorithm="%s"></ds:CanonicalizationMethod>`+
+		`<ds:SignatureMethod Algorithm="%s"></ds:SignatureMethod>`, ExcC14N, RSASHA256)
+	for _, id := range ids {
+		canonical, err := canonicalize(doc, byID(id))
+		if err != nil {
+			return "", fmt.Errorf("wsse: %s: %w", id, err)
+		}
+		digest := sha256.Sum256(canonical)
+		fmt.Fprintf(&b, `<ds:Reference URI="#%s"><ds:Transforms><ds:Transform Algorithm="%s"></ds:Transform></ds:Transforms>`+
+			`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod><ds:DigestValue>%s</ds:DigestValue></ds:Reference>`,
+			id, ExcC14N, SHA256, base64.StdEncoding.EncodeToString(digest[:]))
+	}
+	b.WriteString("</ds:SignedInfo>")
+	return b.String(), nil
+}
@@ -0,0 +1,220 @@
+package wsse
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgolfam/gogutils/soap"
+)
+
+type getUser struct {
+	XMLName xml.Name `xml:"urn:users GetUser"`
+	ID      int      `xml:"id"`
+}
+
+type getUserResponse struct {
+	XMLName xml.Name `xml:"urn:users GetUserResponse"`
+	Name    string   `xml:"name"`
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		match    matcher
+		expected string
+	}{
+		{
+			name:     "used namespaces and sorted attributes",
+			doc:      `<a:Root xmlns:a="urn:a" xmlns:b="urn:b" xmlns:c="urn:c"><a:Child b:x="1" y="2">t &amp; &lt;</a:Child></a:Root>`,
+			match:    byName("Child"),
+			expected: `<a:Child xmlns:a="urn:a" xmlns:b="urn:b" y="2" b:x="1">t &amp; &lt;</a:Child>`,
+		},
+		{
+			name:     "inherited default namespace",
+			doc:      `<Root xmlns="urn:r"><Child><Leaf/></Child></Root>`,
+			match:    byName("Child"),
+			expected: `<Child xmlns="urn:r"><Leaf></Leaf></Child>`,
+		},
+		{
+			name:     "comments and escaping",
+			doc:      `<r><e z="&quot;" a="1"><!-- c -->x&gt;</e></r>`,
+			match:    byName("e"),
+			expected: `<e a="1" z="&quot;">x&gt;</e>`,
+		},
+		{
+			name:     "redeclared prefix",
+			doc:      `<p:A xmlns:p="urn:p" wsu:Id="a1" xmlns:wsu="urn:u"><p:B xmlns:p="urn:p"/></p:A>`,
+			match:    byID("a1"),
+			expected: `<p:A xmlns:p="urn:p" xmlns:wsu="urn:u" wsu:Id="a1"><p:B></p:B></p:A>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := canonicalize([]byte(test.doc), test.match)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != test.expected {
+				t.Errorf("Expected %s\nGot: %s", test.expected, got)
+			}
+		})
+	}
+
+	if _, err := canonicalize([]byte(`<r/>`), byName("x")); !errors.Is(err, errNotFound) {
+		t.Errorf("Expected errNotFound, Got: %v", err)
+	}
+}
+
+func TestPasswordDigest(t *testing.T) {
+	// Computed with: printf 'nonce-1234567890%s%s' "$created" "$password" | openssl dgst -sha1 -binary | base64
+	got := passwordDigest([]byte("nonce-1234567890"), "2026-01-02T03:04:05.000Z", "s3cret")
+	if expected := "In+RNIyjRl8ymRUqme460keYUGE="; got != expected {
+		t.Errorf("Expected %s, Got: %s", expected, got)
+	}
+}
+
+func TestSecureCall(t *testing.T) {
+	cert := selfSigned(t)
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		signer, err := Verify(received)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		} else if signer.Subject.CommonName != "partner" {
+			t.Errorf("Expected the partner certificate, Got: %s", signer.Subject)
+		}
+		user, err := VerifyUsernameToken(received, func(username string) (string, bool) {
+			return "s3cret", username == "partner"
+		})
+		if err != nil || user != "partner" {
+			t.Errorf("Expected user partner, Got: %q, %v", user, err)
+		}
+		var in getUser
+		if err := soap.Unmarshal(received, &in); err != nil || in.ID != 7 {
+			t.Errorf("Expected GetUser 7, Got: %+v, %v", in, err)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetUserResponse xmlns="urn:users"><name>Ada</name></GetUserResponse></soap:Body></soap:Envelope>`)
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, soap.V11)
+	client.Security = &Security{Username: "partner", Password: "s3cret", Digest: true, TTL: time.Minute, Certificate: &cert, MustUnderstand: true}
+	var out getUserResponse
+	if err := client.Call(context.Background(), "urn:GetUser", getUser{ID: 7}, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Name != "Ada" {
+		t.Errorf("Expected Ada, Got: %s", out.Name)
+	}
+	for _, part := range []string{`soap:mustUnderstand="1"`, "#PasswordDigest", "<wsse:Nonce", "<wsu:Timestamp", "<ds:SignatureValue>", "<soap:Body xmlns:wsu="} {
+		if !strings.Contains(string(received), part) {
+			t.Errorf("Expected the envelope to contain %s, Got: %s", part, received)
+		}
+	}
+	if strings.Contains(string(received), "s3cret") {
+		t.Errorf("Expected no clear text password, Got: %s", received)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	cert := selfSigned(t)
+	env := &soap.Envelope{Version: soap.V12, Body: getUser{ID: 7}}
+	secure := func(s *Security) string {
+		data, err := s.Secure(env)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return string(data)
+	}
+	signed := secure(&Security{TTL: time.Minute, Certificate: &cert})
+	if _, err := Verify([]byte(signed)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Signature wrapping: the signed Body moves into a header, a forged
+	// Body takes its place.
+	bodyStart, bodyEnd := strings.Index(signed, "<soap:Body"), strings.Index(signed, "</soap:Envelope>")
+	original := signed[bodyStart:bodyEnd]
+	forged := strings.Replace(original, "<id>7</id>", "<id>8</id>", 1)
+	wrap := func(body string) string {
+		return strings.Replace(signed[:bodyStart], "<soap:Header>", "<soap:Header><Wrapper>"+original+"</Wrapper>", 1) + body + "</soap:Envelope>"
+	}
+	withoutID := regexp.MustCompile(` wsu:Id="[^"]*"`).ReplaceAllString(forged, "")
+
+	past := func() time.Time { return time.Now().Add(-time.Hour) }
+	tests := []struct {
+		name     string
+		envelope string
+		expected error
+	}{
+		{name: "tampered body", envelope: strings.Replace(signed, "<id>7</id>", "<id>8</id>", 1), expected: ErrInvalidSignature},
+		{name: "tampered timestamp", envelope: strings.Replace(signed, "<wsu:Created>", "<wsu:Created> ", 1), expected: ErrInvalidSignature},
+		{name: "wrapped body", envelope: wrap(forged), expected: ErrInvalidSignature},
+		{name: "wrapped body without id", envelope: wrap(withoutID)},
+		{name: "expired", envelope: secure(&Security{TTL: time.Minute, Certificate: &cert, now: past}), expected: ErrExpired},
+		{name: "unsigned", envelope: secure(&Security{TTL: time.Minute}), expected: ErrNoSecurity},
+		{name: "no header", envelope: string(mustMarshal(t, env)), expected: ErrNoSecurity},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Verify([]byte(test.envelope))
+			if err == nil || (test.expected != nil && !errors.Is(err, test.expected)) {
+				t.Errorf("Expected %v, Got: %v", test.expected, err)
+			}
+		})
+	}
+
+	password := func(string) (string, bool) { return "s3cret", true }
+	for _, s := range []*Security{{Username: "u", Password: "wrong"}, {Username: "u", Password: "wrong", Digest: true}} {
+		if _, err := VerifyUsernameToken([]byte(secure(s)), password); !errors.Is(err, ErrInvalidPassword) {
+			t.Errorf("Expected ErrInvalidPassword, Got: %v", err)
+		}
+	}
+	if user, err := VerifyUsernameToken([]byte(secure(&Security{Username: "u", Password: "s3cret"})), password); err != nil || user != "u" {
+		t.Errorf("Expected user u, Got: %q, %v", user, err)
+	}
+}
+
+func mustMarshal(t *testing.T, env *soap.Envelope) []byte {
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return data
+}
+
+func selfSigned(t *testing.T) tls.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "partner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath returns the value at path in data, as decoded by encoding/json.
+// Only plain selections are supported: $.a.b, $.items[0].id, $.items[-1]
+// for the last element and $['key with.dots']. The leading $ is optional.
+func JSONPath(data interface{}, path string) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := data
+	for _, step := range steps {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[step]
+			if !ok {
+				return nil, fmt.Errorf("jsonpath %s: no key %q", path, step)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(step)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %s: %q is not an index", path, step)
+			}
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("jsonpath %s: index %s out of range", path, step)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("jsonpath %s: cannot select %q from %T", path, step, current)
+		}
+	}
+	return current, nil
+}
+
+func parseJSONPath(path string) ([]string, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []string
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("jsonpath %s: empty key", path)
+			}
+			steps = append(steps, p[:end])
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %s: missing ]", path)
+			}
+			step := p[1:end]
+			if len(step) >= 2 && (step[0] == '\'' || step[0] == '"') && step[len(step)-1] == step[0] {
+				step = step[1 : len(step)-1]
+			}
+			steps = append(steps, step)
+			p = p[end+1:]
+		default:
+			// A path without leading $ or dot, such as "data.id".
+			p = "." + p
+		}
+	}
+	return steps, nil
+}
+
+// ExpandVars replaces {{name}} placeholders in s with the values returned by
+// lookup. Unknown names are left as they are.
+func ExpandVars(s string, lookup func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+
+		b.WriteString(s[:start])
+		if value, ok := lookup(strings.TrimSpace(s[start+2 : end])); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(s[start : end+2])
+		}
+		s = s[end+2:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/mgolfam/gogutils/glog"
@@ -92,7 +94,7 @@ func ResolveMX(domain string) ([]*net.MX, error) {
 
 // Function 4: Check if a specific port is open on a remote host
 func IsPortOpen(host string, port int) bool {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", host, port))
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		return false
 	}
@@ -196,3 +198,23 @@ func GetPublicIPAddress() (string, error) {
 
 	return localAddr.IP.String(), nil
 }
+
+// ParseNetworkAddress splits an address into the network and address of
+// net.Dial: "unix:///run/app.sock" and "/run/app.sock" are Unix sockets,
+// "tcp://host:port" and "host:port" are TCP.
+func ParseNetworkAddress(address string) (string, string) {
+	if network, rest, ok := strings.Cut(address, "://"); ok {
+		return network, rest
+	}
+	if strings.HasPrefix(address, "/") || strings.HasPrefix(address, "@") {
+		return "unix", address
+	}
+	return "tcp", address
+}
+
+// DialAddress connects to an address of ParseNetworkAddress.
+func DialAddress(ctx context.Context, address string) (net.Conn, error) {
+	network, addr := ParseNetworkAddress(address)
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, addr)
+}